@@ -97,6 +97,85 @@ func Normalize(v []float32) []float32 {
 	return retVal
 }
 
+// Solve3x3 solves the linear system a*x = b for a 3x3 matrix a using Cramer's rule.
+// An error is returned if a is singular, i.e., if |det(a)| is below tol.
+func Solve3x3(a [3][3]float64, b [3]float64, tol float64) (x [3]float64, err error) {
+	det := a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+	if math.Abs(det) <= tol {
+		return x, errors.New("Solve3x3: the matrix is singular")
+	}
+	for col := 0; col < 3; col++ {
+		// replace column col with b
+		m := a
+		for row := 0; row < 3; row++ {
+			m[row][col] = b[row]
+		}
+		x[col] = (m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+			m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+			m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])) / det
+	}
+	return x, nil
+}
+
+// SymmetricEigen3 computes the eigenvalues and eigenvectors of a symmetric 3x3 matrix
+// with the cyclic Jacobi method.  The eigenvalues are sorted in increasing order and
+// vectors[i] is the unit eigenvector of values[i].
+func SymmetricEigen3(a [3][3]float64) (values [3]float64, vectors [3][3]float64) {
+	v := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for sweep := 0; sweep < 50; sweep++ {
+		offDiagonal := a[0][1]*a[0][1] + a[0][2]*a[0][2] + a[1][2]*a[1][2]
+		if offDiagonal < 1e-30 {
+			break
+		}
+		for p := 0; p < 2; p++ {
+			for q := p + 1; q < 3; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// rotate in the (p,q) plane to zero a[p][q]
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < 3; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < 3; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < 3; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	order := [3]int{0, 1, 2}
+	for i := 0; i < 3; i++ {
+		for j := i + 1; j < 3; j++ {
+			if a[order[j]][order[j]] < a[order[i]][order[i]] {
+				order[i], order[j] = order[j], order[i]
+			}
+		}
+	}
+	for i, col := range order {
+		values[i] = a[col][col]
+		vectors[i] = [3]float64{v[0][col], v[1][col], v[2][col]}
+	}
+	return values, vectors
+}
+
 // RandomUint32 generates a random uint32 based on a min and max.
 // If the min is greater than the max, we will just return 0. Try better next time.
 func RandomUint32(min, max uint32) uint32 {
@@ -68,7 +68,7 @@ func TestNormalize(t *testing.T) {
 func TestRandomUint32(t *testing.T) {
 	t1 := RandomUint32(0, 0)
 	if t1 != 0 {
-		t.Error("Expected 0 and got %v\n", t1)
+		t.Errorf("Expected 0 and got %v\n", t1)
 	}
 	t1 = RandomUint32(9, 4)
 	if t1 != 0 {
@@ -76,7 +76,7 @@ func TestRandomUint32(t *testing.T) {
 	}
 	t1 = RandomUint32(0, 5000)
 	if t1 < 0 || t1 > 5000 {
-		t.Error("The random number generated was outside of the bounds given: %v\n", t1)
+		t.Errorf("The random number generated was outside of the bounds given: %v\n", t1)
 	}
 }
 
@@ -85,3 +85,56 @@ func TestCross(t *testing.T) {
 	c := Cross(u, u)
 	fmt.Printf("c: %v", c)
 }
+
+func TestSolve3x3(t *testing.T) {
+	a := [3][3]float64{
+		{2, 0, 1},
+		{1, 3, 0},
+		{0, 1, 4}}
+	expected := [3]float64{1, -2, 3}
+	b := [3]float64{}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			b[row] += a[row][col] * expected[col]
+		}
+	}
+	x, err := Solve3x3(a, b, 1e-12)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range x {
+		if math.Abs(x[i]-expected[i]) > 1e-9 {
+			t.Errorf("Expected %v and got %v", expected[i], x[i])
+		}
+	}
+
+	singular := [3][3]float64{
+		{1, 2, 3},
+		{2, 4, 6},
+		{0, 1, 4}}
+	if _, err := Solve3x3(singular, b, 1e-12); err == nil {
+		t.Error("Expected an error for a singular matrix")
+	}
+}
+
+func TestSymmetricEigen3(t *testing.T) {
+	a := [3][3]float64{
+		{2, 1, 0},
+		{1, 2, 0},
+		{0, 0, 5}}
+	values, vectors := SymmetricEigen3(a)
+	expected := [3]float64{1, 3, 5}
+	for i := range values {
+		if math.Abs(values[i]-expected[i]) > 1e-9 {
+			t.Errorf("Expected eigenvalue %v and got %v", expected[i], values[i])
+		}
+		// check a*v = lambda*v
+		for row := 0; row < 3; row++ {
+			av := a[row][0]*vectors[i][0] + a[row][1]*vectors[i][1] + a[row][2]*vectors[i][2]
+			if math.Abs(av-values[i]*vectors[i][row]) > 1e-9 {
+				t.Errorf("Eigenvector %v: %v is not an eigenvector", i, vectors[i])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package auxmath
+
+import (
+	"errors"
+	"math"
+)
+
+// Vec3 is a 3D vector passed by value, so that geometry math does not allocate.
+// Its methods match the functions on []float32 of the same name.
+type Vec3 [3]float32
+
+// ToVec3 returns the vector of a slice of 3 floats
+func ToVec3(v []float32) (Vec3, error) {
+	if len(v) != 3 {
+		return Vec3{}, errors.New("ToVec3: a vector has 3 coordinates")
+	}
+	return Vec3{v[0], v[1], v[2]}, nil
+}
+
+// Slice returns the coordinates of v in a new slice, for the functions on []float32
+func (v Vec3) Slice() []float32 {
+	return []float32{v[0], v[1], v[2]}
+}
+
+// Add returns v + u
+func (v Vec3) Add(u Vec3) Vec3 {
+	return Vec3{v[0] + u[0], v[1] + u[1], v[2] + u[2]}
+}
+
+// Sub returns v - u
+func (v Vec3) Sub(u Vec3) Vec3 {
+	return Vec3{v[0] - u[0], v[1] - u[1], v[2] - u[2]}
+}
+
+// Scale returns s v
+func (v Vec3) Scale(s float32) Vec3 {
+	return Vec3{s * v[0], s * v[1], s * v[2]}
+}
+
+// Dot returns the dot product of v and u
+func (v Vec3) Dot(u Vec3) float32 {
+	return v[0]*u[0] + v[1]*u[1] + v[2]*u[2]
+}
+
+// Cross returns the cross product of v and u
+func (v Vec3) Cross(u Vec3) Vec3 {
+	return Vec3{v[1]*u[2] - v[2]*u[1], v[2]*u[0] - v[0]*u[2], v[0]*u[1] - v[1]*u[0]}
+}
+
+// Magnitude returns the length of v, or 0 when its square is below 1e-8 (see Magnitude)
+func (v Vec3) Magnitude() float32 {
+	normSq := v.Dot(v)
+	if normSq < 1e-8 {
+		return 0
+	}
+	return float32(math.Sqrt(float64(normSq)))
+}
+
+// Normalize returns v scaled to unit length, or the zero vector when v is shorter
+// than 1e-6 (see Normalize)
+func (v Vec3) Normalize() Vec3 {
+	norm := v.Magnitude()
+	if norm < 1e-6 {
+		return Vec3{}
+	}
+	return Vec3{v[0] / norm, v[1] / norm, v[2] / norm}
+}
+
+// Parallel tells whether v and u point the same way, within tol (see Parallel)
+func (v Vec3) Parallel(u Vec3, tol float32) bool {
+	deviation := v.Dot(u) - v.Magnitude()*u.Magnitude()
+	return math.Abs(float64(deviation)) <= float64(tol)
+}
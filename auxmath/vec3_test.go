@@ -0,0 +1,69 @@
+package auxmath
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the methods of Vec3 agree with the functions on []float32
+func TestVec3(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() Vec3 {
+		return Vec3{rng.Float32() - .5, rng.Float32() - .5, rng.Float32() - .5}
+	}
+	same := func(what string, expected []float32, got Vec3) {
+		for i := range expected {
+			if expected[i] != got[i] {
+				t.Errorf("%s: expected %v and got %v", what, expected, got)
+				return
+			}
+		}
+	}
+	for i := 0; i < 100; i++ {
+		u, v := random(), random()
+		s := rng.Float32()
+		sum, _ := Add(u.Slice(), v.Slice())
+		same("Add", sum, u.Add(v))
+		difference, _ := Subtract(u.Slice(), v.Slice())
+		same("Sub", difference, u.Sub(v))
+		same("Scale", Scale(u.Slice(), s), u.Scale(s))
+		same("Cross", Cross(u.Slice(), v.Slice()), u.Cross(v))
+		same("Normalize", Normalize(u.Slice()), u.Normalize())
+		if dot, _ := Dot(u.Slice(), v.Slice()); dot != u.Dot(v) {
+			t.Errorf("Dot: expected %v and got %v", dot, u.Dot(v))
+		}
+		if Magnitude(u.Slice()) != u.Magnitude() {
+			t.Errorf("Magnitude: expected %v and got %v", Magnitude(u.Slice()), u.Magnitude())
+		}
+		if Parallel(u.Slice(), v.Slice(), .01) != u.Parallel(v, .01) || !u.Parallel(u.Scale(s), 1e-5) {
+			t.Errorf("Parallel: expected %v for %v and %v", Parallel(u.Slice(), v.Slice(), .01), u, v)
+		}
+	}
+	if (Vec3{1e-5, 0, 0}).Normalize() != (Vec3{}) {
+		t.Error("Expected a tiny vector to normalize to zero")
+	}
+}
+
+func TestToVec3(t *testing.T) {
+	v, err := ToVec3([]float32{1, 2, 3})
+	if err != nil || v != (Vec3{1, 2, 3}) {
+		t.Errorf("Expected (1, 2, 3) and got %v: %v", v, err)
+	}
+	if _, err := ToVec3([]float32{1, 2}); err == nil {
+		t.Error("Expected an error for a slice of 2 floats")
+	}
+}
+
+func BenchmarkVec3Cross(b *testing.B) {
+	u, v := Vec3{1, 2, 3}, Vec3{3, 1, 2}
+	for i := 0; i < b.N; i++ {
+		u = u.Cross(v).Normalize()
+	}
+}
+
+func BenchmarkCross(b *testing.B) {
+	u, v := []float32{1, 2, 3}, []float32{3, 1, 2}
+	for i := 0; i < b.N; i++ {
+		u = Normalize(Cross(u, v))
+	}
+}
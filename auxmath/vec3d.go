@@ -0,0 +1,59 @@
+package auxmath
+
+import "math"
+
+// Vec3d is the double precision counterpart of Vec3, for the geometry of meshes that
+// need more than float32 (see mesh.Mesh64) and of the algorithms that accumulate over
+// many triangles.  Unlike Vec3 it has no tolerances: only an exact zero is degenerate.
+// A [3]float64 converts to a Vec3d and back for free.
+type Vec3d [3]float64
+
+// Add returns v + u
+func (v Vec3d) Add(u Vec3d) Vec3d {
+	return Vec3d{v[0] + u[0], v[1] + u[1], v[2] + u[2]}
+}
+
+// Sub returns v - u
+func (v Vec3d) Sub(u Vec3d) Vec3d {
+	return Vec3d{v[0] - u[0], v[1] - u[1], v[2] - u[2]}
+}
+
+// Scale returns s v
+func (v Vec3d) Scale(s float64) Vec3d {
+	return Vec3d{s * v[0], s * v[1], s * v[2]}
+}
+
+// Dot returns the dot product of v and u
+func (v Vec3d) Dot(u Vec3d) float64 {
+	return v[0]*u[0] + v[1]*u[1] + v[2]*u[2]
+}
+
+// Cross returns the cross product of v and u
+func (v Vec3d) Cross(u Vec3d) Vec3d {
+	return Vec3d{v[1]*u[2] - v[2]*u[1], v[2]*u[0] - v[0]*u[2], v[0]*u[1] - v[1]*u[0]}
+}
+
+// Magnitude returns the length of v
+func (v Vec3d) Magnitude() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// Normalize returns v scaled to unit length, or v itself when it is the zero vector
+func (v Vec3d) Normalize() Vec3d {
+	norm := v.Magnitude()
+	if norm == 0 {
+		return v
+	}
+	return Vec3d{v[0] / norm, v[1] / norm, v[2] / norm}
+}
+
+// TriangleNormal returns the unit normal and the area of the triangle (a, b, c),
+// or the zero vector and a zero area when the triangle is degenerate
+func TriangleNormal(a Vec3d, b Vec3d, c Vec3d) (Vec3d, float64) {
+	n := b.Sub(a).Cross(c.Sub(a))
+	l := n.Magnitude()
+	if l == 0 {
+		return n, 0
+	}
+	return n.Scale(1 / l), l / 2
+}
@@ -0,0 +1,41 @@
+package auxmath
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVec3d(t *testing.T) {
+	u, v := Vec3d{1, 2, 3}, Vec3d{3, 1, 2}
+	if u.Add(v) != (Vec3d{4, 3, 5}) || u.Sub(v) != (Vec3d{-2, 1, 1}) || u.Scale(2) != (Vec3d{2, 4, 6}) {
+		t.Errorf("Expected the sum, difference and scale of %v and %v and got %v, %v, %v", u, v, u.Add(v), u.Sub(v), u.Scale(2))
+	}
+	if u.Dot(v) != 11 {
+		t.Errorf("Dot: expected 11 and got %v", u.Dot(v))
+	}
+	if c := u.Cross(v); c != (Vec3d{1, 7, -5}) || c.Dot(u) != 0 || c.Dot(v) != 0 {
+		t.Errorf("Cross: expected (1, 7, -5) and got %v", c)
+	}
+	if u.Magnitude() != math.Sqrt(14) {
+		t.Errorf("Magnitude: expected %v and got %v", math.Sqrt(14), u.Magnitude())
+	}
+	// no tolerance: a tiny vector still has a direction
+	if n := (Vec3d{1e-20, 0, 0}).Normalize(); n != (Vec3d{1, 0, 0}) {
+		t.Errorf("Expected a tiny vector to normalize to (1, 0, 0) and got %v", n)
+	}
+	if (Vec3d{}).Normalize() != (Vec3d{}) {
+		t.Error("Expected the zero vector to stay zero")
+	}
+}
+
+func TestTriangleNormal(t *testing.T) {
+	// far from the origin, where float32 can't tell the corners apart
+	a := Vec3d{5e6, 5e6, 5e6}
+	n, area := TriangleNormal(a, a.Add(Vec3d{.01, 0, 0}), a.Add(Vec3d{0, .01, 0}))
+	if math.Abs(n[2]-1) > 1e-9 || math.Abs(area-5e-5) > 1e-10 {
+		t.Errorf("Expected the normal (0, 0, 1) and the area 5e-5 and got %v and %v", n, area)
+	}
+	if n, area := TriangleNormal(a, a, Vec3d{}); n != (Vec3d{}) || area != 0 {
+		t.Errorf("Expected a degenerate triangle to have no normal and no area and got %v and %v", n, area)
+	}
+}
@@ -0,0 +1,357 @@
+package cloudmesh
+
+import (
+	"fmt"
+	"math"
+)
+
+// Channel is a named attribute of Components floats per vertex or per triangle
+type Channel struct {
+	Components int
+	Data       []float32
+}
+
+// Attributes are the optional per-vertex and per-triangle channels of an IndexedMesh.
+// A nil slice is a channel the mesh does not have.  Vertex channels follow the order
+// of Vertices and face channels the order of the triangles in Indices, and the
+// methods of IndexedMesh that move, drop or merge vertices and triangles carry them along.
+// Like the slices, the maps of named channels are shared by the copies of a mesh.
+type Attributes struct {
+	// Normals holds 3 floats per vertex
+	Normals []float32
+	// Colors holds 4 floats per vertex, RGBA in [0, 1]
+	Colors []float32
+	// UVs holds 2 texture coordinates per vertex
+	UVs []float32
+	// Materials holds a material ID per triangle
+	Materials []uint32
+	// VertexChannels and FaceChannels are arbitrary named channels
+	VertexChannels map[string]Channel
+	FaceChannels   map[string]Channel
+}
+
+// Empty tells whether there is no channel at all
+func (a Attributes) Empty() bool {
+	return a.Normals == nil && a.Colors == nil && a.UVs == nil && a.Materials == nil &&
+		len(a.VertexChannels) == 0 && len(a.FaceChannels) == 0
+}
+
+// Check returns an error if a channel does not have exactly one entry per vertex or
+// per triangle
+func (a Attributes) Check(numVertices uint32, numTriangles uint32) error {
+	var err error
+	a.forEachVertexChannel(func(name string, data []float32, components int) []float32 {
+		if err == nil {
+			err = checkLength(name, len(data), components, numVertices)
+		}
+		return data
+	})
+	a.forEachFaceChannel(func(name string, data []float32, components int) []float32 {
+		if err == nil {
+			err = checkLength(name, len(data), components, numTriangles)
+		}
+		return data
+	}, func(materials []uint32) []uint32 {
+		if err == nil {
+			err = checkLength("materials", len(materials), 1, numTriangles)
+		}
+		return materials
+	})
+	return err
+}
+
+func checkLength(name string, length int, components int, count uint32) error {
+	if components <= 0 {
+		return fmt.Errorf("Check:channel %s has %d components", name, components)
+	}
+	if length != components*int(count) {
+		return fmt.Errorf("Check:channel %s has %d floats instead of %d", name, length, components*int(count))
+	}
+	return nil
+}
+
+// forEachVertexChannel replaces every vertex channel the attributes have by f of it.
+// The named channels are replaced in their map, see ownMaps.
+func (a *Attributes) forEachVertexChannel(f func(name string, data []float32, components int) []float32) {
+	if a.Normals != nil {
+		a.Normals = f("normals", a.Normals, 3)
+	}
+	if a.Colors != nil {
+		a.Colors = f("colors", a.Colors, 4)
+	}
+	if a.UVs != nil {
+		a.UVs = f("uvs", a.UVs, 2)
+	}
+	forEachNamed(a.VertexChannels, f)
+}
+
+// forEachFaceChannel replaces every face channel the attributes have by f of it, and
+// the materials by g of them
+func (a *Attributes) forEachFaceChannel(f func(name string, data []float32, components int) []float32, g func(materials []uint32) []uint32) {
+	if a.Materials != nil {
+		a.Materials = g(a.Materials)
+	}
+	forEachNamed(a.FaceChannels, f)
+}
+
+// forEachNamed replaces the data of the channels by f of it, in place.  A channel f
+// returns unchanged is not written, so that reading the channels does not write the map.
+func forEachNamed(channels map[string]Channel, f func(name string, data []float32, components int) []float32) {
+	for name, c := range channels {
+		data := f(name, c.Data, c.Components)
+		if len(data) != len(c.Data) || (len(data) > 0 && &data[0] != &c.Data[0]) {
+			channels[name] = Channel{Components: c.Components, Data: data}
+		}
+	}
+}
+
+// ownMaps returns the attributes with maps of named channels of their own, for the
+// methods that derive the attributes of a new mesh and must not write to the maps of
+// the one they come from
+func (a Attributes) ownMaps() Attributes {
+	a.VertexChannels = copyChannels(a.VertexChannels)
+	a.FaceChannels = copyChannels(a.FaceChannels)
+	return a
+}
+
+func copyChannels(channels map[string]Channel) map[string]Channel {
+	if channels == nil {
+		return nil
+	}
+	retVal := make(map[string]Channel, len(channels))
+	for name, c := range channels {
+		retVal[name] = c
+	}
+	return retVal
+}
+
+// sameVertex tells whether vertices v and w have the same vertex attributes
+func (a Attributes) sameVertex(v uint32, w uint32) bool {
+	same := true
+	a.forEachVertexChannel(func(name string, data []float32, components int) []float32 {
+		for c := 0; same && c < components; c++ {
+			i, j := components*int(v)+c, components*int(w)+c
+			if i < len(data) && j < len(data) && data[i] != data[j] {
+				same = false
+			}
+		}
+		return data
+	})
+	return same
+}
+
+// remap returns the attributes of a mesh whose vertices and triangles moved to the
+// given new indices (Removed for the dropped ones), with numVertices vertices and
+// numTriangles triangles.  When several entries move to the same index, the first
+// one is kept.  A nil map leaves those channels as they are.
+func (a Attributes) remap(vertices []uint32, numVertices uint32, triangles []uint32, numTriangles uint32) Attributes {
+	a = a.ownMaps()
+	if vertices != nil {
+		a.forEachVertexChannel(func(name string, data []float32, components int) []float32 {
+			return gather(data, components, vertices, numVertices)
+		})
+	}
+	if triangles != nil {
+		a.forEachFaceChannel(func(name string, data []float32, components int) []float32 {
+			return gather(data, components, triangles, numTriangles)
+		}, func(materials []uint32) []uint32 {
+			retVal := make([]uint32, numTriangles)
+			for old := len(triangles) - 1; old >= 0; old-- {
+				if t := triangles[old]; t != Removed && old < len(materials) {
+					retVal[t] = materials[old]
+				}
+			}
+			return retVal
+		})
+	}
+	return a
+}
+
+// gather returns the entries of data moved to their new index.  It walks the old
+// entries backward so that the first one to move to an index overwrites the others.
+func gather(data []float32, components int, newIndex []uint32, count uint32) []float32 {
+	retVal := make([]float32, components*int(count))
+	for old := len(newIndex) - 1; old >= 0; old-- {
+		n := newIndex[old]
+		if n == Removed || components*(old+1) > len(data) {
+			continue
+		}
+		copy(retVal[components*int(n):components*int(n+1)], data[components*old:components*(old+1)])
+	}
+	return retVal
+}
+
+// Resample returns attributes for a mesh derived from the one these attributes belong
+// to, e.g., a simplification of it.  Vertex v of the derived mesh gets the sum of the
+// vertex attributes of corners[v] with weights[v], and triangle t gets the face
+// attributes of triangle faces[t].  Normals are normalized again after the sum.
+func (a Attributes) Resample(corners [][3]uint32, weights [][3]float32, faces []uint32) Attributes {
+	a = a.ownMaps()
+	a.forEachVertexChannel(func(name string, data []float32, components int) []float32 {
+		retVal := make([]float32, components*len(corners))
+		for v, corner := range corners {
+			dst := retVal[components*v : components*(v+1)]
+			for i, w := range corner {
+				if components*int(w+1) > len(data) {
+					continue
+				}
+				for c, x := range data[components*int(w) : components*int(w+1)] {
+					dst[c] += weights[v][i] * x
+				}
+			}
+		}
+		if name == "normals" {
+			for v := 0; v < len(corners); v++ {
+				normalize(retVal[3*v : 3*v+3])
+			}
+		}
+		return retVal
+	})
+	a.selectFaces(faces)
+	return a
+}
+
+// Select returns the attributes of a mesh made of the given vertices and triangles of
+// the one these attributes belong to, in that order, e.g., one of its parts
+func (a Attributes) Select(vertices []uint32, triangles []uint32) Attributes {
+	a = a.ownMaps()
+	a.forEachVertexChannel(func(name string, data []float32, components int) []float32 {
+		return pick(data, components, vertices)
+	})
+	a.selectFaces(triangles)
+	return a
+}
+
+// selectFaces replaces the face channels by their entries for the given triangles
+func (a *Attributes) selectFaces(triangles []uint32) {
+	a.forEachFaceChannel(func(name string, data []float32, components int) []float32 {
+		return pick(data, components, triangles)
+	}, func(materials []uint32) []uint32 {
+		retVal := make([]uint32, len(triangles))
+		for t, f := range triangles {
+			if int(f) < len(materials) {
+				retVal[t] = materials[f]
+			}
+		}
+		return retVal
+	})
+}
+
+// pick returns the entries of data at the given indices, zeroed for those out of range
+func pick(data []float32, components int, indices []uint32) []float32 {
+	retVal := make([]float32, components*len(indices))
+	for i, old := range indices {
+		if components*int(old+1) <= len(data) {
+			copy(retVal[components*i:components*(i+1)], data[components*int(old):components*int(old+1)])
+		}
+	}
+	return retVal
+}
+
+// Copy returns a copy of the attributes that shares no slice nor map with them
+func (a Attributes) Copy() Attributes {
+	a = a.ownMaps()
+	a.forEachVertexChannel(func(name string, data []float32, components int) []float32 {
+		return append(make([]float32, 0, len(data)), data...)
+	})
+	a.forEachFaceChannel(func(name string, data []float32, components int) []float32 {
+		return append(make([]float32, 0, len(data)), data...)
+	}, func(materials []uint32) []uint32 {
+		return append(make([]uint32, 0, len(materials)), materials...)
+	})
+	return a
+}
+
+// append appends the attributes b of a mesh of otherVertices vertices and otherTriangles
+// triangles to these attributes of a mesh of numVertices vertices and numTriangles triangles.  A channel
+// that only one of them has is zeroed for the entries of the other one.  Like grow, it
+// writes the maps of named channels in place.
+func (a *Attributes) append(b Attributes, numVertices uint32, numTriangles uint32, otherVertices uint32, otherTriangles uint32) {
+	a.Normals = appendChannel(a.Normals, b.Normals, 3, numVertices, otherVertices)
+	a.Colors = appendChannel(a.Colors, b.Colors, 4, numVertices, otherVertices)
+	a.UVs = appendChannel(a.UVs, b.UVs, 2, numVertices, otherVertices)
+	if a.Materials != nil || b.Materials != nil {
+		materials := make([]uint32, numTriangles+otherTriangles)
+		copy(materials[:numTriangles], a.Materials)
+		copy(materials[numTriangles:], b.Materials)
+		a.Materials = materials
+	}
+	a.VertexChannels = appendNamed(a.VertexChannels, b.VertexChannels, numVertices, otherVertices)
+	a.FaceChannels = appendNamed(a.FaceChannels, b.FaceChannels, numTriangles, otherTriangles)
+}
+
+// appendChannel returns data, sized for count entries, followed by other, sized for
+// otherCount entries, or nil if neither has the channel
+func appendChannel(data []float32, other []float32, components int, count uint32, otherCount uint32) []float32 {
+	if data == nil && other == nil {
+		return nil
+	}
+	retVal := make([]float32, components*int(count+otherCount))
+	copy(retVal[:components*int(count)], data)
+	copy(retVal[components*int(count):], other)
+	return retVal
+}
+
+func appendNamed(channels map[string]Channel, other map[string]Channel, count uint32, otherCount uint32) map[string]Channel {
+	if len(other) == 0 && len(channels) == 0 {
+		return channels
+	}
+	if channels == nil {
+		channels = make(map[string]Channel, len(other))
+	}
+	for name, c := range channels {
+		var data []float32
+		if o, ok := other[name]; ok && o.Components == c.Components {
+			data = o.Data
+		}
+		channels[name] = Channel{Components: c.Components, Data: appendChannel(c.Data, data, c.Components, count, otherCount)}
+	}
+	for name, o := range other {
+		if _, ok := channels[name]; !ok {
+			channels[name] = Channel{Components: o.Components, Data: appendChannel(nil, o.Data, o.Components, count, otherCount)}
+		}
+	}
+	return channels
+}
+
+// normalize scales n to unit length in place, unless it is too short to have a direction
+func normalize(n []float32) {
+	norm := math.Sqrt(float64(n[0]*n[0] + n[1]*n[1] + n[2]*n[2]))
+	if norm < 1e-6 {
+		return
+	}
+	for i := range n {
+		n[i] = float32(float64(n[i]) / norm)
+	}
+}
+
+// grow appends the entries of one vertex or one triangle, zeroed, to the channels
+func (a *Attributes) grow(vertex bool) {
+	pad := func(name string, data []float32, components int) []float32 {
+		return append(data, make([]float32, components)...)
+	}
+	if vertex {
+		a.forEachVertexChannel(pad)
+	} else {
+		a.forEachFaceChannel(pad, func(materials []uint32) []uint32 { return append(materials, 0) })
+	}
+}
+
+// swapRemoveFace moves the face attributes of the last of numTriangles triangles onto
+// triangle t and drops the last entry, as RemoveTriangle does with the indices
+func (a *Attributes) swapRemoveFace(t uint32, numTriangles uint32) {
+	last := numTriangles - 1
+	a.forEachFaceChannel(func(name string, data []float32, components int) []float32 {
+		if components*int(numTriangles) != len(data) {
+			return data
+		}
+		copy(data[components*int(t):components*int(t+1)], data[components*int(last):])
+		return data[:components*int(last)]
+	}, func(materials []uint32) []uint32 {
+		if int(numTriangles) != len(materials) {
+			return materials
+		}
+		materials[t] = materials[last]
+		return materials[:last]
+	})
+}
@@ -0,0 +1,222 @@
+package cloudmesh
+
+import (
+	"fmt"
+	"testing"
+)
+
+// createAttributedQuad creates a square of 2 triangles and a fifth, unused vertex.
+// Every vertex has the color (v, v, v, 1), a UV of (v, 0) and a "weight" of 10 v,
+// and triangle t has the material 7 + t and a "label" of t.
+func createAttributedQuad() IndexedMesh {
+	m := IndexedMesh{
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0, 5, 5, 5},
+		Indices:  []uint32{0, 1, 2, 0, 2, 3},
+	}
+	for v := 0; v < 5; v++ {
+		f := float32(v)
+		m.Attributes.Normals = append(m.Attributes.Normals, 0, 0, 1)
+		m.Attributes.Colors = append(m.Attributes.Colors, f, f, f, 1)
+		m.Attributes.UVs = append(m.Attributes.UVs, f, 0)
+	}
+	m.Attributes.Materials = []uint32{7, 8}
+	m.Attributes.VertexChannels = map[string]Channel{"weight": {Components: 1, Data: []float32{0, 10, 20, 30, 40}}}
+	m.Attributes.FaceChannels = map[string]Channel{"label": {Components: 1, Data: []float32{0, 1}}}
+	return m
+}
+
+func TestAttributesCheck(t *testing.T) {
+	m := createAttributedQuad()
+	if err := m.Attributes.Check(m.GetNumVertices(), m.GetNumFacets()); err != nil {
+		t.Errorf("Expected consistent attributes, got %v", err)
+	}
+	if !(Attributes{}).Empty() || m.Attributes.Empty() {
+		t.Error("Empty is wrong")
+	}
+	m.Attributes.UVs = m.Attributes.UVs[:8]
+	if err := m.Attributes.Check(m.GetNumVertices(), m.GetNumFacets()); err == nil {
+		t.Error("Expected an error for a short channel")
+	}
+	m = createAttributedQuad()
+	m.Attributes.FaceChannels["label"] = Channel{Components: 0, Data: nil}
+	if err := m.Attributes.Check(m.GetNumVertices(), m.GetNumFacets()); err == nil {
+		t.Error("Expected an error for a channel of no components")
+	}
+}
+
+func TestAttributesRemoveTriangle(t *testing.T) {
+	m := createAttributedQuad()
+	m.RemoveTriangle(0)
+	if fmt.Sprint(m.Attributes.Materials) != "[8]" || fmt.Sprint(m.Attributes.FaceChannels["label"].Data) != "[1]" {
+		t.Errorf("Expected the face attributes of the last triangle, got %v", m.Attributes)
+	}
+
+	m = createAttributedQuad()
+	m.Pop()
+	if fmt.Sprint(m.Attributes.Materials) != "[8]" {
+		t.Errorf("Expected Pop to drop the first material, got %v", m.Attributes.Materials)
+	}
+
+	m = createAttributedQuad()
+	m.AddTriangle(1, 2, 4)
+	if _, err := m.RemoveTriangles([]uint32{1}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(m.Attributes.Materials) != "[7 0]" || fmt.Sprint(m.Attributes.FaceChannels["label"].Data) != "[0 0]" {
+		t.Errorf("Expected the materials of triangles 0 and 2, got %v", m.Attributes)
+	}
+	if err := m.Attributes.Check(m.GetNumVertices(), m.GetNumFacets()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAttributesAddVertex(t *testing.T) {
+	m := createAttributedQuad()
+	v := m.AddVertex(2, 2, 2)
+	if err := m.Attributes.Check(m.GetNumVertices(), m.GetNumFacets()); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(m.Attributes.Colors[4*v:]) != "[0 0 0 0]" {
+		t.Errorf("Expected a zeroed color, got %v", m.Attributes.Colors[4*v:])
+	}
+
+	// a mesh without attributes gets none
+	bare := *NewMesh()
+	bare.AddVertex(0, 0, 0)
+	bare.AddTriangle(0, 0, 0)
+	if !bare.Attributes.Empty() {
+		t.Errorf("Expected no attributes, got %v", bare.Attributes)
+	}
+}
+
+func TestAttributesCompact(t *testing.T) {
+	m := createAttributedQuad()
+	m.RemoveTriangle(1)
+	compact, r := m.Compact(true)
+	if err := compact.Attributes.Check(compact.GetNumVertices(), compact.GetNumFacets()); err != nil {
+		t.Fatal(err)
+	}
+	for v, w := range r.Vertices {
+		if w == Removed {
+			continue
+		}
+		if compact.Attributes.UVs[2*w] != m.Attributes.UVs[2*v] || compact.Attributes.VertexChannels["weight"].Data[w] != m.Attributes.VertexChannels["weight"].Data[v] {
+			t.Errorf("Expected vertex %d to keep its attributes at %d", v, w)
+		}
+	}
+	if fmt.Sprint(compact.Attributes.Materials) != "[7]" {
+		t.Errorf("Expected the material of the kept triangle, got %v", compact.Attributes.Materials)
+	}
+
+	// the named channels of the copy are its own
+	compact.Attributes.VertexChannels["weight"].Data[0] = -1
+	delete(compact.Attributes.FaceChannels, "label")
+	if m.Attributes.VertexChannels["weight"].Data[0] != 0 || len(m.Attributes.FaceChannels) != 1 {
+		t.Error("Expected Compact to copy the channels")
+	}
+}
+
+func TestAttributesWeld(t *testing.T) {
+	// two triangles with their own vertices along the diagonal, and a sliver that
+	// collapses in the weld
+	m := IndexedMesh{
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 0, 0, 1, 1, 0, 0, 1, 0, 1, 1, 1e-5},
+		Indices:  []uint32{0, 1, 2, 3, 4, 5, 2, 4, 6},
+	}
+	m.Attributes.UVs = []float32{0, 0, 1, 0, 1, 1, 0, 0, 1, 1, 0, 1, 1, 1}
+	m.Attributes.Materials = []uint32{1, 2, 3}
+	welded, merged := m.Weld(1e-3)
+	if merged != 3 {
+		t.Fatalf("Expected 3 merged vertices, got %d", merged)
+	}
+	if err := welded.Attributes.Check(welded.GetNumVertices(), welded.GetNumFacets()); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(welded.Attributes.UVs) != "[0 0 1 0 1 1 0 1]" || fmt.Sprint(welded.Attributes.Materials) != "[1 2]" {
+		t.Errorf("Expected the attributes of the representatives, got %v and %v", welded.Attributes.UVs, welded.Attributes.Materials)
+	}
+
+	// a seam of the texture coordinates at vertex 4 keeps it apart from vertex 2, and
+	// the sliver still collapses onto vertex 2, which has the texture coordinates of 6
+	m.Attributes.UVs[8], m.Attributes.UVs[9] = 8, 8
+	welded, merged = m.Weld(1e-3)
+	if merged != 2 {
+		t.Fatalf("Expected 2 merged vertices across the seam, got %d", merged)
+	}
+	if fmt.Sprint(welded.Attributes.UVs) != "[0 0 1 0 1 1 8 8 0 1]" || fmt.Sprint(welded.Indices) != "[0 1 2 0 3 4]" {
+		t.Errorf("Expected the seam to stay, got %v and %v", welded.Attributes.UVs, welded.Indices)
+	}
+
+	// welding exact positions only merges vertex 3 into vertex 0
+	if welded, merged = m.Weld(0); merged != 1 || fmt.Sprint(welded.Indices) != "[0 1 2 0 3 4 2 3 5]" {
+		t.Errorf("Expected vertex 3 merged into 0 only, got %d and %v", merged, welded.Indices)
+	}
+}
+
+func TestResample(t *testing.T) {
+	m := createAttributedQuad()
+	m.Attributes.Normals[0], m.Attributes.Normals[2] = 1, 0
+	a := m.Attributes.Resample([][3]uint32{{0, 1, 2}, {4, 4, 4}}, [][3]float32{{.5, .5, 0}, {1, 0, 0}}, []uint32{1, 1, 0})
+	if err := a.Check(2, 3); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(a.Colors) != "[0.5 0.5 0.5 1 4 4 4 1]" || fmt.Sprint(a.VertexChannels["weight"].Data) != "[5 40]" {
+		t.Errorf("Expected interpolated vertex attributes, got %v and %v", a.Colors, a.VertexChannels["weight"].Data)
+	}
+	if n := a.Normals[:3]; n[0] < .7 || n[0] > .71 || n[2] < .7 || n[2] > .71 {
+		t.Errorf("Expected a normalized normal, got %v", n)
+	}
+	if fmt.Sprint(a.Materials) != "[8 8 7]" {
+		t.Errorf("Expected the materials of the source triangles, got %v", a.Materials)
+	}
+}
+
+func TestAttributesSelect(t *testing.T) {
+	m := createAttributedQuad()
+	a := m.Attributes.Select([]uint32{4, 2}, []uint32{1})
+	if err := a.Check(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(a.UVs) != "[4 0 2 0]" || fmt.Sprint(a.VertexChannels["weight"].Data) != "[40 20]" {
+		t.Errorf("Expected the attributes of vertices 4 and 2, got %v and %v", a.UVs, a.VertexChannels["weight"].Data)
+	}
+	if fmt.Sprint(a.Materials) != "[8]" || fmt.Sprint(a.FaceChannels["label"].Data) != "[1]" {
+		t.Errorf("Expected the attributes of triangle 1, got %v and %v", a.Materials, a.FaceChannels["label"].Data)
+	}
+	if len(m.Attributes.VertexChannels["weight"].Data) != 5 {
+		t.Error("Expected the channels of the mesh to be left alone")
+	}
+
+	copied := m.Attributes.Copy()
+	copied.Colors[0] = 9
+	copied.Materials[0] = 9
+	copied.VertexChannels["weight"].Data[0] = 9
+	if m.Attributes.Colors[0] != 0 || m.Attributes.Materials[0] != 7 || m.Attributes.VertexChannels["weight"].Data[0] != 0 {
+		t.Error("Expected the copy to share nothing with the mesh")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	m := createAttributedQuad()
+	// a triangle with normals and a "weight" of its own, but no other channel
+	other := IndexedMesh{Vertices: []float32{0, 0, 1, 1, 0, 1, 0, 1, 1}, Indices: []uint32{0, 1, 2}}
+	other.Attributes.Normals = []float32{1, 0, 0, 1, 0, 0, 1, 0, 0}
+	other.Attributes.VertexChannels = map[string]Channel{"weight": {Components: 1, Data: []float32{1, 2, 3}}, "extra": {Components: 2, Data: []float32{1, 1, 2, 2, 3, 3}}}
+
+	m.Append(other)
+	if m.GetNumVertices() != 8 || m.GetNumFacets() != 3 || fmt.Sprint(m.Indices[6:]) != "[5 6 7]" {
+		t.Fatalf("Expected the triangle to move to vertices 5 to 7, got %v", m.Indices)
+	}
+	if err := m.Attributes.Check(8, 3); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(m.Attributes.Normals[15:]) != "[1 0 0 1 0 0 1 0 0]" || fmt.Sprint(m.Attributes.VertexChannels["weight"].Data) != "[0 10 20 30 40 1 2 3]" {
+		t.Errorf("Expected the appended vertex attributes, got %v and %v", m.Attributes.Normals, m.Attributes.VertexChannels["weight"].Data)
+	}
+	if fmt.Sprint(m.Attributes.UVs[10:]) != "[0 0 0 0 0 0]" || fmt.Sprint(m.Attributes.Materials) != "[7 8 0]" {
+		t.Errorf("Expected zeroed attributes for the channels the triangle does not have, got %v and %v", m.Attributes.UVs, m.Attributes.Materials)
+	}
+	if fmt.Sprint(m.Attributes.VertexChannels["extra"].Data) != "[0 0 0 0 0 0 0 0 0 0 1 1 2 2 3 3]" {
+		t.Errorf("Expected a new channel zeroed for the vertices of the quad, got %v", m.Attributes.VertexChannels["extra"].Data)
+	}
+}
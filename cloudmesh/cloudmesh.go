@@ -3,12 +3,16 @@ package cloudmesh
 
 import (
 	"errors"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
 )
 
 // A data structure that houses a mesh that has been indexed
 type IndexedMesh struct {
 	Indices  []uint32
 	Vertices []float32
+	// Attributes are the optional normals, colors, materials, etc. of the mesh
+	Attributes Attributes
 }
 
 // NewMesh returns a pointer to an initialized mesh
@@ -44,7 +48,7 @@ func (m IndexedMesh) GetVertices(triangle uint32) ([]uint32, error) {
 
 // Given a vertex return a slice of points
 func (m IndexedMesh) GetPoint(vertex uint32) ([]float32, error) {
-	if vertex > m.GetNumVertices() {
+	if vertex >= m.GetNumVertices() {
 		return []float32{0, 0, 0}, errors.New("GetPoint:requested index is out of bounds")
 	}
 
@@ -53,6 +57,22 @@ func (m IndexedMesh) GetPoint(vertex uint32) ([]float32, error) {
 		m.Vertices[3*vertex+2]}, nil
 }
 
+// Given an index of a triangle, return its vertices without allocating
+func (m IndexedMesh) GetTriangle(triangle uint32) ([3]uint32, error) {
+	if triangle >= m.GetNumFacets() {
+		return [3]uint32{}, errors.New("GetTriangle:requested index is out of bounds")
+	}
+	return [3]uint32{m.Indices[3*triangle], m.Indices[3*triangle+1], m.Indices[3*triangle+2]}, nil
+}
+
+// Given a vertex, return its position without allocating
+func (m IndexedMesh) GetPointVec3(vertex uint32) (auxmath.Vec3, error) {
+	if vertex >= m.GetNumVertices() {
+		return auxmath.Vec3{}, errors.New("GetPointVec3:requested index is out of bounds")
+	}
+	return auxmath.Vec3{m.Vertices[3*vertex], m.Vertices[3*vertex+1], m.Vertices[3*vertex+2]}, nil
+}
+
 // Given an index(triangle) remove a triangle from the mesh
 func (m *IndexedMesh) RemoveTriangle(triangle uint32) { //O(1), 0-based index
 	//swap with last element and pop_back
@@ -65,17 +85,112 @@ func (m *IndexedMesh) RemoveTriangle(triangle uint32) { //O(1), 0-based index
 	for i := 0; i < 3; i++ {
 		m.Indices = m.Indices[:len(m.Indices)-1] //pop last element of a slice
 	}
+	m.Attributes.swapRemoveFace(triangle, m.GetNumFacets()+1)
 }
 
 func (m *IndexedMesh) Pop() {
 	for i := 0; i < 3; i++ {
 		m.Indices = m.Indices[1:]
 	}
+	m.Attributes.forEachFaceChannel(func(name string, data []float32, components int) []float32 {
+		if len(data) < components {
+			return data
+		}
+		return data[components:]
+	}, func(materials []uint32) []uint32 {
+		if len(materials) == 0 {
+			return materials
+		}
+		return materials[1:]
+	})
 }
 
-// Append a triangle to the mesh
+// Append a triangle to the mesh, with zeroed face attributes
 func (m *IndexedMesh) AddTriangle(v1 uint32, v2 uint32, v3 uint32) { //O(1)
 	m.Indices = append(m.Indices, v1, v2, v3)
+	m.Attributes.grow(false)
+}
+
+// Append a vertex to the mesh, with zeroed vertex attributes, and return its index
+func (m *IndexedMesh) AddVertex(x float32, y float32, z float32) uint32 { //O(1)
+	m.Vertices = append(m.Vertices, x, y, z)
+	m.Attributes.grow(true)
+	return m.GetNumVertices() - 1
+}
+
+// Move a vertex to the point p
+func (m *IndexedMesh) SetPoint(vertex uint32, p []float32) error {
+	if vertex >= m.GetNumVertices() {
+		return errors.New("SetPoint:requested index is out of bounds")
+	}
+	if len(p) != 3 {
+		return errors.New("SetPoint:a point has 3 coordinates")
+	}
+	copy(m.Vertices[3*vertex:3*vertex+3], p)
+	return nil
+}
+
+// Replace the vertices of a triangle
+func (m *IndexedMesh) SetVertices(triangle uint32, vertices []uint32) error {
+	if triangle >= m.GetNumFacets() {
+		return errors.New("SetVertices:requested index is out of bounds")
+	}
+	if len(vertices) != 3 {
+		return errors.New("SetVertices:a triangle has 3 vertices")
+	}
+	copy(m.Indices[3*triangle:3*triangle+3], vertices)
+	return nil
+}
+
+// Remove a batch of triangles, keeping the others in order, and return the new index
+// of every triangle (Removed for the removed ones).  Unlike RemoveTriangle, the
+// triangles that are kept are only shifted down.
+func (m *IndexedMesh) RemoveTriangles(triangles []uint32) ([]uint32, error) { //O(n)
+	numTris := m.GetNumFacets()
+	newIndex := make([]uint32, numTris)
+	for _, t := range triangles {
+		if t >= numTris {
+			return nil, errors.New("RemoveTriangles:requested index is out of bounds")
+		}
+		newIndex[t] = Removed
+	}
+	kept := uint32(0)
+	for t := uint32(0); t < numTris; t++ {
+		if newIndex[t] == Removed {
+			continue
+		}
+		newIndex[t] = kept
+		copy(m.Indices[3*kept:3*kept+3], m.Indices[3*t:3*t+3])
+		kept++
+	}
+	m.Indices = m.Indices[:3*kept]
+	m.Attributes = m.Attributes.remap(nil, 0, newIndex, kept)
+	return newIndex, nil
+}
+
+// Make room for numTriangles triangles and numVertices vertices in total
+func (m *IndexedMesh) Reserve(numTriangles uint32, numVertices uint32) {
+	if int(3*numTriangles) > cap(m.Indices) {
+		indices := make([]uint32, len(m.Indices), 3*numTriangles)
+		copy(indices, m.Indices)
+		m.Indices = indices
+	}
+	if int(3*numVertices) > cap(m.Vertices) {
+		vertices := make([]float32, len(m.Vertices), 3*numVertices)
+		copy(vertices, m.Vertices)
+		m.Vertices = vertices
+	}
+}
+
+// Append the vertices and triangles of other to the mesh, with their attributes.  A
+// channel that only one of the meshes has is zeroed for the entries of the other one.
+func (m *IndexedMesh) Append(other IndexedMesh) {
+	offset := m.GetNumVertices()
+	m.Attributes.append(other.Attributes, offset, m.GetNumFacets(), other.GetNumVertices(), other.GetNumFacets())
+	m.Vertices = append(m.Vertices, other.Vertices...)
+	for _, v := range other.Indices {
+		m.Indices = append(m.Indices, v+offset)
+	}
 }
 
 // Return the index of the last triangle in the mesh
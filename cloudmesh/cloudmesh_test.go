@@ -2,6 +2,7 @@ package cloudmesh
 
 import (
 	"log"
+	"math/rand"
 	"testing"
 )
 
@@ -71,6 +72,10 @@ func TestGetPoint(t *testing.T) {
 	if err == nil {
 		t.Errorf("Expected an error as the point requested is out of bounds.")
 	}
+	// and where it is one past the last vertex
+	if _, err = testMesh.GetPoint(3); err == nil {
+		t.Errorf("Expected an error as the point requested is one past the last vertex.")
+	}
 }
 
 func TestRemoveTriangle(t *testing.T) {
@@ -115,20 +120,22 @@ func TestAddTriangle(t *testing.T) {
 	}
 }
 
-/*
 func TestAddVertex(t *testing.T) {
 	theseVertices := []float32{0, 0, 1, 1, 1, 1}
 	theseTriangles := []uint32{2, 1, 9, 2, 2, 2}
 	testMesh := IndexedMesh{Indices: theseTriangles, Vertices: theseVertices}
 
 	vertexIndex := testMesh.AddVertex(1, 1, 1)
-	if vertexIndex != 1 {
-		t.Error("Expected 1, got:", vertexIndex)
-	}
-	vertexIndex = testMesh.AddVertex(0, 1, 0)
 	if vertexIndex != 2 {
 		t.Error("Expected 2, got:", vertexIndex)
 	}
+	vertexIndex = testMesh.AddVertex(0, 1, 0)
+	if vertexIndex != 3 {
+		t.Error("Expected 3, got:", vertexIndex)
+	}
+	if point, _ := testMesh.GetPoint(3); point[0] != 0 || point[1] != 1 || point[2] != 0 {
+		t.Error("Expected 0 1 0, got:", point)
+	}
 }
 
 func BenchmarkAddVertex(b *testing.B) {
@@ -140,7 +147,85 @@ func BenchmarkAddVertex(b *testing.B) {
 		testMesh.AddVertex(rand.Float32(), 1, 1)
 	}
 }
-*/
+
+func TestSetPoint(t *testing.T) {
+	testMesh := IndexedMesh{Indices: []uint32{0, 1, 2}, Vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}}
+	if err := testMesh.SetPoint(1, []float32{5, 6, 7}); err != nil {
+		t.Fatal(err)
+	}
+	if point, _ := testMesh.GetPoint(1); point[0] != 5 || point[1] != 6 || point[2] != 7 {
+		t.Error("Expected 5 6 7, got:", point)
+	}
+	if err := testMesh.SetPoint(3, []float32{5, 6, 7}); err == nil {
+		t.Error("Expected an error as the point is out of bounds.")
+	}
+	if err := testMesh.SetPoint(0, []float32{5, 6}); err == nil {
+		t.Error("Expected an error for a point of 2 coordinates.")
+	}
+}
+
+func TestSetVertices(t *testing.T) {
+	testMesh := IndexedMesh{Indices: []uint32{0, 1, 2, 2, 1, 3}, Vertices: make([]float32, 12)}
+	if err := testMesh.SetVertices(1, []uint32{3, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if vertices, _ := testMesh.GetVertices(1); vertices[0] != 3 || vertices[1] != 1 || vertices[2] != 2 {
+		t.Error("Expected 3 1 2, got:", vertices)
+	}
+	if err := testMesh.SetVertices(2, []uint32{0, 1, 2}); err == nil {
+		t.Error("Expected an error as the triangle is out of bounds.")
+	}
+	if err := testMesh.SetVertices(0, []uint32{0, 1}); err == nil {
+		t.Error("Expected an error for a triangle of 2 vertices.")
+	}
+}
+
+func TestRemoveTriangles(t *testing.T) {
+	theseTriangles := []uint32{0, 1, 2, 1, 2, 3, 2, 3, 4, 3, 4, 5, 4, 5, 6}
+	testMesh := IndexedMesh{Indices: theseTriangles, Vertices: make([]float32, 21)}
+	newIndex, err := testMesh.RemoveTriangles([]uint32{3, 0, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedIndices := []uint32{1, 2, 3, 2, 3, 4, 4, 5, 6}
+	if len(testMesh.Indices) != len(expectedIndices) {
+		t.Fatal("Expected 3 triangles, got:", testMesh.GetNumFacets())
+	}
+	for i := range expectedIndices {
+		if testMesh.Indices[i] != expectedIndices[i] {
+			t.Errorf("Expected %v at index %d and got %v", expectedIndices[i], i, testMesh.Indices[i])
+		}
+	}
+	expectedNewIndex := []uint32{Removed, 0, 1, Removed, 2}
+	for i := range expectedNewIndex {
+		if newIndex[i] != expectedNewIndex[i] {
+			t.Errorf("Expected triangle %d to become %v and got %v", i, expectedNewIndex[i], newIndex[i])
+		}
+	}
+	if testMesh.GetNumVertices() != 7 {
+		t.Error("Expected the vertices to be kept, got:", testMesh.GetNumVertices())
+	}
+	if _, err := testMesh.RemoveTriangles([]uint32{3}); err == nil {
+		t.Error("Expected an error as the triangle is out of bounds.")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	testMesh := IndexedMesh{Indices: []uint32{0, 1, 2}, Vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}}
+	testMesh.Reserve(10, 20)
+	if cap(testMesh.Indices) != 30 || cap(testMesh.Vertices) != 60 {
+		t.Error("Expected room for 10 triangles and 20 vertices, got:", cap(testMesh.Indices), cap(testMesh.Vertices))
+	}
+	if testMesh.GetNumFacets() != 1 || testMesh.GetNumVertices() != 3 || testMesh.Vertices[3] != 1 {
+		t.Error("Expected the mesh to be kept")
+	}
+	// reserving less than the capacity changes nothing
+	testMesh.Reserve(1, 1)
+	if cap(testMesh.Indices) != 30 || cap(testMesh.Vertices) != 60 {
+		t.Error("Expected the capacity to be kept, got:", cap(testMesh.Indices), cap(testMesh.Vertices))
+	}
+}
+
 func TestLastTriangle(t *testing.T) {
 	theseVertices := []float32{0, 0, 1, 1, 1, 1}
 	theseTriangles := []uint32{2, 1, 9, 2, 2, 2}
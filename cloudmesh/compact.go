@@ -0,0 +1,72 @@
+package cloudmesh
+
+import (
+	"math"
+)
+
+// Removed is the new index of a vertex or triangle that Compact dropped
+const Removed = math.MaxUint32
+
+// Reindex maps the old indices of the vertices and triangles of a mesh to their new
+// indices, or Removed, so that per-vertex attributes and per-triangle labels can
+// follow the mesh
+type Reindex struct {
+	Vertices  []uint32
+	Triangles []uint32
+}
+
+// Compact returns a copy of the mesh without unreferenced vertices, nor triangles that
+// reference a vertex out of range, and the maps from the old indices to the new ones.
+// The vertices keep their order, or are numbered in the order the triangles first use
+// them with byFirstUse, which keeps the vertices of neighboring triangles close in memory.
+// The attributes of the mesh follow their vertices and triangles.
+func (m IndexedMesh) Compact(byFirstUse bool) (IndexedMesh, Reindex) {
+	numVertices := m.GetNumVertices()
+	r := Reindex{Vertices: make([]uint32, numVertices), Triangles: make([]uint32, m.GetNumFacets())}
+	for v := range r.Vertices {
+		r.Vertices[v] = Removed
+	}
+
+	numTris, numUsed := uint32(0), uint32(0)
+	for t := range r.Triangles {
+		tri := m.Indices[3*t : 3*t+3]
+		if tri[0] >= numVertices || tri[1] >= numVertices || tri[2] >= numVertices {
+			r.Triangles[t] = Removed
+			continue
+		}
+		r.Triangles[t] = numTris
+		numTris++
+		for _, v := range tri {
+			if r.Vertices[v] == Removed {
+				r.Vertices[v] = numUsed
+				numUsed++
+			}
+		}
+	}
+	if !byFirstUse {
+		numUsed = 0
+		for v, w := range r.Vertices {
+			if w != Removed {
+				r.Vertices[v] = numUsed
+				numUsed++
+			}
+		}
+	}
+
+	retVal := IndexedMesh{Indices: make([]uint32, 0, 3*numTris), Vertices: make([]float32, 3*numUsed)}
+	for v, w := range r.Vertices {
+		if w != Removed {
+			copy(retVal.Vertices[3*w:3*w+3], m.Vertices[3*v:3*v+3])
+		}
+	}
+	for t, u := range r.Triangles {
+		if u == Removed {
+			continue
+		}
+		for _, v := range m.Indices[3*t : 3*t+3] {
+			retVal.Indices = append(retVal.Indices, r.Vertices[v])
+		}
+	}
+	retVal.Attributes = m.Attributes.remap(r.Vertices, numUsed, r.Triangles, numTris)
+	return retVal, r
+}
@@ -0,0 +1,62 @@
+package cloudmesh
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	// vertices 0 and 3 are unreferenced, and triangle 2 references a vertex out of range
+	m := IndexedMesh{
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 2, 0, 0, 3, 0, 0, 4, 0, 0, 5, 0, 0},
+		Indices:  []uint32{5, 2, 1, 1, 4, 5, 4, 9, 1},
+	}
+	cases := []struct {
+		byFirstUse bool
+		indices    string
+		vertices   string
+		reindex    string
+	}{
+		{false, "[3 1 0 0 2 3]", "[1 0 0 2 0 0 4 0 0 5 0 0]", "{[4294967295 0 1 4294967295 2 3] [0 1 4294967295]}"},
+		{true, "[0 1 2 2 3 0]", "[5 0 0 2 0 0 1 0 0 4 0 0]", "{[4294967295 2 1 4294967295 3 0] [0 1 4294967295]}"},
+	}
+	for _, c := range cases {
+		compact, r := m.Compact(c.byFirstUse)
+		if fmt.Sprint(compact.Indices) != c.indices || fmt.Sprint(compact.Vertices) != c.vertices {
+			t.Errorf("byFirstUse %v: expected %v and %v, got %v and %v", c.byFirstUse, c.indices, c.vertices, compact.Indices, compact.Vertices)
+		}
+		if fmt.Sprint(r) != c.reindex {
+			t.Errorf("byFirstUse %v: expected the maps %v, got %v", c.byFirstUse, c.reindex, r)
+		}
+		// the maps carry the positions and triangles over
+		for v, w := range r.Vertices {
+			if w == Removed {
+				continue
+			}
+			p, _ := m.GetPoint(uint32(v))
+			q, _ := compact.GetPoint(w)
+			if fmt.Sprint(p) != fmt.Sprint(q) {
+				t.Errorf("byFirstUse %v: expected vertex %d at %v, got %v", c.byFirstUse, v, p, q)
+			}
+		}
+	}
+
+	// compacting twice changes nothing
+	once, _ := m.Compact(true)
+	twice, r := once.Compact(false)
+	if fmt.Sprint(once) != fmt.Sprint(twice) || fmt.Sprint(r) != "{[0 1 2 3] [0 1]}" {
+		t.Errorf("Expected a compact mesh to be left alone, got %v", r)
+	}
+}
+
+func TestCompactAfterRemoveTriangle(t *testing.T) {
+	m := IndexedMesh{
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0},
+		Indices:  []uint32{0, 1, 2, 0, 2, 3},
+	}
+	m.RemoveTriangle(1)
+	compact, r := m.Compact(false)
+	if compact.GetNumVertices() != 3 || r.Vertices[3] != Removed {
+		t.Errorf("Expected vertex 3 to be dropped, got %v", r.Vertices)
+	}
+}
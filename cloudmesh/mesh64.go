@@ -0,0 +1,128 @@
+package cloudmesh
+
+import (
+	"errors"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+)
+
+// IndexedMesh64 is an indexed mesh with double precision vertices, e.g., for
+// georeferenced scans whose coordinates are in the millions.  It is a mesh.Mesh,
+// whose GetPoint rounds to float32, and a mesh.Mesh64, whose GetPoint64 does not.
+type IndexedMesh64 struct {
+	Indices  []uint32
+	Vertices []float64
+}
+
+// NewMesh64 returns a pointer to an initialized double precision mesh
+func NewMesh64() *IndexedMesh64 {
+	return &IndexedMesh64{Indices: make([]uint32, 0), Vertices: make([]float64, 0)}
+}
+
+// Get the number of triangles in the mesh
+func (m IndexedMesh64) GetNumFacets() uint32 {
+	return uint32(len(m.Indices) / 3)
+}
+
+// Get the number of vertices in the mesh
+func (m IndexedMesh64) GetNumVertices() uint32 {
+	return uint32(len(m.Vertices) / 3)
+}
+
+// Given an index of a triangle, return its vertices
+func (m IndexedMesh64) GetVertices(triangle uint32) ([]uint32, error) {
+	if triangle >= m.GetNumFacets() {
+		return []uint32{0, 0, 0}, errors.New("GetVertices:requested index is out of bounds")
+	}
+	return []uint32{m.Indices[3*triangle], m.Indices[3*triangle+1], m.Indices[3*triangle+2]}, nil
+}
+
+// Given an index of a triangle, return its vertices without allocating
+func (m IndexedMesh64) GetTriangle(triangle uint32) ([3]uint32, error) {
+	if triangle >= m.GetNumFacets() {
+		return [3]uint32{}, errors.New("GetTriangle:requested index is out of bounds")
+	}
+	return [3]uint32{m.Indices[3*triangle], m.Indices[3*triangle+1], m.Indices[3*triangle+2]}, nil
+}
+
+// Given a vertex, return its position rounded to float32
+func (m IndexedMesh64) GetPoint(vertex uint32) ([]float32, error) {
+	p, err := m.GetPointVec3(vertex)
+	return p.Slice(), err
+}
+
+// Given a vertex, return its position rounded to float32 without allocating
+func (m IndexedMesh64) GetPointVec3(vertex uint32) (auxmath.Vec3, error) {
+	if vertex >= m.GetNumVertices() {
+		return auxmath.Vec3{}, errors.New("GetPointVec3:requested index is out of bounds")
+	}
+	return auxmath.Vec3{float32(m.Vertices[3*vertex]), float32(m.Vertices[3*vertex+1]), float32(m.Vertices[3*vertex+2])}, nil
+}
+
+// Given a vertex, return its position in double precision
+func (m IndexedMesh64) GetPoint64(vertex uint32) ([3]float64, error) {
+	if vertex >= m.GetNumVertices() {
+		return [3]float64{}, errors.New("GetPoint64:requested index is out of bounds")
+	}
+	return [3]float64{m.Vertices[3*vertex], m.Vertices[3*vertex+1], m.Vertices[3*vertex+2]}, nil
+}
+
+// Append a vertex to the mesh and return its index
+func (m *IndexedMesh64) AddVertex(x float64, y float64, z float64) uint32 {
+	m.Vertices = append(m.Vertices, x, y, z)
+	return m.GetNumVertices() - 1
+}
+
+// Append a triangle to the mesh
+func (m *IndexedMesh64) AddTriangle(v1 uint32, v2 uint32, v3 uint32) {
+	m.Indices = append(m.Indices, v1, v2, v3)
+}
+
+// ToIndexedMesh64 returns the mesh in double precision, which is exact
+func (m IndexedMesh) ToIndexedMesh64() IndexedMesh64 {
+	retVal := IndexedMesh64{Indices: append([]uint32{}, m.Indices...), Vertices: make([]float64, len(m.Vertices))}
+	for i, c := range m.Vertices {
+		retVal.Vertices[i] = float64(c)
+	}
+	return retVal
+}
+
+// ToIndexedMesh returns the mesh in single precision, for the algorithms and files
+// that only take float32.  Recenter it first to keep the precision of coordinates
+// far from the origin.
+func (m IndexedMesh64) ToIndexedMesh() IndexedMesh {
+	retVal := IndexedMesh{Indices: append([]uint32{}, m.Indices...), Vertices: make([]float32, len(m.Vertices))}
+	for i, c := range m.Vertices {
+		retVal.Vertices[i] = float32(c)
+	}
+	return retVal
+}
+
+// Recenter returns a copy of the mesh moved so that the center of its bounding box
+// is at the origin, and that center: a vertex p of the input is at p - center in the
+// copy.  Near the origin float32 keeps the precision that coordinates in the millions lose.
+func (m IndexedMesh64) Recenter() (IndexedMesh64, [3]float64) {
+	var center [3]float64
+	if m.GetNumVertices() > 0 {
+		low := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+		high := [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+		for i, c := range m.Vertices {
+			if !math.IsNaN(c) && !math.IsInf(c, 0) {
+				low[i%3], high[i%3] = math.Min(low[i%3], c), math.Max(high[i%3], c)
+			}
+		}
+		for i := range center {
+			center[i] = (low[i] + high[i]) / 2
+			if math.IsNaN(center[i]) {
+				// no finite coordinate
+				center[i] = 0
+			}
+		}
+	}
+	retVal := IndexedMesh64{Indices: append([]uint32{}, m.Indices...), Vertices: make([]float64, len(m.Vertices))}
+	for i, c := range m.Vertices {
+		retVal.Vertices[i] = c - center[i%3]
+	}
+	return retVal, center
+}
@@ -0,0 +1,75 @@
+package cloudmesh
+
+import (
+	"math"
+	"testing"
+)
+
+// createFarTriangle creates a triangle with millimeter edges around (x, y, z)
+func createFarTriangle(x float64, y float64, z float64) IndexedMesh64 {
+	m := NewMesh64()
+	a := m.AddVertex(x, y, z)
+	b := m.AddVertex(x+.001, y, z)
+	c := m.AddVertex(x, y+.001, z+.002)
+	m.AddTriangle(a, b, c)
+	return *m
+}
+
+func TestIndexedMesh64(t *testing.T) {
+	m := createFarTriangle(5e6, 2e6, 100)
+	if m.GetNumFacets() != 1 || m.GetNumVertices() != 3 {
+		t.Fatalf("Expected 1 triangle and 3 vertices, got %d and %d", m.GetNumFacets(), m.GetNumVertices())
+	}
+	if vertices, err := m.GetVertices(0); err != nil || vertices[1] != 1 {
+		t.Errorf("Expected the vertices 0 1 2, got %v: %v", vertices, err)
+	}
+	if _, err := m.GetVertices(1); err == nil {
+		t.Error("Expected an error as the triangle is out of bounds")
+	}
+	p, err := m.GetPoint64(1)
+	if err != nil || p != [3]float64{5e6 + .001, 2e6, 100} {
+		t.Errorf("Expected the exact point, got %v: %v", p, err)
+	}
+	// float32 rounds the millimeter away
+	q, err := m.GetPoint(1)
+	if err != nil || q[0] != 5e6 {
+		t.Errorf("Expected the point rounded to float32, got %v: %v", q, err)
+	}
+	if _, err := m.GetPoint64(3); err == nil {
+		t.Error("Expected an error as the point is out of bounds")
+	}
+}
+
+func TestRecenter(t *testing.T) {
+	m := createFarTriangle(5e6, 2e6, 100)
+	recentered, center := m.Recenter()
+	expected := [3]float64{5e6 + .0005, 2e6 + .0005, 100.001}
+	for i := range center {
+		if math.Abs(center[i]-expected[i]) > 1e-9 {
+			t.Errorf("Expected the center of the bounding box %v, got %v", expected, center)
+		}
+	}
+	// the float32 copy of the recentered mesh keeps the millimeters
+	single := recentered.ToIndexedMesh()
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		p, _ := m.GetPoint64(v)
+		q, _ := single.GetPoint(v)
+		for i := 0; i < 3; i++ {
+			if d := math.Abs(float64(q[i]) + center[i] - p[i]); d > 1e-8 {
+				t.Errorf("Expected vertex %d at %v, off by %v", v, p, d)
+			}
+		}
+	}
+	// converting to double precision and back is exact
+	back := single.ToIndexedMesh64().ToIndexedMesh()
+	for i := range back.Vertices {
+		if back.Vertices[i] != single.Vertices[i] {
+			t.Errorf("Expected %v and got %v", single.Vertices[i], back.Vertices[i])
+		}
+	}
+
+	empty, center := NewMesh64().Recenter()
+	if empty.GetNumVertices() != 0 || center != [3]float64{} {
+		t.Errorf("Expected an empty mesh to stay at the origin, got %v", center)
+	}
+}
@@ -0,0 +1,110 @@
+package cloudmesh
+
+import (
+	"math"
+)
+
+// Weld returns a copy of the mesh where vertices within epsilon of each other are
+// merged, and the number of vertices merged away.  Each vertex is merged into the
+// nearest earlier vertex within epsilon that was kept and has the same vertex
+// attributes, so a merged group spans at most epsilon from the vertex that represents
+// it.  Vertices whose attributes differ, e.g., across a seam of the texture
+// coordinates, stay apart.  Triangles that lose a corner in the merge are dropped.
+// Vertices with a NaN or infinite coordinate are never merged.
+//
+// An epsilon of 0 merges the vertices at exactly the same position only.
+func (m IndexedMesh) Weld(epsilon float32) (IndexedMesh, int) {
+	numVertices := m.GetNumVertices()
+	newIndex := make([]uint32, numVertices)
+	retVal := IndexedMesh{Indices: make([]uint32, 0, len(m.Indices)), Vertices: make([]float32, 0, len(m.Vertices))}
+	// kept holds the input vertex each vertex of retVal comes from
+	kept := make([]uint32, 0, numVertices)
+	keep := func(v uint32) uint32 {
+		retVal.Vertices = append(retVal.Vertices, m.Vertices[3*v:3*v+3]...)
+		kept = append(kept, v)
+		return retVal.GetNumVertices() - 1
+	}
+	attributes := !m.Attributes.Empty()
+	same := func(v uint32, w uint32) bool {
+		return !attributes || m.Attributes.sameVertex(v, kept[w])
+	}
+
+	if epsilon <= 0 {
+		exact := make(map[[3]float32][]uint32, numVertices)
+		for v := uint32(0); v < numVertices; v++ {
+			key := [3]float32{m.Vertices[3*v], m.Vertices[3*v+1], m.Vertices[3*v+2]}
+			newIndex[v] = Removed
+			for _, w := range exact[key] {
+				if same(v, w) {
+					newIndex[v] = w
+					break
+				}
+			}
+			if newIndex[v] == Removed {
+				newIndex[v] = keep(v)
+				exact[key] = append(exact[key], newIndex[v])
+			}
+		}
+	} else {
+		// a grid of cells of size epsilon: the vertices within epsilon of a vertex are
+		// in its cell or in the 26 around it
+		grid := make(map[[3]int64][]uint32, numVertices)
+		eps := float64(epsilon)
+		for v := uint32(0); v < numVertices; v++ {
+			p := m.Vertices[3*v : 3*v+3]
+			var cell [3]int64
+			finite := true
+			for i, c := range p {
+				f := math.Floor(float64(c) / eps)
+				if math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > math.MaxInt64/2 {
+					finite = false
+					break
+				}
+				cell[i] = int64(f)
+			}
+			if !finite {
+				newIndex[v] = keep(v)
+				continue
+			}
+
+			nearest, nearestDistance := uint32(0), math.Inf(1)
+			for dx := int64(-1); dx <= 1; dx++ {
+				for dy := int64(-1); dy <= 1; dy++ {
+					for dz := int64(-1); dz <= 1; dz++ {
+						for _, w := range grid[[3]int64{cell[0] + dx, cell[1] + dy, cell[2] + dz}] {
+							q := retVal.Vertices[3*w : 3*w+3]
+							x, y, z := float64(p[0]-q[0]), float64(p[1]-q[1]), float64(p[2]-q[2])
+							if d := math.Sqrt(x*x + y*y + z*z); d <= eps && d < nearestDistance && same(v, w) {
+								nearest, nearestDistance = w, d
+							}
+						}
+					}
+				}
+			}
+			if math.IsInf(nearestDistance, 1) {
+				nearest = keep(v)
+				grid[cell] = append(grid[cell], nearest)
+			}
+			newIndex[v] = nearest
+		}
+	}
+
+	triangles := make([]uint32, m.GetNumFacets())
+	for t := range triangles {
+		tri := m.Indices[3*t : 3*t+3]
+		triangles[t] = retVal.GetNumFacets()
+		if tri[0] >= numVertices || tri[1] >= numVertices || tri[2] >= numVertices {
+			// leave the indices out of range as they are, for validation to find
+			retVal.Indices = append(retVal.Indices, tri...)
+			continue
+		}
+		a, b, d := newIndex[tri[0]], newIndex[tri[1]], newIndex[tri[2]]
+		if (a == b || b == d || d == a) && !(tri[0] == tri[1] || tri[1] == tri[2] || tri[2] == tri[0]) {
+			triangles[t] = Removed
+			continue
+		}
+		retVal.Indices = append(retVal.Indices, a, b, d)
+	}
+	retVal.Attributes = m.Attributes.remap(newIndex, retVal.GetNumVertices(), triangles, retVal.GetNumFacets())
+	return retVal, int(numVertices - retVal.GetNumVertices())
+}
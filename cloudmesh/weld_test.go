@@ -0,0 +1,73 @@
+package cloudmesh
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// createSplitGrid creates a grid of n x n squares where every triangle has vertices
+// of its own, the i-th corner moved by i jitter
+func createSplitGrid(n int, jitter float32) IndexedMesh {
+	m := IndexedMesh{Indices: make([]uint32, 0), Vertices: make([]float32, 0)}
+	corner := func(x int, y int) {
+		j := jitter * float32(len(m.Indices))
+		m.Vertices = append(m.Vertices, float32(x)+j, float32(y)-j, j)
+		m.Indices = append(m.Indices, m.GetNumVertices()-1)
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			corner(x, y)
+			corner(x+1, y)
+			corner(x+1, y+1)
+			corner(x, y)
+			corner(x+1, y+1)
+			corner(x, y+1)
+		}
+	}
+	return m
+}
+
+func TestWeld(t *testing.T) {
+	cases := []struct {
+		jitter      float32
+		epsilon     float32
+		numVertices uint32
+	}{
+		{0, 0, 16},
+		{1e-6, 0, 54},
+		{1e-6, 1e-4, 16},
+		{1e-4, 1e-4, 54},
+	}
+	for _, c := range cases {
+		split := createSplitGrid(3, c.jitter)
+		welded, numMerged := split.Weld(c.epsilon)
+		if welded.GetNumVertices() != c.numVertices || numMerged != 54-int(c.numVertices) {
+			t.Errorf("jitter %v, epsilon %v: expected %d vertices, got %d (%d merged)", c.jitter, c.epsilon, c.numVertices, welded.GetNumVertices(), numMerged)
+		}
+		if welded.GetNumFacets() != 18 {
+			t.Errorf("jitter %v, epsilon %v: expected 18 triangles, got %d", c.jitter, c.epsilon, welded.GetNumFacets())
+		}
+		for i, v := range welded.Indices {
+			p, _ := welded.GetPoint(v)
+			q, _ := split.GetPoint(split.Indices[i])
+			if d := math.Abs(float64(p[0]-q[0])) + math.Abs(float64(p[1]-q[1])) + math.Abs(float64(p[2]-q[2])); d > 3*float64(c.epsilon) {
+				t.Errorf("jitter %v, epsilon %v: corner %d moved by %v", c.jitter, c.epsilon, i, d)
+			}
+		}
+	}
+}
+
+func TestWeldCollapse(t *testing.T) {
+	// the small triangle collapses, the others are kept, and so are the invalid vertex
+	// and the indices out of range
+	nan := float32(math.NaN())
+	m := IndexedMesh{
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0, 1e-5, 1e-5, 0, nan, 0, 0, nan, 0, 0},
+		Indices:  []uint32{0, 1, 2, 0, 3, 1, 4, 5, 0, 0, 1, 9},
+	}
+	welded, numMerged := m.Weld(1e-3)
+	if numMerged != 1 || fmt.Sprint(welded.Indices) != "[0 1 2 3 4 0 0 1 9]" {
+		t.Errorf("Expected the triangle (0, 3, 1) to collapse, got %v (%d merged)", welded.Indices, numMerged)
+	}
+}
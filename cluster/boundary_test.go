@@ -0,0 +1,78 @@
+package cluster
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func boundaryPoints(t *testing.T, m cloudmesh.IndexedMesh) [][3]float32 {
+	edges, err := mesh.FindBoundaryEdges(m, mesh.CreateNeighborhood(m))
+	if err != nil {
+		t.Fatalf("Error finding the boundary: %v", err)
+	}
+	boundary, _ := mesh.BoundaryVertices(m, edges)
+	points := make([][3]float32, 0)
+	for v, b := range boundary {
+		if b {
+			p, _ := m.GetPoint(uint32(v))
+			points = append(points, [3]float32{p[0], p[1], p[2]})
+		}
+	}
+	return points
+}
+
+// closest returns the distance from p to the closest vertex of the mesh
+func closest(m cloudmesh.IndexedMesh, p [3]float32) float64 {
+	best := math.MaxFloat64
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		q, _ := m.GetPoint(v)
+		dx, dy, dz := float64(p[0]-q[0]), float64(p[1]-q[1]), float64(p[2]-q[2])
+		best = math.Min(best, math.Sqrt(dx*dx+dy*dy+dz*dz))
+	}
+	return best
+}
+
+func TestLockBoundary(t *testing.T) {
+	tile := shape.Grid(20, 3)
+	before := boundaryPoints(t, tile)
+	for _, r := range []Representative{Average, Quadric} {
+		result, err := Simplify(tile, Options{CellSize: 4, Representative: r, LockBoundary: true})
+		if err != nil {
+			t.Fatalf("Simplify failed: %v", err)
+		}
+		if result.GetNumFacets() >= tile.GetNumFacets() {
+			t.Errorf("Expected the interior to be simplified, got %d triangles", result.GetNumFacets())
+		}
+		for _, p := range before {
+			if closest(result, p) != 0 {
+				t.Errorf("Boundary vertex %v moved", p)
+			}
+		}
+		if after := boundaryPoints(t, result); len(after) != len(before) {
+			t.Errorf("Expected %d boundary vertices, got %d", len(before), len(after))
+		}
+	}
+}
+
+func TestBoundaryTolerance(t *testing.T) {
+	tile := shape.Grid(20, 3)
+	before := boundaryPoints(t, tile)
+	const tolerance = 2
+	result, err := Simplify(tile, Options{CellSize: 5, LockBoundary: true, BoundaryTolerance: tolerance})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	after := boundaryPoints(t, result)
+	if len(after) >= len(before) {
+		t.Errorf("Expected the tolerance to let the boundary simplify, got %d vertices out of %d", len(after), len(before))
+	}
+	for _, p := range before {
+		if d := closest(result, p); d > tolerance {
+			t.Errorf("Boundary vertex %v moved by %v", p, d)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package cluster implements vertex clustering decimation (Rossignac and Borrel):
+// the vertices are snapped to the cells of a uniform grid, every cell is replaced
+// by a single representative vertex, and the triangles that collapse are dropped.
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/qem"
+)
+
+// Representative selects how the vertex of a cell is placed
+type Representative int
+
+const (
+	// Average places the vertex at the average of the vertices in the cell
+	Average Representative = iota
+	// Quadric places the vertex where it minimizes the quadric error of the
+	// triangles around the cell, which keeps sharp features.  It falls back to the
+	// average when the quadric has no unique minimum inside the cell.
+	Quadric
+)
+
+// Options configures a vertex clustering
+type Options struct {
+	// CellSize is the edge length of the cells of the grid
+	CellSize float32
+	// Representative selects how the vertex of a cell is placed
+	Representative Representative
+	// LockBoundary keeps the boundary vertices of an open mesh where they are, each
+	// in a cell of its own, so that adjacent tiles still line up after simplification
+	LockBoundary bool
+	// BoundaryTolerance relaxes LockBoundary: the boundary vertices are clustered
+	// among themselves, in cells small enough that none moves further than this
+	BoundaryTolerance float32
+}
+
+type cellKey [3]int32
+
+// cell accumulates what is known of the vertices that fall in a grid cell
+type cell struct {
+	index    uint32 // index of the cell's vertex in the output mesh
+	sum      [3]float64
+	count    uint32
+	quadric  qem.Quadric
+	boundary bool // a cell of locked boundary vertices, which are averaged
+}
+
+// Simplify clusters the vertices of the mesh in a single pass over its triangles.
+// The memory used grows with the number of occupied cells and output triangles,
+// plus one bit per input vertex, not with the size of the input.  LockBoundary
+// needs another pass, and memory, to find the boundary first.
+func Simplify(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, error) {
+	if !(opts.CellSize > 0) {
+		return *cloudmesh.NewMesh(), errors.New("cluster.Simplify: the cell size must be positive")
+	}
+	cellSize := float64(opts.CellSize)
+
+	var locked []bool
+	var boundaryCellSize float64
+	if opts.LockBoundary {
+		edges, err := mesh.FindBoundaryEdges(m, mesh.CreateNeighborhood(m))
+		if err != nil {
+			return *cloudmesh.NewMesh(), fmt.Errorf("cluster.Simplify: %v", err)
+		}
+		if locked, err = mesh.BoundaryVertices(m, edges); err != nil {
+			return *cloudmesh.NewMesh(), fmt.Errorf("cluster.Simplify: %v", err)
+		}
+		// the diagonal of a boundary cell is at most the tolerance
+		boundaryCellSize = math.Min(cellSize, float64(opts.BoundaryTolerance)/math.Sqrt(3))
+	}
+	boundaryCells := make(map[cellKey]*cell)
+	vertexCells := make(map[uint32]*cell)
+
+	cells := make(map[cellKey]*cell)
+	ordered := make([]*cell, 0)
+	// visited has a bit per input vertex so that each one is averaged in only once
+	visited := make([]uint64, (m.GetNumVertices()+63)/64)
+	triangles := make(map[[3]uint32]bool)
+	retVal := cloudmesh.IndexedMesh{Indices: make([]uint32, 0), Vertices: make([]float32, 0)}
+
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return *cloudmesh.NewMesh(), fmt.Errorf("cluster.Simplify: %v", err)
+		}
+		var points [3][3]float64
+		var triCells [3]*cell
+		for i := 0; i < 3; i++ {
+			if vertices[i] >= m.GetNumVertices() {
+				return *cloudmesh.NewMesh(), fmt.Errorf("cluster.Simplify: triangle %d references vertex %d out of bounds", t, vertices[i])
+			}
+			p, err := m.GetPoint(vertices[i])
+			if err != nil {
+				return *cloudmesh.NewMesh(), fmt.Errorf("cluster.Simplify: %v", err)
+			}
+			points[i] = [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}
+			var c *cell
+			var ok bool
+			switch {
+			case locked == nil || !locked[vertices[i]]:
+				key := keyOf(points[i], cellSize)
+				if c, ok = cells[key]; !ok {
+					c = &cell{index: uint32(len(ordered))}
+					cells[key] = c
+				}
+			case boundaryCellSize > 0:
+				key := keyOf(points[i], boundaryCellSize)
+				if c, ok = boundaryCells[key]; !ok {
+					c = &cell{index: uint32(len(ordered)), boundary: true}
+					boundaryCells[key] = c
+				}
+			default:
+				if c, ok = vertexCells[vertices[i]]; !ok {
+					c = &cell{index: uint32(len(ordered)), boundary: true}
+					vertexCells[vertices[i]] = c
+				}
+			}
+			if !ok {
+				ordered = append(ordered, c)
+			}
+			triCells[i] = c
+
+			word, bit := vertices[i]/64, uint64(1)<<(vertices[i]%64)
+			if visited[word]&bit == 0 {
+				visited[word] |= bit
+				for coord := 0; coord < 3; coord++ {
+					c.sum[coord] += points[i][coord]
+				}
+				c.count++
+			}
+		}
+
+		if opts.Representative == Quadric {
+			n, area := auxmath.TriangleNormal(points[0], points[1], points[2])
+			if area > 0 {
+				q := qem.PlaneQuadric(n, -n.Dot(points[0]), area)
+				for i := 0; i < 3; i++ {
+					// a cell holding two corners of the triangle only gets its plane once
+					if i > 0 && triCells[i] == triCells[0] || i > 1 && triCells[i] == triCells[1] {
+						continue
+					}
+					triCells[i].quadric.Add(q)
+				}
+			}
+		}
+
+		a, b, c := triCells[0].index, triCells[1].index, triCells[2].index
+		if a == b || b == c || c == a {
+			// the triangle collapsed into an edge or a point
+			continue
+		}
+		// the same output triangle can come from many input triangles
+		key := [3]uint32{a, b, c}
+		if b < a && b < c {
+			key = [3]uint32{b, c, a}
+		} else if c < a && c < b {
+			key = [3]uint32{c, a, b}
+		}
+		if triangles[key] {
+			continue
+		}
+		triangles[key] = true
+		retVal.AddTriangle(a, b, c)
+	}
+
+	retVal.Vertices = make([]float32, 0, 3*len(ordered))
+	for _, c := range ordered {
+		p := c.position(opts.Representative, cellSize)
+		retVal.Vertices = append(retVal.Vertices, float32(p[0]), float32(p[1]), float32(p[2]))
+	}
+	return retVal, nil
+}
+
+func keyOf(p [3]float64, cellSize float64) cellKey {
+	return cellKey{
+		int32(math.Floor(p[0] / cellSize)),
+		int32(math.Floor(p[1] / cellSize)),
+		int32(math.Floor(p[2] / cellSize))}
+}
+
+// position returns the representative vertex of the cell
+func (c *cell) position(r Representative, cellSize float64) [3]float64 {
+	average := [3]float64{c.sum[0] / float64(c.count), c.sum[1] / float64(c.count), c.sum[2] / float64(c.count)}
+	if r != Quadric || c.boundary {
+		return average
+	}
+	p, ok := c.quadric.Minimizer()
+	if !ok {
+		return average
+	}
+	// a minimizer outside of the cell comes from a badly conditioned quadric
+	for i := 0; i < 3; i++ {
+		min := math.Floor(average[i]/cellSize) * cellSize
+		if p[i] < min || p[i] > min+cellSize {
+			return average
+		}
+	}
+	return p
+}
+
+// CellSizeForResolution returns the cell size that divides the longest side of the
+// bounding box of the mesh into the given number of cells.  It takes a pass over the
+// vertices of the mesh.
+func CellSizeForResolution(m mesh.Mesh, resolution int) (float32, error) {
+	if resolution < 1 || m.GetNumVertices() == 0 {
+		return 0, errors.New("cluster.CellSizeForResolution: need a positive resolution and a non-empty mesh")
+	}
+	min := []float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+	max := []float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		p, err := m.GetPoint(v)
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < 3; i++ {
+			min[i] = float32(math.Min(float64(min[i]), float64(p[i])))
+			max[i] = float32(math.Max(float64(max[i]), float64(p[i])))
+		}
+	}
+	longest := float32(0)
+	for i := 0; i < 3; i++ {
+		if max[i]-min[i] > longest {
+			longest = max[i] - min[i]
+		}
+	}
+	if longest == 0 {
+		return 1, nil
+	}
+	return longest / float32(resolution), nil
+}
@@ -0,0 +1,78 @@
+package cluster
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func meanRadius(m cloudmesh.IndexedMesh) float64 {
+	sum := float64(0)
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		p, _ := m.GetPoint(v)
+		sum += math.Sqrt(float64(p[0]*p[0] + p[1]*p[1] + p[2]*p[2]))
+	}
+	return sum / float64(m.GetNumVertices())
+}
+
+func TestSimplifyCube(t *testing.T) {
+	cube := shape.BasicCube()
+	// every corner has its own cell, so nothing changes
+	result, err := Simplify(cube, Options{CellSize: 60})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	if result.GetNumFacets() != 12 || result.GetNumVertices() != 8 {
+		t.Errorf("Expected 12 triangles and 8 vertices, got %v and %v", result.GetNumFacets(), result.GetNumVertices())
+	}
+
+	// one cell for everything leaves nothing
+	result, _ = Simplify(cube, Options{CellSize: 1000})
+	if result.GetNumFacets() != 0 {
+		t.Errorf("Expected no triangles and got %v", result.GetNumFacets())
+	}
+
+	if _, err := Simplify(cube, Options{}); err == nil {
+		t.Error("Expected an error for a cell size of 0")
+	}
+}
+
+func TestSimplifySphere(t *testing.T) {
+	sphere := shape.Sphere(5000, 100)
+	average, err := Simplify(sphere, Options{CellSize: 25})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	if average.GetNumFacets() == 0 || average.GetNumFacets() >= sphere.GetNumFacets()/4 {
+		t.Errorf("Expected far fewer triangles than %v, got %v", sphere.GetNumFacets(), average.GetNumFacets())
+	}
+	for tri := uint32(0); tri < average.GetNumFacets(); tri++ {
+		v, _ := average.GetVertices(tri)
+		if v[0] == v[1] || v[1] == v[2] || v[2] == v[0] {
+			t.Errorf("Triangle %v is degenerate: %v", tri, v)
+		}
+	}
+
+	// averaging pulls the vertices inside the sphere, the quadrics keep them on it
+	quadric, _ := Simplify(sphere, Options{CellSize: 25, Representative: Quadric})
+	if quadric.GetNumFacets() != average.GetNumFacets() {
+		t.Errorf("Expected the representative not to change the triangles, got %v and %v",
+			quadric.GetNumFacets(), average.GetNumFacets())
+	}
+	if math.Abs(meanRadius(quadric)-100) > math.Abs(meanRadius(average)-100) {
+		t.Errorf("Expected the quadric vertices (mean radius %v) to be closer to the sphere than the averages (%v)",
+			meanRadius(quadric), meanRadius(average))
+	}
+}
+
+func TestCellSizeForResolution(t *testing.T) {
+	cellSize, err := CellSizeForResolution(shape.BasicCube(), 10)
+	if err != nil || cellSize != 10 {
+		t.Errorf("Expected a cell size of 10 and got %v, %v", cellSize, err)
+	}
+	if _, err := CellSizeForResolution(shape.BasicCube(), 0); err == nil {
+		t.Error("Expected an error for a resolution of 0")
+	}
+}
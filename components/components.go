@@ -0,0 +1,211 @@
+// Package components finds the connected components of a mesh and splits it into
+// one mesh per component, e.g., to simplify the parts of an assembly one by one.
+package components
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// Connectivity is what connects two triangles of a component
+type Connectivity int
+
+const (
+	// EdgeConnected triangles share an edge
+	EdgeConnected Connectivity = iota
+	// VertexConnected triangles share a vertex, so two parts touching at a corner
+	// are a single component
+	VertexConnected
+)
+
+// Component is a connected component of a mesh
+type Component struct {
+	Mesh cloudmesh.IndexedMesh
+	// Triangles are the indices in the input of the triangles of the component
+	Triangles []uint32
+	// Vertices are the indices in the input of the vertices of the component
+	Vertices []uint32
+	Area     float64
+	// Volume is the volume enclosed by the triangles, positive when they face outward.
+	// It is only meaningful for a closed component.
+	Volume float64
+}
+
+// NumTriangles returns the number of triangles of the component
+func (c Component) NumTriangles() int {
+	return len(c.Triangles)
+}
+
+// Label returns the component of each triangle and the number of components.  The
+// components are numbered in the order of their first triangle.
+func Label(m mesh.Mesh, connectivity Connectivity) ([]uint32, int, error) {
+	labels, numComponents, err := label(m, connectivity)
+	if err != nil {
+		return nil, 0, fmt.Errorf("components.Label: %v", err)
+	}
+	return labels, numComponents, nil
+}
+
+func label(m mesh.Mesh, connectivity Connectivity) ([]uint32, int, error) {
+	numTris := m.GetNumFacets()
+	for t := uint32(0); t < numTris; t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, v := range vertices {
+			if v >= m.GetNumVertices() {
+				return nil, 0, fmt.Errorf("triangle %d references a vertex out of bounds", t)
+			}
+		}
+	}
+	if connectivity != EdgeConnected && connectivity != VertexConnected {
+		return nil, 0, errors.New("unknown connectivity")
+	}
+
+	neighborhood := mesh.CreateNeighborhood(m)
+	labels := make([]uint32, numTris)
+	for t := range labels {
+		labels[t] = math.MaxUint32
+	}
+	numComponents := 0
+	stack := make([]uint32, 0)
+	for seed := uint32(0); seed < numTris; seed++ {
+		if labels[seed] != math.MaxUint32 {
+			continue
+		}
+		component := uint32(numComponents)
+		labels[seed] = component
+		stack = append(stack[:0], seed)
+		for len(stack) > 0 {
+			t := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			var neighbors []uint32
+			if connectivity == EdgeConnected {
+				neighbors, _ = neighborhood.GetTriangleNeighborsOfTriangle(t)
+			} else {
+				vertices, _ := m.GetVertices(t)
+				for _, v := range vertices {
+					tris, _ := neighborhood.GetTriangleNeighborsOfVertex(v)
+					neighbors = append(neighbors, tris...)
+				}
+			}
+			for _, n := range neighbors {
+				if labels[n] == math.MaxUint32 {
+					labels[n] = component
+					stack = append(stack, n)
+				}
+			}
+		}
+		numComponents++
+	}
+	return labels, numComponents, nil
+}
+
+// Split returns the components of the mesh, in the order of their first triangle.
+// The vertices of each component keep their relative order, and the components of a
+// cloudmesh.IndexedMesh keep the attributes of their vertices and triangles.
+func Split(m mesh.Mesh, connectivity Connectivity) ([]Component, error) {
+	labels, numComponents, err := label(m, connectivity)
+	if err != nil {
+		return nil, fmt.Errorf("components.Split: %v", err)
+	}
+	components := make([]Component, numComponents)
+	for t, label := range labels {
+		components[label].Triangles = append(components[label].Triangles, uint32(t))
+	}
+
+	// the components take the attributes of their vertices and triangles
+	var attributes cloudmesh.Attributes
+	switch im := m.(type) {
+	case cloudmesh.IndexedMesh:
+		attributes = im.Attributes
+	case *cloudmesh.IndexedMesh:
+		attributes = im.Attributes
+	}
+
+	// newIndex maps the vertices of the input to the vertices of the current component
+	newIndex := make([]uint32, m.GetNumVertices())
+	for v := range newIndex {
+		newIndex[v] = math.MaxUint32
+	}
+	for i := range components {
+		c := &components[i]
+		c.Mesh = *cloudmesh.NewMesh()
+		c.Vertices = make([]uint32, 0)
+		for _, t := range c.Triangles {
+			vertices, _ := m.GetVertices(t)
+			for _, v := range vertices {
+				if newIndex[v] == math.MaxUint32 {
+					newIndex[v] = 0
+					c.Vertices = append(c.Vertices, v)
+				}
+			}
+		}
+		sort.Slice(c.Vertices, func(a, b int) bool { return c.Vertices[a] < c.Vertices[b] })
+		c.Mesh.Reserve(uint32(len(c.Triangles)), uint32(len(c.Vertices)))
+		for w, v := range c.Vertices {
+			newIndex[v] = uint32(w)
+			p, _ := m.GetPoint(v)
+			c.Mesh.AddVertex(p[0], p[1], p[2])
+		}
+		for _, t := range c.Triangles {
+			vertices, _ := m.GetVertices(t)
+			c.Mesh.AddTriangle(newIndex[vertices[0]], newIndex[vertices[1]], newIndex[vertices[2]])
+		}
+		for _, v := range c.Vertices {
+			newIndex[v] = math.MaxUint32
+		}
+		c.Mesh.Attributes = attributes.Select(c.Vertices, c.Triangles)
+		c.Area, c.Volume = measure(c.Mesh)
+	}
+	return components, nil
+}
+
+// Filter returns the components with at least minTriangles triangles and an area of
+// at least minArea, e.g., to drop the specks of a scan
+func Filter(components []Component, minTriangles int, minArea float64) []Component {
+	retVal := make([]Component, 0, len(components))
+	for _, c := range components {
+		if c.NumTriangles() >= minTriangles && c.Area >= minArea {
+			retVal = append(retVal, c)
+		}
+	}
+	return retVal
+}
+
+// Merge returns a single mesh made of the meshes of the components, in order, with
+// their attributes
+func Merge(components []Component) cloudmesh.IndexedMesh {
+	numTris, numVertices := uint32(0), uint32(0)
+	for _, c := range components {
+		numTris += c.Mesh.GetNumFacets()
+		numVertices += c.Mesh.GetNumVertices()
+	}
+	retVal := *cloudmesh.NewMesh()
+	retVal.Reserve(numTris, numVertices)
+	for _, c := range components {
+		retVal.Append(c.Mesh)
+	}
+	return retVal
+}
+
+// measure returns the area of the mesh and the volume it encloses
+func measure(m cloudmesh.IndexedMesh) (float64, float64) {
+	area, volume := 0.0, 0.0
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		q, _ := mesh.GetTrianglePoints64(m, t)
+		p := [3]auxmath.Vec3d{q[0], q[1], q[2]}
+		_, a := auxmath.TriangleNormal(p[0], p[1], p[2])
+		area += a
+		// the signed volume of the tetrahedron of the triangle and the origin
+		volume += p[0].Dot(p[1].Cross(p[2])) / 6
+	}
+	return area, volume
+}
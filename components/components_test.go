@@ -0,0 +1,146 @@
+package components
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/validate"
+)
+
+// createAssembly creates two cubes touching at a corner, and a small triangle apart
+func createAssembly() cloudmesh.IndexedMesh {
+	cube := shape.BasicCube()
+	m := cloudmesh.IndexedMesh{Indices: append([]uint32{}, cube.Indices...), Vertices: append([]float32{}, cube.Vertices...)}
+	// the second cube starts at the corner (100, 100, 100), vertex 6 of the first
+	for v := 1; v < 8; v++ {
+		p := cube.Vertices[3*v : 3*v+3]
+		m.Vertices = append(m.Vertices, p[0]+100, p[1]+100, p[2]+100)
+	}
+	for _, v := range cube.Indices {
+		if v == 0 {
+			m.Indices = append(m.Indices, 6)
+		} else {
+			m.Indices = append(m.Indices, v+7)
+		}
+	}
+	m.Vertices = append(m.Vertices, -10, 0, 0, -11, 0, 0, -10, 1, 0)
+	m.Indices = append(m.Indices, 15, 16, 17)
+	return m
+}
+
+func TestLabel(t *testing.T) {
+	m := createAssembly()
+	labels, numComponents, err := Label(m, EdgeConnected)
+	if err != nil || numComponents != 3 {
+		t.Fatalf("Expected 3 edge connected components, got %d: %v", numComponents, err)
+	}
+	for tri, label := range labels {
+		if expected := uint32(tri / 12); label != expected {
+			t.Errorf("Expected triangle %d in component %d, got %d", tri, expected, label)
+		}
+	}
+	labels, numComponents, err = Label(m, VertexConnected)
+	if err != nil || numComponents != 2 || labels[0] != labels[23] || labels[24] != 1 {
+		t.Errorf("Expected the cubes to be a single vertex connected component, got %d components: %v", numComponents, err)
+	}
+
+	bad := cloudmesh.IndexedMesh{Indices: []uint32{0, 1, 7}, Vertices: make([]float32, 9)}
+	if _, _, err := Label(bad, EdgeConnected); err == nil {
+		t.Error("Expected an error for a vertex out of bounds")
+	}
+	if _, _, err := Label(m, Connectivity(7)); err == nil {
+		t.Error("Expected an error for an unknown connectivity")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	m := createAssembly()
+	components, err := Split(m, EdgeConnected)
+	if err != nil || len(components) != 3 {
+		t.Fatalf("Expected 3 components, got %d: %v", len(components), err)
+	}
+	for i, c := range components[:2] {
+		if c.NumTriangles() != 12 || c.Mesh.GetNumVertices() != 8 {
+			t.Errorf("Expected component %d to be a cube, got %d triangles and %d vertices", i, c.NumTriangles(), c.Mesh.GetNumVertices())
+		}
+		if math.Abs(c.Area-60000) > 1e-6 || math.Abs(c.Volume-1e6) > 1e-3 {
+			t.Errorf("Expected component %d to have an area of 60000 and a volume of 1e6, got %v and %v", i, c.Area, c.Volume)
+		}
+		if r := validate.Validate(c.Mesh); !r.Watertight || !r.Valid() {
+			t.Errorf("Expected component %d to be watertight, got %+v", i, r)
+		}
+		// the maps lead back to the input
+		for tri, input := range c.Triangles {
+			for corner := 0; corner < 3; corner++ {
+				if c.Vertices[c.Mesh.Indices[3*tri+corner]] != m.Indices[3*input+uint32(corner)] {
+					t.Errorf("Expected triangle %d of component %d to be triangle %d of the input", tri, i, input)
+				}
+			}
+		}
+	}
+	if fmt.Sprint(components[1].Vertices) != "[6 8 9 10 11 12 13 14]" {
+		t.Errorf("Expected the second cube to share vertex 6, got %v", components[1].Vertices)
+	}
+	if speck := components[2]; speck.NumTriangles() != 1 || math.Abs(speck.Area-.5) > 1e-6 {
+		t.Errorf("Expected a triangle of area .5, got %d triangles of area %v", speck.NumTriangles(), speck.Area)
+	}
+
+	// drop the speck and put the parts back together
+	kept := Filter(components, 2, 0)
+	if len(kept) != 2 || len(Filter(components, 0, 1)) != 2 || len(Filter(components, 0, 0)) != 3 {
+		t.Errorf("Expected the speck to be dropped, got %d components", len(kept))
+	}
+	merged := Merge(kept)
+	if r := validate.Validate(merged); merged.GetNumFacets() != 24 || merged.GetNumVertices() != 16 || !r.Watertight || r.NumComponents != 2 {
+		t.Errorf("Expected two separate cubes, got %+v", r)
+	}
+
+	components, err = Split(m, VertexConnected)
+	if err != nil || len(components) != 2 || components[0].Mesh.GetNumVertices() != 15 {
+		t.Errorf("Expected the cubes to share a vertex in a single component, got %d: %v", len(components), err)
+	}
+}
+
+func TestSplitAttributes(t *testing.T) {
+	// every vertex has a UV of (v, 0) and every triangle the material t
+	m := createAssembly()
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		m.Attributes.UVs = append(m.Attributes.UVs, float32(v), 0)
+	}
+	for tri := uint32(0); tri < m.GetNumFacets(); tri++ {
+		m.Attributes.Materials = append(m.Attributes.Materials, tri)
+	}
+	components, err := Split(&m, EdgeConnected)
+	if err != nil || len(components) != 3 {
+		t.Fatalf("Expected 3 components, got %d: %v", len(components), err)
+	}
+	for i, c := range components {
+		if err := c.Mesh.Attributes.Check(c.Mesh.GetNumVertices(), c.Mesh.GetNumFacets()); err != nil {
+			t.Fatalf("component %d: %v", i, err)
+		}
+		for w, v := range c.Vertices {
+			if c.Mesh.Attributes.UVs[2*w] != float32(v) {
+				t.Errorf("Expected vertex %d of component %d to have the UV of vertex %d, got %v", w, i, v, c.Mesh.Attributes.UVs[2*w])
+			}
+		}
+		for tri, input := range c.Triangles {
+			if c.Mesh.Attributes.Materials[tri] != input {
+				t.Errorf("Expected triangle %d of component %d to have the material %d, got %d", tri, i, input, c.Mesh.Attributes.Materials[tri])
+			}
+		}
+	}
+
+	merged := Merge(components)
+	if err := merged.Attributes.Check(merged.GetNumVertices(), merged.GetNumFacets()); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(merged.Attributes.Materials) != fmt.Sprint(m.Attributes.Materials) {
+		t.Errorf("Expected the materials of the input, got %v", merged.Attributes.Materials)
+	}
+	if merged.Attributes.UVs[2*8] != 6 {
+		t.Errorf("Expected the second cube to start with the UV of shared vertex 6, got %v", merged.Attributes.UVs[2*8])
+	}
+}
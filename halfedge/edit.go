@@ -0,0 +1,254 @@
+package halfedge
+
+import (
+	"errors"
+)
+
+// CanCollapse tells whether collapsing h keeps the mesh manifold: the vertices
+// adjacent to both ends of h must be the ones opposite to it (the link condition),
+// an interior edge must not join two boundary vertices, and no vertex may be left
+// without faces.
+func (hm *Mesh) CanCollapse(h uint32) bool {
+	if h >= hm.NumHalfEdges() || hm.faceRemoved[hm.Face(h)] {
+		return false
+	}
+	a, b := hm.Origin(h), hm.Dest(h)
+	t := hm.twin[h]
+	if t != Invalid && hm.IsBoundaryVertex(a) && hm.IsBoundaryVertex(b) {
+		return false
+	}
+	opposite := []uint32{hm.origin[hm.Prev(h)]}
+	if hm.twin[hm.Next(h)] == Invalid && hm.twin[hm.Prev(h)] == Invalid {
+		return false
+	}
+	if t != Invalid {
+		opposite = append(opposite, hm.origin[hm.Prev(t)])
+		if hm.twin[hm.Next(t)] == Invalid && hm.twin[hm.Prev(t)] == Invalid {
+			return false
+		}
+		// the faces (a, c, d) and (b, c, d) would become the same face
+		if hm.hasFace(a, opposite[0], opposite[1]) && hm.hasFace(b, opposite[0], opposite[1]) {
+			return false
+		}
+	}
+	neighborsA := hm.VertexNeighbors(a)
+	numCommon := 0
+	for _, w := range hm.VertexNeighbors(b) {
+		if !contains(neighborsA, w) {
+			continue
+		}
+		if !contains(opposite, w) {
+			return false
+		}
+		numCommon++
+	}
+	return numCommon == len(opposite)
+}
+
+// hasFace tells whether x, c and d make a face, in either orientation
+func (hm *Mesh) hasFace(x uint32, c uint32, d uint32) bool {
+	if h := hm.FindHalfEdge(c, d); h != Invalid && hm.origin[hm.Prev(h)] == x {
+		return true
+	}
+	h := hm.FindHalfEdge(d, c)
+	return h != Invalid && hm.origin[hm.Prev(h)] == x
+}
+
+// CollapseEdge merges the end of h into its start, moves the merged vertex to p and
+// removes the faces of the edge.  It returns the merged vertex.
+func (hm *Mesh) CollapseEdge(h uint32, p []float32) (uint32, error) {
+	if !hm.CanCollapse(h) {
+		return Invalid, errors.New("halfedge.CollapseEdge: the collapse would make the mesh non-manifold")
+	}
+	a, b, t := hm.Origin(h), hm.Dest(h), hm.twin[h]
+	// the vertices whose half-edge may be removed, with the half-edges that may replace it
+	affected := []uint32{a, hm.origin[hm.Prev(h)]}
+	candidates := [][]uint32{append(hm.OutgoingHalfEdges(a), hm.OutgoingHalfEdges(b)...), hm.OutgoingHalfEdges(affected[1])}
+	if t != Invalid {
+		affected = append(affected, hm.origin[hm.Prev(t)])
+		candidates = append(candidates, hm.OutgoingHalfEdges(affected[2]))
+	}
+	for _, out := range hm.OutgoingHalfEdges(b) {
+		hm.origin[out] = a
+	}
+	hm.removeFace(hm.Face(h))
+	if t != Invalid {
+		hm.removeFace(hm.Face(t))
+	}
+	hm.vertexRemoved[b] = true
+	hm.vertexHalfEdge[b] = Invalid
+	for i, v := range affected {
+		hm.resetVertexHalfEdge(v, candidates[i])
+	}
+	hm.SetPoint(a, p)
+	return a, nil
+}
+
+// removeFace flags face f as removed and makes the twins of its two remaining
+// edges twins of each other.  The ends of the collapsed edge of f are already merged.
+func (hm *Mesh) removeFace(f uint32) {
+	hm.faceRemoved[f] = true
+	var outer [2]uint32
+	n := 0
+	for i := uint32(0); i < 3; i++ {
+		h := 3*f + i
+		if hm.origin[h] == hm.Dest(h) {
+			continue // the collapsed edge
+		}
+		outer[n] = hm.twin[h]
+		n++
+	}
+	if outer[0] != Invalid {
+		hm.twin[outer[0]] = outer[1]
+	}
+	if outer[1] != Invalid {
+		hm.twin[outer[1]] = outer[0]
+	}
+	for i := uint32(0); i < 3; i++ {
+		hm.twin[3*f+i] = Invalid
+	}
+}
+
+// resetVertexHalfEdge picks a half-edge of v among the candidates that still leave it
+// and turns it clockwise to the boundary, if any
+func (hm *Mesh) resetVertexHalfEdge(v uint32, candidates []uint32) {
+	hm.vertexHalfEdge[v] = Invalid
+	for _, h := range candidates {
+		if !hm.faceRemoved[hm.Face(h)] && hm.origin[h] == v {
+			hm.vertexHalfEdge[v] = h
+			break
+		}
+	}
+	start := hm.vertexHalfEdge[v]
+	if start == Invalid {
+		return
+	}
+	for h := start; hm.twin[h] != Invalid; {
+		h = hm.Next(hm.twin[h])
+		if h == start {
+			return
+		}
+		hm.vertexHalfEdge[v] = h
+	}
+}
+
+// SplitEdge adds a vertex at p on the edge of h and splits each face of the edge
+// in two.  It returns the new vertex.
+func (hm *Mesh) SplitEdge(h uint32, p []float32) (uint32, error) {
+	if h >= hm.NumHalfEdges() || hm.faceRemoved[hm.Face(h)] {
+		return Invalid, errors.New("halfedge.SplitEdge: the half-edge belongs to a removed face")
+	}
+	m := hm.addVertex(p)
+	t := hm.twin[h]
+	e := hm.splitFace(h, m)
+	if t != Invalid {
+		// h runs from a to m and t from b to m now
+		e2 := hm.splitFace(t, m)
+		hm.link(h, e2)
+		hm.link(e, t)
+	}
+	hm.vertexHalfEdge[m] = e
+	return m, nil
+}
+
+// splitFace splits the face (a, b, c) of h, which runs from a to b, into (a, m, c)
+// and a new face (m, b, c), and returns the half-edge from m to b of the new face
+func (hm *Mesh) splitFace(h uint32, m uint32) uint32 {
+	next := hm.Next(h)
+	b, c := hm.origin[next], hm.origin[hm.Prev(h)]
+	e := 3 * hm.addFace(m, b, c)
+	outer := hm.twin[next]
+	hm.origin[next] = m
+	// (b, c) moves to the new face and (m, c) splits the old one
+	hm.link(e+1, outer)
+	hm.link(next, e+2)
+	if hm.vertexHalfEdge[b] == next {
+		hm.vertexHalfEdge[b] = e + 1
+	}
+	return e
+}
+
+// CanFlip tells whether h can be flipped: it must be an interior edge whose
+// opposite vertices are not already joined, and both of its ends must keep at
+// least three neighbors.
+func (hm *Mesh) CanFlip(h uint32) bool {
+	if h >= hm.NumHalfEdges() || hm.faceRemoved[hm.Face(h)] || hm.twin[h] == Invalid {
+		return false
+	}
+	t := hm.twin[h]
+	c, d := hm.origin[hm.Prev(h)], hm.origin[hm.Prev(t)]
+	if c == d || hm.FindHalfEdge(c, d) != Invalid || hm.FindHalfEdge(d, c) != Invalid {
+		return false
+	}
+	for _, v := range []uint32{hm.Origin(h), hm.Dest(h)} {
+		if len(hm.VertexNeighbors(v)) <= 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// FlipEdge replaces the edge of h, shared by the faces (a, b, c) and (b, a, d), with
+// the edge from c to d; the faces become (c, a, d) and (d, b, c).  h is then the
+// half-edge from d to c.
+func (hm *Mesh) FlipEdge(h uint32) error {
+	if !hm.CanFlip(h) {
+		return errors.New("halfedge.FlipEdge: the edge cannot be flipped")
+	}
+	t := hm.twin[h]
+	hn, hp, tn, tp := hm.Next(h), hm.Prev(h), hm.Next(t), hm.Prev(t)
+	a, b, c, d := hm.origin[h], hm.origin[t], hm.origin[hp], hm.origin[tp]
+	// the twins of (b, c), (c, a), (a, d) and (d, b)
+	bc, ca, ad, db := hm.twin[hn], hm.twin[hp], hm.twin[tn], hm.twin[tp]
+
+	hm.origin[h], hm.origin[hn], hm.origin[hp] = d, c, a
+	hm.origin[t], hm.origin[tn], hm.origin[tp] = c, d, b
+	// h, hn, hp run d-c, c-a, a-d and t, tn, tp run c-d, d-b, b-c
+	hm.link(h, t)
+	hm.link(hn, ca)
+	hm.link(hp, ad)
+	hm.link(tn, db)
+	hm.link(tp, bc)
+	// the half-edges that now leave a, b, c and d keep the twins, hence the boundary
+	// status, of the ones that left them before
+	changed := []uint32{h, hn, hp, t, tn, tp}
+	for i, v := range []uint32{a, b, c, d} {
+		if contains(changed, hm.vertexHalfEdge[v]) {
+			hm.vertexHalfEdge[v] = []uint32{hp, tp, hn, tn}[i]
+		}
+	}
+	return nil
+}
+
+// link makes h and t twins; either may be Invalid
+func (hm *Mesh) link(h uint32, t uint32) {
+	if h != Invalid {
+		hm.twin[h] = t
+	}
+	if t != Invalid {
+		hm.twin[t] = h
+	}
+}
+
+func (hm *Mesh) addVertex(p []float32) uint32 {
+	hm.points = append(hm.points, p[0], p[1], p[2])
+	hm.vertexHalfEdge = append(hm.vertexHalfEdge, Invalid)
+	hm.vertexRemoved = append(hm.vertexRemoved, false)
+	return uint32(len(hm.vertexRemoved) - 1)
+}
+
+func (hm *Mesh) addFace(a uint32, b uint32, c uint32) uint32 {
+	hm.origin = append(hm.origin, a, b, c)
+	hm.twin = append(hm.twin, Invalid, Invalid, Invalid)
+	hm.faceRemoved = append(hm.faceRemoved, false)
+	return uint32(len(hm.faceRemoved) - 1)
+}
+
+func contains(vertices []uint32, v uint32) bool {
+	for _, w := range vertices {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package halfedge
+
+import (
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func TestCollapseEdge(t *testing.T) {
+	hm, err := FromMesh(shape.Sphere(800, 100))
+	if err != nil {
+		t.Fatalf("FromMesh failed: %v", err)
+	}
+	numCollapses := 0
+	for h := uint32(0); h < hm.NumHalfEdges() && numCollapses < 300; h += 7 {
+		if !hm.CanCollapse(h) {
+			continue
+		}
+		a, b := hm.Origin(h), hm.Dest(h)
+		pa, pb := hm.Point(a), hm.Point(b)
+		kept, err := hm.CollapseEdge(h, []float32{(pa[0] + pb[0]) / 2, (pa[1] + pb[1]) / 2, (pa[2] + pb[2]) / 2})
+		if err != nil {
+			t.Fatalf("CollapseEdge failed: %v", err)
+		}
+		if kept != a || !hm.IsVertexRemoved(b) || hm.IsVertexRemoved(a) {
+			t.Fatalf("Expected %d to be merged into %d", b, a)
+		}
+		if err := hm.Check(); err != nil {
+			t.Fatalf("Check failed after collapsing %d: %v", h, err)
+		}
+		numCollapses++
+	}
+	if numCollapses < 100 {
+		t.Errorf("Expected at least 100 collapses, got %d", numCollapses)
+	}
+	if chi := eulerCharacteristic(hm); chi != 2 {
+		t.Errorf("Expected a sphere, got an Euler characteristic of %d", chi)
+	}
+	m := hm.ToIndexedMesh()
+	if m.GetNumFacets() != 800-2*uint32(numCollapses) || m.GetNumVertices() != 402-uint32(numCollapses) {
+		t.Errorf("Expected %d triangles and %d vertices, got %d and %d",
+			800-2*numCollapses, 402-numCollapses, m.GetNumFacets(), m.GetNumVertices())
+	}
+	if _, err := FromMesh(m); err != nil {
+		t.Errorf("The collapsed mesh is not manifold: %v", err)
+	}
+}
+
+func TestCollapseBoundary(t *testing.T) {
+	hm, err := FromMesh(shape.Grid(3, 0))
+	if err != nil {
+		t.Fatalf("FromMesh failed: %v", err)
+	}
+	// the interior edge from 2 to 7 joins two boundary vertices
+	if h := hm.FindHalfEdge(2, 7); hm.CanCollapse(h) {
+		t.Errorf("Expected the collapse of an interior edge between boundary vertices to be blocked")
+	}
+	h := hm.FindHalfEdge(0, 1)
+	if h == Invalid || !hm.IsBoundary(h) {
+		t.Fatalf("Expected the boundary half-edge from 0 to 1")
+	}
+	if _, err := hm.CollapseEdge(h, hm.Point(1)); err != nil {
+		t.Fatalf("CollapseEdge failed: %v", err)
+	}
+	if err := hm.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	// 0 keeps the neighbors 1 had, except itself and 5, which it already had
+	if !hm.IsBoundaryVertex(0) || len(hm.VertexNeighbors(0)) != 4 {
+		t.Errorf("Expected vertex 0 on the boundary with 4 neighbors, got %v", hm.VertexNeighbors(0))
+	}
+	if chi := eulerCharacteristic(hm); chi != 1 {
+		t.Errorf("Expected a disk, got an Euler characteristic of %d", chi)
+	}
+	// the corner 3 would be left without faces
+	if h := hm.FindHalfEdge(7, 2); hm.CanCollapse(h) {
+		t.Errorf("Expected the collapse that removes the last face of a corner to be blocked")
+	}
+}
+
+func TestSplitEdge(t *testing.T) {
+	hm, err := FromMesh(shape.Grid(3, 0))
+	if err != nil {
+		t.Fatalf("FromMesh failed: %v", err)
+	}
+	// an interior edge
+	h := hm.FindHalfEdge(5, 10)
+	m, err := hm.SplitEdge(h, []float32{1.5, 2.5, 0})
+	if err != nil {
+		t.Fatalf("SplitEdge failed: %v", err)
+	}
+	if err := hm.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if m != 16 || hm.NumFaces() != 20 || hm.IsBoundaryVertex(m) || len(hm.VertexNeighbors(m)) != 4 {
+		t.Errorf("Expected the inner vertex 16 with 4 neighbors and 20 faces, got %d with %v and %d",
+			m, hm.VertexNeighbors(m), hm.NumFaces())
+	}
+	if hm.FindHalfEdge(5, 10) != Invalid || hm.FindHalfEdge(5, m) == Invalid || hm.FindHalfEdge(m, 10) == Invalid {
+		t.Errorf("Expected the edge from 5 to 10 to go through %d", m)
+	}
+	// a boundary edge
+	h = hm.FindHalfEdge(0, 1)
+	m, err = hm.SplitEdge(h, []float32{.5, 0, 0})
+	if err != nil {
+		t.Fatalf("SplitEdge failed: %v", err)
+	}
+	if err := hm.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !hm.IsBoundaryVertex(m) || len(hm.VertexNeighbors(m)) != 3 || hm.NumFaces() != 21 {
+		t.Errorf("Expected a boundary vertex with 3 neighbors, got %v", hm.VertexNeighbors(m))
+	}
+	if chi := eulerCharacteristic(hm); chi != 1 {
+		t.Errorf("Expected a disk, got an Euler characteristic of %d", chi)
+	}
+}
+
+func TestFlipEdge(t *testing.T) {
+	hm, err := FromMesh(shape.Grid(3, 0))
+	if err != nil {
+		t.Fatalf("FromMesh failed: %v", err)
+	}
+	// the diagonal from 5 to 10 is shared by (5, 10, 9) and (5, 6, 10)
+	h := hm.FindHalfEdge(5, 10)
+	if err := hm.FlipEdge(h); err != nil {
+		t.Fatalf("FlipEdge failed: %v", err)
+	}
+	if err := hm.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if hm.FindHalfEdge(5, 10) != Invalid || hm.FindHalfEdge(10, 5) != Invalid {
+		t.Errorf("Expected the edge from 5 to 10 to be gone")
+	}
+	if hm.Origin(h) != 6 || hm.Dest(h) != 9 {
+		t.Errorf("Expected the flipped half-edge to run from 6 to 9, got %d to %d", hm.Origin(h), hm.Dest(h))
+	}
+	if len(hm.VertexNeighbors(5)) != 5 || len(hm.VertexNeighbors(6)) != 7 {
+		t.Errorf("Expected 5 and 7 neighbors, got %v and %v", hm.VertexNeighbors(5), hm.VertexNeighbors(6))
+	}
+	// flipping back restores the diagonal
+	if err := hm.FlipEdge(h); err != nil {
+		t.Fatalf("FlipEdge failed: %v", err)
+	}
+	if hm.FindHalfEdge(5, 10) == Invalid && hm.FindHalfEdge(10, 5) == Invalid {
+		t.Errorf("Expected the edge from 5 to 10 to be back")
+	}
+	// boundary edges cannot be flipped
+	if err := hm.FlipEdge(hm.FindHalfEdge(0, 1)); err == nil {
+		t.Errorf("Expected an error flipping a boundary edge")
+	}
+}
+
+func TestEditSphere(t *testing.T) {
+	hm, err := FromMesh(shape.Sphere(800, 100))
+	if err != nil {
+		t.Fatalf("FromMesh failed: %v", err)
+	}
+	numFlips := 0
+	for h := uint32(0); h < 600; h += 5 {
+		if hm.CanFlip(h) {
+			if err := hm.FlipEdge(h); err != nil {
+				t.Fatalf("FlipEdge failed: %v", err)
+			}
+			numFlips++
+		}
+		if h%3 == 0 {
+			p := hm.Point(hm.Origin(h))
+			if _, err := hm.SplitEdge(h, p); err != nil {
+				t.Fatalf("SplitEdge failed: %v", err)
+			}
+		}
+		if err := hm.Check(); err != nil {
+			t.Fatalf("Check failed after editing %d: %v", h, err)
+		}
+	}
+	if numFlips == 0 {
+		t.Errorf("Expected some flips")
+	}
+	if chi := eulerCharacteristic(hm); chi != 2 {
+		t.Errorf("Expected a sphere, got an Euler characteristic of %d", chi)
+	}
+}
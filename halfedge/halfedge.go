@@ -0,0 +1,318 @@
+// Package halfedge implements a half-edge mesh: every triangle is made of three
+// directed half-edges, each linked to the half-edge of the neighboring triangle
+// that runs the other way (its twin).  It answers adjacency queries in constant
+// time and supports local edits (edge collapse, split and flip).
+package halfedge
+
+import (
+	"fmt"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// Invalid is the index of a missing half-edge, face or vertex, e.g., the twin of
+// a boundary half-edge
+const Invalid = math.MaxUint32
+
+// Mesh is a manifold, consistently oriented triangle mesh.  The half-edges of face f
+// are 3f, 3f+1 and 3f+2, in the order of its corners, so the face and the next
+// half-edge of a half-edge are implicit.  Half-edge 3f+i starts at corner i of f.
+//
+// The edits never renumber faces or vertices: the ones they remove are flagged,
+// so indices stay valid until ToIndexedMesh compacts the mesh.
+type Mesh struct {
+	points []float32
+	// origin[h] is the vertex half-edge h starts from
+	origin []uint32
+	twin   []uint32
+	// vertexHalfEdge[v] is a half-edge leaving v, the boundary one when v is on the boundary
+	vertexHalfEdge []uint32
+	faceRemoved    []bool
+	vertexRemoved  []bool
+}
+
+// FromMesh builds the half-edge mesh of m.  It fails on degenerate triangles, on
+// edges shared by more than two triangles or by two triangles that disagree on
+// their orientation, and on vertices where several fans of triangles meet.
+func FromMesh(m mesh.Mesh) (*Mesh, error) {
+	numVertices := m.GetNumVertices()
+	numFaces := m.GetNumFacets()
+	hm := &Mesh{
+		points:         make([]float32, 3*numVertices),
+		origin:         make([]uint32, 3*numFaces),
+		twin:           make([]uint32, 3*numFaces),
+		vertexHalfEdge: make([]uint32, numVertices),
+		faceRemoved:    make([]bool, numFaces),
+		vertexRemoved:  make([]bool, numVertices),
+	}
+	for v := uint32(0); v < numVertices; v++ {
+		p, err := m.GetPoint(v)
+		if err != nil {
+			return nil, fmt.Errorf("halfedge.FromMesh: %v", err)
+		}
+		copy(hm.points[3*v:3*v+3], p)
+		hm.vertexHalfEdge[v] = Invalid
+	}
+
+	edges := make(map[[2]uint32]uint32, 3*numFaces)
+	for f := uint32(0); f < numFaces; f++ {
+		vertices, err := m.GetVertices(f)
+		if err != nil {
+			return nil, fmt.Errorf("halfedge.FromMesh: %v", err)
+		}
+		if vertices[0] == vertices[1] || vertices[1] == vertices[2] || vertices[2] == vertices[0] {
+			return nil, fmt.Errorf("halfedge.FromMesh: triangle %d is degenerate", f)
+		}
+		for i := uint32(0); i < 3; i++ {
+			a, b := vertices[i], vertices[(i+1)%3]
+			if a >= numVertices || b >= numVertices {
+				return nil, fmt.Errorf("halfedge.FromMesh: triangle %d references a vertex out of bounds", f)
+			}
+			if _, ok := edges[[2]uint32{a, b}]; ok {
+				return nil, fmt.Errorf("halfedge.FromMesh: edge (%d, %d) is non-manifold or inconsistently oriented", a, b)
+			}
+			edges[[2]uint32{a, b}] = 3*f + i
+			hm.origin[3*f+i] = a
+		}
+	}
+	for e, h := range edges {
+		if t, ok := edges[[2]uint32{e[1], e[0]}]; ok {
+			hm.twin[h] = t
+		} else {
+			hm.twin[h] = Invalid
+		}
+	}
+
+	// start every vertex at its boundary half-edge, if any, and check that a
+	// single fan goes around it
+	numFans := make([]uint32, numVertices)
+	for h, v := range hm.origin {
+		if hm.vertexHalfEdge[v] == Invalid || hm.twin[h] == Invalid {
+			if hm.twin[h] == Invalid && hm.vertexHalfEdge[v] != Invalid && hm.twin[hm.vertexHalfEdge[v]] == Invalid {
+				return nil, fmt.Errorf("halfedge.FromMesh: vertex %d is non-manifold", v)
+			}
+			hm.vertexHalfEdge[v] = uint32(h)
+		}
+		numFans[v]++
+	}
+	for v := uint32(0); v < numVertices; v++ {
+		if hm.vertexHalfEdge[v] != Invalid && uint32(len(hm.OutgoingHalfEdges(v))) != numFans[v] {
+			return nil, fmt.Errorf("halfedge.FromMesh: vertex %d is non-manifold", v)
+		}
+	}
+	return hm, nil
+}
+
+// NumFaces returns the number of face indices, including removed faces
+func (hm *Mesh) NumFaces() uint32 {
+	return uint32(len(hm.faceRemoved))
+}
+
+// NumVertices returns the number of vertex indices, including removed vertices
+func (hm *Mesh) NumVertices() uint32 {
+	return uint32(len(hm.vertexRemoved))
+}
+
+// NumHalfEdges returns the number of half-edge indices, i.e., three per face index
+func (hm *Mesh) NumHalfEdges() uint32 {
+	return uint32(len(hm.origin))
+}
+
+// IsFaceRemoved tells whether an edit removed face f
+func (hm *Mesh) IsFaceRemoved(f uint32) bool {
+	return hm.faceRemoved[f]
+}
+
+// IsVertexRemoved tells whether an edit removed vertex v
+func (hm *Mesh) IsVertexRemoved(v uint32) bool {
+	return hm.vertexRemoved[v]
+}
+
+// Next returns the half-edge that follows h around its face
+func (hm *Mesh) Next(h uint32) uint32 {
+	return h - h%3 + (h+1)%3
+}
+
+// Prev returns the half-edge that precedes h around its face
+func (hm *Mesh) Prev(h uint32) uint32 {
+	return h - h%3 + (h+2)%3
+}
+
+// Twin returns the half-edge that runs the other way along the edge of h, or
+// Invalid when h is on the boundary
+func (hm *Mesh) Twin(h uint32) uint32 {
+	return hm.twin[h]
+}
+
+// Face returns the face of h
+func (hm *Mesh) Face(h uint32) uint32 {
+	return h / 3
+}
+
+// Origin returns the vertex h starts from
+func (hm *Mesh) Origin(h uint32) uint32 {
+	return hm.origin[h]
+}
+
+// Dest returns the vertex h points to
+func (hm *Mesh) Dest(h uint32) uint32 {
+	return hm.origin[hm.Next(h)]
+}
+
+// IsBoundary tells whether h is on the boundary, i.e., has no twin
+func (hm *Mesh) IsBoundary(h uint32) bool {
+	return hm.twin[h] == Invalid
+}
+
+// FaceHalfEdge returns the first half-edge of face f
+func (hm *Mesh) FaceHalfEdge(f uint32) uint32 {
+	return 3 * f
+}
+
+// FaceVertices returns the corners of face f
+func (hm *Mesh) FaceVertices(f uint32) [3]uint32 {
+	return [3]uint32{hm.origin[3*f], hm.origin[3*f+1], hm.origin[3*f+2]}
+}
+
+// VertexHalfEdge returns a half-edge leaving v, which is a boundary half-edge when
+// v is on the boundary, or Invalid when no face uses v
+func (hm *Mesh) VertexHalfEdge(v uint32) uint32 {
+	return hm.vertexHalfEdge[v]
+}
+
+// IsBoundaryVertex tells whether v is on the boundary
+func (hm *Mesh) IsBoundaryVertex(v uint32) bool {
+	h := hm.vertexHalfEdge[v]
+	return h != Invalid && hm.twin[h] == Invalid
+}
+
+// Point returns the position of v
+func (hm *Mesh) Point(v uint32) []float32 {
+	return []float32{hm.points[3*v], hm.points[3*v+1], hm.points[3*v+2]}
+}
+
+// SetPoint moves v to p
+func (hm *Mesh) SetPoint(v uint32, p []float32) {
+	copy(hm.points[3*v:3*v+3], p)
+}
+
+// OutgoingHalfEdges returns the half-edges leaving v in counterclockwise order.  On
+// the boundary the first one is the boundary half-edge leaving v.
+func (hm *Mesh) OutgoingHalfEdges(v uint32) []uint32 {
+	start := hm.vertexHalfEdge[v]
+	if start == Invalid {
+		return []uint32{}
+	}
+	retVal := make([]uint32, 0, 6)
+	for h := start; ; {
+		retVal = append(retVal, h)
+		h = hm.twin[hm.Prev(h)]
+		if h == Invalid || h == start {
+			break
+		}
+	}
+	return retVal
+}
+
+// VertexNeighbors returns the one-ring of v: the vertices sharing an edge with it,
+// in counterclockwise order
+func (hm *Mesh) VertexNeighbors(v uint32) []uint32 {
+	outgoing := hm.OutgoingHalfEdges(v)
+	retVal := make([]uint32, 0, len(outgoing)+1)
+	for _, h := range outgoing {
+		retVal = append(retVal, hm.Dest(h))
+	}
+	if len(outgoing) > 0 && hm.IsBoundaryVertex(v) {
+		// the last edge of the fan is on the boundary too
+		retVal = append(retVal, hm.origin[hm.Prev(outgoing[len(outgoing)-1])])
+	}
+	return retVal
+}
+
+// VertexFaces returns the faces around v in counterclockwise order
+func (hm *Mesh) VertexFaces(v uint32) []uint32 {
+	outgoing := hm.OutgoingHalfEdges(v)
+	retVal := make([]uint32, len(outgoing))
+	for i, h := range outgoing {
+		retVal[i] = hm.Face(h)
+	}
+	return retVal
+}
+
+// FindHalfEdge returns the half-edge from a to b, or Invalid if there is none
+func (hm *Mesh) FindHalfEdge(a uint32, b uint32) uint32 {
+	for _, h := range hm.OutgoingHalfEdges(a) {
+		if hm.Dest(h) == b {
+			return h
+		}
+	}
+	return Invalid
+}
+
+// ToIndexedMesh returns the faces and vertices that were not removed, in order
+func (hm *Mesh) ToIndexedMesh() cloudmesh.IndexedMesh {
+	newIndex := make([]uint32, hm.NumVertices())
+	retVal := cloudmesh.IndexedMesh{Indices: make([]uint32, 0, len(hm.origin)), Vertices: make([]float32, 0, len(hm.points))}
+	numVertices := uint32(0)
+	for v, removed := range hm.vertexRemoved {
+		if removed {
+			continue
+		}
+		newIndex[v] = numVertices
+		numVertices++
+		retVal.Vertices = append(retVal.Vertices, hm.points[3*v:3*v+3]...)
+	}
+	for f, removed := range hm.faceRemoved {
+		if removed {
+			continue
+		}
+		for _, v := range hm.origin[3*f : 3*f+3] {
+			retVal.Indices = append(retVal.Indices, newIndex[v])
+		}
+	}
+	return retVal
+}
+
+// Check verifies the links of the mesh: twins are mutual and run the other way, and
+// every vertex reaches all of its faces from its half-edge
+func (hm *Mesh) Check() error {
+	numFans := make([]uint32, hm.NumVertices())
+	for h := uint32(0); h < hm.NumHalfEdges(); h++ {
+		if hm.faceRemoved[hm.Face(h)] {
+			continue
+		}
+		v := hm.origin[h]
+		if hm.vertexRemoved[v] {
+			return fmt.Errorf("halfedge.Check: half-edge %d starts at removed vertex %d", h, v)
+		}
+		numFans[v]++
+		t := hm.twin[h]
+		if t == Invalid {
+			continue
+		}
+		if hm.faceRemoved[hm.Face(t)] || hm.twin[t] != h || hm.origin[t] != hm.Dest(h) || hm.Dest(t) != v {
+			return fmt.Errorf("halfedge.Check: half-edge %d and its twin %d do not match", h, t)
+		}
+	}
+	for v := uint32(0); v < hm.NumVertices(); v++ {
+		if hm.vertexRemoved[v] {
+			continue
+		}
+		h := hm.vertexHalfEdge[v]
+		if h == Invalid {
+			if numFans[v] != 0 {
+				return fmt.Errorf("halfedge.Check: vertex %d has faces but no half-edge", v)
+			}
+			continue
+		}
+		if hm.faceRemoved[hm.Face(h)] || hm.origin[h] != v {
+			return fmt.Errorf("halfedge.Check: the half-edge of vertex %d does not leave it", v)
+		}
+		if uint32(len(hm.OutgoingHalfEdges(v))) != numFans[v] {
+			return fmt.Errorf("halfedge.Check: vertex %d does not reach all of its faces", v)
+		}
+	}
+	return nil
+}
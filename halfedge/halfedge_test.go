@@ -0,0 +1,148 @@
+package halfedge
+
+import (
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// eulerCharacteristic returns V - E + F of the faces and vertices that were not removed
+func eulerCharacteristic(hm *Mesh) int {
+	numVertices, numFaces, numHalfEdges, numBoundary := 0, 0, 0, 0
+	for v := uint32(0); v < hm.NumVertices(); v++ {
+		if !hm.IsVertexRemoved(v) {
+			numVertices++
+		}
+	}
+	for h := uint32(0); h < hm.NumHalfEdges(); h++ {
+		if hm.IsFaceRemoved(hm.Face(h)) {
+			continue
+		}
+		numHalfEdges++
+		if hm.IsBoundary(h) {
+			numBoundary++
+		}
+	}
+	numFaces = numHalfEdges / 3
+	return numVertices - (numHalfEdges+numBoundary)/2 + numFaces
+}
+
+func TestFromMesh(t *testing.T) {
+	for _, m := range []cloudmesh.IndexedMesh{shape.BasicCube(), shape.Sphere(800, 100), shape.Grid(4, 0)} {
+		hm, err := FromMesh(m)
+		if err != nil {
+			t.Fatalf("FromMesh failed: %v", err)
+		}
+		if err := hm.Check(); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		back := hm.ToIndexedMesh()
+		if len(back.Indices) != len(m.Indices) || len(back.Vertices) != len(m.Vertices) {
+			t.Fatalf("Expected %d indices and %d coordinates, got %d and %d",
+				len(m.Indices), len(m.Vertices), len(back.Indices), len(back.Vertices))
+		}
+		for i := range m.Indices {
+			if back.Indices[i] != m.Indices[i] {
+				t.Fatalf("Index %d changed from %d to %d", i, m.Indices[i], back.Indices[i])
+			}
+		}
+		for i := range m.Vertices {
+			if back.Vertices[i] != m.Vertices[i] {
+				t.Fatalf("Coordinate %d changed from %v to %v", i, m.Vertices[i], back.Vertices[i])
+			}
+		}
+	}
+}
+
+func TestTraversal(t *testing.T) {
+	m := shape.Grid(3, 0)
+	hm, err := FromMesh(m)
+	if err != nil {
+		t.Fatalf("FromMesh failed: %v", err)
+	}
+	numBoundary := 0
+	for h := uint32(0); h < hm.NumHalfEdges(); h++ {
+		if hm.Next(hm.Next(hm.Next(h))) != h || hm.Prev(hm.Next(h)) != h {
+			t.Fatalf("Half-edge %d does not go around its face", h)
+		}
+		if hm.Face(hm.Next(h)) != hm.Face(h) || hm.Origin(hm.Next(h)) != hm.Dest(h) {
+			t.Fatalf("Half-edge %d is not followed by its next", h)
+		}
+		if hm.IsBoundary(h) {
+			numBoundary++
+			continue
+		}
+		twin := hm.Twin(h)
+		if hm.Twin(twin) != h || hm.Origin(twin) != hm.Dest(h) {
+			t.Fatalf("Half-edge %d and its twin %d do not match", h, twin)
+		}
+	}
+	if numBoundary != 12 {
+		t.Errorf("Expected 12 boundary half-edges, got %d", numBoundary)
+	}
+	if v := hm.FaceVertices(1); v[0] != 0 || v[1] != 5 || v[2] != 4 {
+		t.Errorf("Expected face 1 to be (0, 5, 4), got %v", v)
+	}
+	if h := hm.FindHalfEdge(0, 5); h == Invalid || hm.Origin(h) != 0 || hm.Dest(h) != 5 {
+		t.Errorf("Expected to find the half-edge from 0 to 5, got %d", h)
+	}
+	if h := hm.FindHalfEdge(0, 6); h != Invalid {
+		t.Errorf("Expected no half-edge from 0 to 6, got %d", h)
+	}
+}
+
+func TestOneRing(t *testing.T) {
+	hm, err := FromMesh(shape.Grid(3, 0))
+	if err != nil {
+		t.Fatalf("FromMesh failed: %v", err)
+	}
+	// the inner vertex 5 of the 4 x 4 grid, counterclockwise from 6
+	expected := []uint32{6, 10, 9, 4, 0, 1}
+	neighbors := hm.VertexNeighbors(5)
+	if len(neighbors) != len(expected) {
+		t.Fatalf("Expected the neighbors %v, got %v", expected, neighbors)
+	}
+	start := 0
+	for neighbors[start] != expected[0] {
+		start++
+	}
+	for i := range expected {
+		if neighbors[(start+i)%len(neighbors)] != expected[i] {
+			t.Fatalf("Expected the neighbors %v in this order, got %v", expected, neighbors)
+		}
+	}
+	if hm.IsBoundaryVertex(5) || len(hm.VertexFaces(5)) != 6 {
+		t.Errorf("Expected vertex 5 to be an inner vertex with 6 faces")
+	}
+	// the boundary vertex 1 starts and ends on the boundary
+	if !hm.IsBoundaryVertex(1) {
+		t.Fatalf("Expected vertex 1 to be on the boundary")
+	}
+	neighbors = hm.VertexNeighbors(1)
+	if len(neighbors) != 4 || neighbors[0] != 2 || neighbors[3] != 0 {
+		t.Errorf("Expected the neighbors of 1 to go from 2 to 0, got %v", neighbors)
+	}
+	if faces := hm.VertexFaces(1); len(faces) != 3 {
+		t.Errorf("Expected 3 faces around vertex 1, got %v", faces)
+	}
+	// the corner 3 has a single face
+	if neighbors := hm.VertexNeighbors(3); len(neighbors) != 2 || neighbors[0] != 7 || neighbors[1] != 2 {
+		t.Errorf("Expected the neighbors of 3 to be [7 2], got %v", neighbors)
+	}
+}
+
+func TestFromMeshErrors(t *testing.T) {
+	vertices := []float32{0, 0, 0, 1, 0, 0, 0, 1, 0, 1, 1, 0, 0, -1, 0, -1, 0, 0, 0, 0, 1}
+	for name, indices := range map[string][]uint32{
+		"degenerate":    {0, 1, 1},
+		"three faces":   {0, 1, 2, 1, 0, 4, 0, 1, 6},
+		"flipped":       {0, 1, 2, 0, 1, 4},
+		"bowtie":        {0, 1, 3, 0, 5, 4},
+		"out of bounds": {0, 1, 7},
+	} {
+		if _, err := FromMesh(cloudmesh.IndexedMesh{Indices: indices, Vertices: vertices}); err == nil {
+			t.Errorf("Expected an error for the %s mesh", name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package lod
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/stl"
+)
+
+// WriteSTL writes every level to its own STL file, named prefix_0.stl for the
+// input, prefix_1.stl for the next level and so on.  It returns the file names.
+func (c Chain) WriteSTL(prefix string) ([]string, error) {
+	names := make([]string, len(c.Levels))
+	for i, level := range c.Levels {
+		names[i] = fmt.Sprintf("%s_%d.stl", prefix, i)
+		if err := stl.SaveSTLFile(level.Mesh, names[i]); err != nil {
+			return nil, fmt.Errorf("lod.WriteSTL: %v", err)
+		}
+	}
+	return names, nil
+}
+
+// glTF 2.0 constants
+const (
+	gltfFloat        = 5126
+	gltfUnsignedInt  = 5125
+	gltfArrayBuffer  = 34962
+	gltfElementArray = 34963
+	gltfTriangles    = 4
+)
+
+type gltfDocument struct {
+	Asset          gltfAsset        `json:"asset"`
+	ExtensionsUsed []string         `json:"extensionsUsed"`
+	Scene          int              `json:"scene"`
+	Scenes         []gltfScene      `json:"scenes"`
+	Nodes          []gltfNode       `json:"nodes"`
+	Meshes         []gltfMesh       `json:"meshes"`
+	Accessors      []gltfAccessor   `json:"accessors"`
+	BufferViews    []gltfBufferView `json:"bufferViews"`
+	Buffers        []gltfBuffer     `json:"buffers"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name       string                 `json:"name"`
+	Mesh       int                    `json:"mesh"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	Extras     gltfLevelExtras        `json:"extras"`
+}
+
+// gltfLevelExtras carries the error of a level, in model units
+type gltfLevelExtras struct {
+	Error     float64 `json:"error"`
+	MeanError float64 `json:"meanError"`
+	NumFacets uint32  `json:"numFacets"`
+}
+
+type gltfMsftLod struct {
+	IDs []int `json:"ids"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Mode       int            `json:"mode"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         uint32    `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target"`
+}
+
+type gltfBuffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri"`
+}
+
+// WriteGLTF writes the chain as a glTF 2.0 document with its buffer embedded.
+// The scene has a single node with the input; the coarser levels are listed in its
+// MSFT_lod extension, and every node has the error of its level in its extras.
+func (c Chain) WriteGLTF(w io.Writer) error {
+	if len(c.Levels) == 0 {
+		return fmt.Errorf("lod.WriteGLTF: the chain is empty")
+	}
+	doc := gltfDocument{
+		Asset:          gltfAsset{Version: "2.0", Generator: "cloud-mesh-simplifier"},
+		ExtensionsUsed: []string{"MSFT_lod"},
+		Scenes:         []gltfScene{{Nodes: []int{0}}},
+	}
+	buf := make([]byte, 0)
+	addView := func(data []byte, target int) int {
+		doc.BufferViews = append(doc.BufferViews, gltfBufferView{ByteOffset: len(buf), ByteLength: len(data), Target: target})
+		buf = append(buf, data...)
+		return len(doc.BufferViews) - 1
+	}
+	lodIDs := make([]int, 0, len(c.Levels)-1)
+	for i, level := range c.Levels {
+		m := level.Mesh
+		if uint32(len(m.Vertices)) != 3*m.GetNumVertices() || uint32(len(m.Indices)) != 3*m.GetNumFacets() {
+			return fmt.Errorf("lod.WriteGLTF: level %d is malformed", i)
+		}
+		positions := make([]byte, 4*len(m.Vertices))
+		min := []float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+		max := []float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+		for j, f := range m.Vertices {
+			binary.LittleEndian.PutUint32(positions[4*j:], math.Float32bits(f))
+			min[j%3] = float32(math.Min(float64(min[j%3]), float64(f)))
+			max[j%3] = float32(math.Max(float64(max[j%3]), float64(f)))
+		}
+		indices := make([]byte, 4*len(m.Indices))
+		for j, index := range m.Indices {
+			if index >= m.GetNumVertices() {
+				return fmt.Errorf("lod.WriteGLTF: level %d has an out of bounds index", i)
+			}
+			binary.LittleEndian.PutUint32(indices[4*j:], index)
+		}
+		doc.Accessors = append(doc.Accessors,
+			gltfAccessor{BufferView: addView(positions, gltfArrayBuffer), ComponentType: gltfFloat,
+				Count: m.GetNumVertices(), Type: "VEC3", Min: min, Max: max},
+			gltfAccessor{BufferView: addView(indices, gltfElementArray), ComponentType: gltfUnsignedInt,
+				Count: uint32(len(m.Indices)), Type: "SCALAR"})
+		doc.Meshes = append(doc.Meshes, gltfMesh{Primitives: []gltfPrimitive{{
+			Attributes: map[string]int{"POSITION": 2 * i}, Indices: 2*i + 1, Mode: gltfTriangles}}})
+		doc.Nodes = append(doc.Nodes, gltfNode{Name: fmt.Sprintf("lod%d", i), Mesh: i,
+			Extras: gltfLevelExtras{Error: level.Error, MeanError: level.MeanError, NumFacets: m.GetNumFacets()}})
+		if i > 0 {
+			lodIDs = append(lodIDs, i)
+		}
+	}
+	if len(lodIDs) > 0 {
+		doc.Nodes[0].Extensions = map[string]interface{}{"MSFT_lod": gltfMsftLod{IDs: lodIDs}}
+	}
+	doc.Buffers = []gltfBuffer{{ByteLength: len(buf),
+		URI: "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(buf)}}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("lod.WriteGLTF: %v", err)
+	}
+	return nil
+}
+
+// SaveGLTF writes the chain to a glTF file (see WriteGLTF)
+func (c Chain) SaveGLTF(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("lod.SaveGLTF: %v", err)
+	}
+	if err := c.WriteGLTF(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,164 @@
+// Package lod builds chains of levels of detail, each with about half the triangles
+// of the previous one, and annotates every level with its measured geometric error
+// so that a renderer can pick a level by its projected (screen space) error.
+package lod
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/simplify"
+)
+
+// Options configures a chain
+type Options struct {
+	// NumLevels is the largest number of levels, including the input
+	NumLevels int
+	// Ratio is the number of triangles of a level relative to the previous one.  Defaults to .5
+	Ratio float64
+	// Algorithm is the name of the simplifier (see simplify.Names).  Defaults to "qem"
+	Algorithm string
+	// Simplify holds the options given to the simplifier.  TargetFacets is set for every level.
+	Simplify simplify.Options
+}
+
+// Level is a level of detail
+type Level struct {
+	Mesh cloudmesh.IndexedMesh
+	// Error is the largest distance between the level and the input, in model units
+	// (see mesh.HausdorffDistance), and MeanError the mean distance
+	Error     float64
+	MeanError float64
+	// Report is the report of the simplification that made the level
+	Report simplify.Report
+}
+
+// Chain is a list of levels from the finest, the input itself, to the coarsest
+type Chain struct {
+	Levels []Level
+}
+
+// Build simplifies every level from the previous one and measures its error
+// against the input.  The chain stops early when the simplifier can't go any further.
+// The report of a level tells if the simplifier missed the target of the level
+// (see simplify.Report.TargetMissed), as vsa does on curved meshes.
+func Build(m mesh.Mesh, opts Options) (Chain, error) {
+	if opts.NumLevels < 1 {
+		return Chain{}, errors.New("lod.Build: need at least one level")
+	}
+	if opts.Ratio == 0 {
+		opts.Ratio = .5
+	}
+	if !(opts.Ratio > 0 && opts.Ratio < 1) {
+		return Chain{}, fmt.Errorf("lod.Build: the ratio must be between 0 and 1, got %v", opts.Ratio)
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = "qem"
+	}
+	s, err := simplify.Lookup(opts.Algorithm)
+	if err != nil {
+		return Chain{}, fmt.Errorf("lod.Build: %v", err)
+	}
+	input, err := copyMesh(m)
+	if err != nil {
+		return Chain{}, fmt.Errorf("lod.Build: %v", err)
+	}
+	inputGrid, err := mesh.NewClosestPointGrid(input)
+	if err != nil {
+		return Chain{}, fmt.Errorf("lod.Build: %v", err)
+	}
+
+	c := Chain{Levels: []Level{{Mesh: input}}}
+	for len(c.Levels) < opts.NumLevels {
+		previous := c.Levels[len(c.Levels)-1].Mesh
+		target := uint32(float64(previous.GetNumFacets()) * opts.Ratio)
+		if target < 1 {
+			break
+		}
+		simplifyOpts := opts.Simplify
+		simplifyOpts.TargetFacets = target
+		simplified, report, err := s.Simplify(previous, simplifyOpts)
+		if err != nil {
+			return c, fmt.Errorf("lod.Build: level %d: %v", len(c.Levels), err)
+		}
+		if simplified.GetNumFacets() == 0 || simplified.GetNumFacets() >= previous.GetNumFacets() {
+			break
+		}
+		level := Level{Mesh: simplified, Report: report}
+		level.Error, level.MeanError, err = measure(input, inputGrid, simplified)
+		if err != nil {
+			return c, fmt.Errorf("lod.Build: level %d: %v", len(c.Levels), err)
+		}
+		c.Levels = append(c.Levels, level)
+	}
+	return c, nil
+}
+
+// measure returns the largest of the two one-sided distances between the input and a level
+func measure(input cloudmesh.IndexedMesh, inputGrid *mesh.ClosestPointGrid, level cloudmesh.IndexedMesh) (float64, float64, error) {
+	levelGrid, err := mesh.NewClosestPointGrid(level)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxA, meanA, err := mesh.Distance(level, inputGrid)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxB, meanB, err := mesh.Distance(input, levelGrid)
+	if err != nil {
+		return 0, 0, err
+	}
+	return math.Max(maxA, maxB), math.Max(meanA, meanB), nil
+}
+
+// copyMesh returns a copy of the mesh, with its attributes when it is a cloudmesh.IndexedMesh
+func copyMesh(m mesh.Mesh) (cloudmesh.IndexedMesh, error) {
+	retVal := cloudmesh.IndexedMesh{
+		Indices:  make([]uint32, 0, 3*m.GetNumFacets()),
+		Vertices: make([]float32, 0, 3*m.GetNumVertices())}
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		p, err := m.GetPoint(v)
+		if err != nil {
+			return retVal, err
+		}
+		retVal.Vertices = append(retVal.Vertices, p...)
+	}
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return retVal, err
+		}
+		retVal.Indices = append(retVal.Indices, vertices...)
+	}
+	switch im := m.(type) {
+	case cloudmesh.IndexedMesh:
+		retVal.Attributes = im.Attributes.Copy()
+	case *cloudmesh.IndexedMesh:
+		retVal.Attributes = im.Attributes.Copy()
+	}
+	return retVal, nil
+}
+
+// ProjectedError returns the size on screen, in pixels, of a geometric error seen
+// from a distance with a perspective camera of vertical field of view fovY (radians)
+// and a viewport viewportHeight pixels high
+func ProjectedError(err float64, distance float64, fovY float64, viewportHeight float64) float64 {
+	if distance <= 0 {
+		return math.Inf(1)
+	}
+	return err * viewportHeight / (2 * distance * math.Tan(fovY/2))
+}
+
+// Pick returns the index of the coarsest level whose projected error is at most
+// maxPixels (see ProjectedError), or 0, the input, if none is
+func (c Chain) Pick(distance float64, fovY float64, viewportHeight float64, maxPixels float64) int {
+	for i := len(c.Levels) - 1; i > 0; i-- {
+		if ProjectedError(c.Levels[i].Error, distance, fovY, viewportHeight) <= maxPixels {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,218 @@
+package lod
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/simplify"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/stl"
+)
+
+func TestBuild(t *testing.T) {
+	sphere := shape.Sphere(2000, 100)
+	for _, algorithm := range []string{"qem", "cluster-quadric"} {
+		c, err := Build(sphere, Options{NumLevels: 5, Algorithm: algorithm})
+		if err != nil {
+			t.Fatalf("%v: Build failed: %v", algorithm, err)
+		}
+		if len(c.Levels) != 5 {
+			t.Fatalf("%v: expected 5 levels, got %v", algorithm, len(c.Levels))
+		}
+		if c.Levels[0].Error != 0 || c.Levels[0].Mesh.GetNumFacets() != sphere.GetNumFacets() {
+			t.Errorf("%v: expected the input as the first level", algorithm)
+		}
+		for i := 1; i < len(c.Levels); i++ {
+			previous, level := c.Levels[i-1], c.Levels[i]
+			// the grid of a clustering can't be made arbitrarily coarse, so it only has to shrink
+			limit := previous.Mesh.GetNumFacets() / 2
+			if algorithm != "qem" {
+				limit = previous.Mesh.GetNumFacets() - 1
+			}
+			if level.Mesh.GetNumFacets() > limit {
+				t.Errorf("%v: level %d has %d triangles, more than %d", algorithm, i,
+					level.Mesh.GetNumFacets(), limit)
+			}
+			if level.Error <= 0 || level.MeanError > level.Error {
+				t.Errorf("%v: level %d has errors %v and %v", algorithm, i, level.Error, level.MeanError)
+			}
+			if level.Report.Algorithm != algorithm {
+				t.Errorf("%v: level %d was made by %v", algorithm, i, level.Report.Algorithm)
+			}
+		}
+		if c.Levels[4].Error < c.Levels[1].Error {
+			t.Errorf("%v: expected the coarsest level to have the largest error, got %v and %v", algorithm,
+				c.Levels[4].Error, c.Levels[1].Error)
+		}
+	}
+
+	if _, err := Build(sphere, Options{NumLevels: 3, Algorithm: "nope"}); err == nil {
+		t.Errorf("Expected an error for an unknown algorithm")
+	}
+	if _, err := Build(sphere, Options{NumLevels: 3, Ratio: 2}); err == nil {
+		t.Errorf("Expected an error for a ratio above 1")
+	}
+}
+
+func TestBuildAttributes(t *testing.T) {
+	// normals pointing outward, a color and UV per vertex, and a material of 1 on the
+	// top half and 2 on the bottom one
+	sphere := shape.Sphere(2000, 100)
+	for v := 0; v < len(sphere.Vertices); v += 3 {
+		p := sphere.Vertices[v : v+3]
+		sphere.Attributes.Normals = append(sphere.Attributes.Normals, p[0]/100, p[1]/100, p[2]/100)
+		sphere.Attributes.Colors = append(sphere.Attributes.Colors, 1, 0, 0, 1)
+		sphere.Attributes.UVs = append(sphere.Attributes.UVs, p[0]/200+.5, p[1]/200+.5)
+	}
+	for tri := uint32(0); tri < sphere.GetNumFacets(); tri++ {
+		center, _ := mesh.ComputeCentroid64(sphere, tri)
+		if center[2] > 0 {
+			sphere.Attributes.Materials = append(sphere.Attributes.Materials, 1)
+		} else {
+			sphere.Attributes.Materials = append(sphere.Attributes.Materials, 2)
+		}
+	}
+
+	c, err := Build(sphere, Options{NumLevels: 4})
+	if err != nil || len(c.Levels) != 4 {
+		t.Fatalf("Expected 4 levels, got %d: %v", len(c.Levels), err)
+	}
+	for i, level := range c.Levels {
+		a := level.Mesh.Attributes
+		if a.Normals == nil || a.Colors == nil || a.UVs == nil || a.Materials == nil {
+			t.Errorf("level %d lost a channel: %+v", i, a)
+			continue
+		}
+		if err := a.Check(level.Mesh.GetNumVertices(), level.Mesh.GetNumFacets()); err != nil {
+			t.Errorf("level %d: %v", i, err)
+			continue
+		}
+		for v := uint32(0); v < level.Mesh.GetNumVertices(); v++ {
+			p, _ := level.Mesh.GetPointVec3(v)
+			n := a.Normals[3*v : 3*v+3]
+			if cos := p.Normalize().Dot([3]float32{n[0], n[1], n[2]}); cos < .9 || math.Abs(float64(a.Colors[4*v])-1) > 1e-5 {
+				t.Errorf("level %d: vertex %d has the normal %v and the color %v", i, v, n, a.Colors[4*v:4*v+4])
+				break
+			}
+		}
+		for _, material := range a.Materials {
+			if material != 1 && material != 2 {
+				t.Errorf("level %d: unexpected material %d", i, material)
+				break
+			}
+		}
+	}
+
+	// the input level is a copy
+	sphere.Attributes.Colors[0] = 0
+	if c.Levels[0].Mesh.Attributes.Colors[0] != 1 {
+		t.Error("Expected the first level to have attributes of its own")
+	}
+}
+
+func TestBuildStopsEarly(t *testing.T) {
+	// a closed mesh can't have fewer than 4 triangles
+	c, err := Build(shape.BasicCube(), Options{NumLevels: 10})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(c.Levels) >= 10 {
+		t.Errorf("Expected the chain to stop early, got %d levels", len(c.Levels))
+	}
+	if last := c.Levels[len(c.Levels)-1].Mesh.GetNumFacets(); last < 4 {
+		t.Errorf("Expected the coarsest level to be closed, got %d triangles", last)
+	}
+}
+
+func TestPick(t *testing.T) {
+	c := Chain{Levels: []Level{{Error: 0}, {Error: 1}, {Error: 4}}}
+	fovY := math.Pi / 2 // 2*tan(fovY/2) = 2
+	if e := ProjectedError(1, 100, fovY, 1000); math.Abs(e-5) > 1e-9 {
+		t.Errorf("Expected a projected error of 5 pixels, got %v", e)
+	}
+	cases := []struct {
+		distance float64
+		expected int
+	}{{10, 0}, {100, 1}, {400, 2}, {1e6, 2}}
+	for _, tc := range cases {
+		if got := c.Pick(tc.distance, fovY, 1000, 5); got != tc.expected {
+			t.Errorf("At distance %v expected level %v, got %v", tc.distance, tc.expected, got)
+		}
+	}
+}
+
+func TestWriteGLTF(t *testing.T) {
+	c, err := Build(shape.Sphere(800, 100), Options{NumLevels: 3, Simplify: simplify.Options{}})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := c.WriteGLTF(&buf); err != nil {
+		t.Fatalf("WriteGLTF failed: %v", err)
+	}
+	var doc gltfDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Error reading the glTF back: %v", err)
+	}
+	if len(doc.ExtensionsUsed) != 1 || doc.ExtensionsUsed[0] != "MSFT_lod" {
+		t.Errorf("Expected MSFT_lod to be used, got %v", doc.ExtensionsUsed)
+	}
+	if len(doc.Nodes) != 3 || len(doc.Meshes) != 3 || len(doc.Accessors) != 6 {
+		t.Fatalf("Expected 3 nodes, 3 meshes and 6 accessors, got %d, %d and %d", len(doc.Nodes), len(doc.Meshes), len(doc.Accessors))
+	}
+	ids := doc.Nodes[0].Extensions["MSFT_lod"].(map[string]interface{})["ids"].([]interface{})
+	if len(ids) != 2 || ids[0].(float64) != 1 || ids[1].(float64) != 2 {
+		t.Errorf("Expected the MSFT_lod ids [1 2], got %v", ids)
+	}
+	for i, node := range doc.Nodes {
+		if node.Extras.Error != c.Levels[i].Error || node.Extras.NumFacets != c.Levels[i].Mesh.GetNumFacets() {
+			t.Errorf("Node %d does not carry the error of its level: %+v", i, node.Extras)
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(doc.Buffers[0].URI, "data:application/octet-stream;base64,"))
+	if err != nil || len(data) != doc.Buffers[0].ByteLength {
+		t.Errorf("Expected an embedded buffer of %d bytes, got %d (%v)", doc.Buffers[0].ByteLength, len(data), err)
+	}
+	last := doc.BufferViews[len(doc.BufferViews)-1]
+	if last.ByteOffset+last.ByteLength != len(data) {
+		t.Errorf("The buffer views don't cover the buffer")
+	}
+}
+
+func TestWriteSTL(t *testing.T) {
+	c, err := Build(shape.Sphere(800, 100), Options{NumLevels: 3})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "lod")
+	if err != nil {
+		t.Fatalf("Error creating a directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	names, err := c.WriteSTL(filepath.Join(dir, "sphere"))
+	if err != nil {
+		t.Fatalf("WriteSTL failed: %v", err)
+	}
+	for i, name := range names {
+		if filepath.Base(name) != "sphere_"+string(rune('0'+i))+".stl" {
+			t.Errorf("Unexpected name %v", name)
+		}
+		m, err := stl.LoadSTLFile(name)
+		if err != nil {
+			t.Fatalf("Error loading %v: %v", name, err)
+		}
+		if m.GetNumFacets() != c.Levels[i].Mesh.GetNumFacets() {
+			t.Errorf("Expected %v to have %d triangles, got %d", name, c.Levels[i].Mesh.GetNumFacets(), m.GetNumFacets())
+		}
+	}
+	if _, err := c.WriteSTL(filepath.Join(dir, "missing", "sphere")); err == nil {
+		t.Error("Expected an error writing to a missing directory")
+	}
+}
@@ -1,32 +1,292 @@
 package main
 
 import (
-	//"fmt"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/lod"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/remesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/repair"
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
-	//"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
-	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/vsa"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/simplify"
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/stl"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/validate"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/vsa"
 )
 
+const usage = `usage: cloud-mesh-simplifier <command> [flags]
+
+commands:
+  simplify    simplify an STL file with one or more algorithms
+  lod         build a chain of levels of detail from an STL file
+  remesh      remesh an STL file to a target edge length
+  validate    check an STL file and print a report
+  repair      fix the orientation of an STL file and fill its holes
+  algorithms  list the available algorithms
+  demo        run VSA on a generated octahedron
+`
+
 func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "simplify":
+		err = runSimplify(os.Args[2:])
+	case "lod":
+		err = runLOD(os.Args[2:])
+	case "remesh":
+		err = runRemesh(os.Args[2:])
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "repair":
+		err = runRepair(os.Args[2:])
+	case "algorithms":
+		fmt.Println(strings.Join(simplify.Names(), "\n"))
+	case "demo":
+		runDemo()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runSimplify runs every algorithm of -algorithm (a comma separated list) on the
+// input, writes the results and prints their reports as JSON
+func runSimplify(args []string) error {
+	flags := flag.NewFlagSet("simplify", flag.ExitOnError)
+	in := flags.String("in", "", "input STL file")
+	weld := flags.Float64("weld", 0, "merge the vertices of the input within this distance of each other")
+	out := flags.String("out", "simplified.stl", "output STL file; with several algorithms the name of each is added")
+	algorithms := flags.String("algorithm", "qem", "comma separated algorithms: "+strings.Join(simplify.Names(), ", "))
+	var opts simplify.Options
+	target := flags.Uint("target", 0, "number of triangles to aim for")
+	flags.Float64Var(&opts.MaxError, "maxerror", 0, "largest error, in the algorithm's own metric")
+	cellSize := flags.Float64("cellsize", 0, "cell size of grid based algorithms")
+	flags.IntVar(&opts.Resolution, "resolution", 0, "cells along the longest side for grid based algorithms")
+	flags.Int64Var(&opts.Seed, "seed", 0, "seed of randomized algorithms, 0 for the clock")
+	flags.BoolVar(&opts.Measure, "measure", false, "measure the distance between the input and the output")
+	flags.BoolVar(&opts.LockBoundary, "lockboundary", false, "keep the boundary of open meshes in place")
+	flags.Float64Var(&opts.BoundaryTolerance, "boundarytolerance", 0, "how far a locked boundary may move")
+	flags.Parse(args)
+	if *in == "" {
+		return fmt.Errorf("simplify: -in is required")
+	}
+	opts.TargetFacets = uint32(*target)
+	opts.CellSize = float32(*cellSize)
+
+	m, err := loadSTL("simplify", *in, *weld)
+	if err != nil {
+		return err
+	}
+	names := strings.Split(*algorithms, ",")
+	reports := make([]simplify.Report, 0, len(names))
+	for _, name := range names {
+		simplified, report, err := simplify.Run(strings.TrimSpace(name), m, opts)
+		if err != nil {
+			return fmt.Errorf("simplify: %v", err)
+		}
+		name := *out
+		if len(names) > 1 {
+			name = strings.TrimSuffix(*out, ".stl") + "_" + report.Algorithm + ".stl"
+		}
+		if err := stl.SaveSTLFile(simplified, name); err != nil {
+			return fmt.Errorf("simplify: %v", err)
+		}
+		reports = append(reports, report)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+// runLOD builds a chain of levels of detail and writes it as numbered STL files or as a glTF
+func runLOD(args []string) error {
+	flags := flag.NewFlagSet("lod", flag.ExitOnError)
+	in := flags.String("in", "", "input STL file")
+	weld := flags.Float64("weld", 0, "merge the vertices of the input within this distance of each other")
+	out := flags.String("out", "lod", "output: the prefix of the STL files, or the glTF file")
+	format := flags.String("format", "stl", "stl or gltf")
+	var opts lod.Options
+	flags.IntVar(&opts.NumLevels, "levels", 4, "number of levels, including the input")
+	flags.Float64Var(&opts.Ratio, "ratio", .5, "triangles of a level relative to the previous one")
+	flags.StringVar(&opts.Algorithm, "algorithm", "qem", "algorithm: "+strings.Join(simplify.Names(), ", "))
+	flags.Int64Var(&opts.Simplify.Seed, "seed", 0, "seed of randomized algorithms, 0 for the clock")
+	flags.Parse(args)
+	if *in == "" {
+		return fmt.Errorf("lod: -in is required")
+	}
+
+	m, err := loadSTL("lod", *in, *weld)
+	if err != nil {
+		return err
+	}
+	chain, err := lod.Build(m, opts)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "stl":
+		if _, err := chain.WriteSTL(*out); err != nil {
+			return err
+		}
+	case "gltf":
+		if err := chain.SaveGLTF(*out); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("lod: unknown format %q", *format)
+	}
+	for i, level := range chain.Levels {
+		fmt.Printf("level %d: %d triangles, error %g (mean %g)\n", i, level.Mesh.GetNumFacets(), level.Error, level.MeanError)
+	}
+	return nil
+}
+
+// runRemesh remeshes the input isotropically to the given edge length
+func runRemesh(args []string) error {
+	flags := flag.NewFlagSet("remesh", flag.ExitOnError)
+	in := flags.String("in", "", "input STL file")
+	weld := flags.Float64("weld", 0, "merge the vertices of the input within this distance of each other")
+	out := flags.String("out", "remeshed.stl", "output STL file")
+	var opts remesh.Options
+	flags.Float64Var(&opts.TargetEdgeLength, "length", 0, "target edge length")
+	flags.IntVar(&opts.Iterations, "iterations", 5, "number of remeshing rounds")
+	flags.Parse(args)
+	if *in == "" {
+		return fmt.Errorf("remesh: -in is required")
+	}
+
+	m, err := loadSTL("remesh", *in, *weld)
+	if err != nil {
+		return err
+	}
+	remeshed, err := remesh.Remesh(m, opts)
+	if err != nil {
+		return err
+	}
+	if err := stl.SaveSTLFile(remeshed, *out); err != nil {
+		return fmt.Errorf("remesh: %v", err)
+	}
+	fmt.Printf("%d triangles, %d vertices\n", remeshed.GetNumFacets(), remeshed.GetNumVertices())
+	return nil
+}
+
+// runValidate prints the validation report of the input as JSON, and fails when the
+// mesh has problems, or is not watertight with -watertight
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	in := flags.String("in", "", "input STL file")
+	weld := flags.Float64("weld", 0, "merge the vertices of the input within this distance of each other")
+	watertight := flags.Bool("watertight", false, "also fail when the mesh is not watertight")
+	flags.Parse(args)
+	if *in == "" {
+		return fmt.Errorf("validate: -in is required")
+	}
 
-	//it TriangleIterator = FacetSphere(r double )
+	m, err := loadSTL("validate", *in, *weld)
+	if err != nil {
+		return err
+	}
+	report := validate.Validate(m)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	problems := report.Problems()
+	if *watertight && !report.Watertight {
+		problems = append(problems, "the mesh is not watertight")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("validate: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// runRepair orients the triangles of the input consistently, and fills its holes
+// with -fillholes
+func runRepair(args []string) error {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	in := flags.String("in", "", "input STL file")
+	weld := flags.Float64("weld", 0, "merge the vertices of the input within this distance of each other")
+	out := flags.String("out", "repaired.stl", "output STL file")
+	fillHoles := flags.Bool("fillholes", false, "fill the holes of the mesh")
+	var holeOpts repair.HoleOptions
+	flags.IntVar(&holeOpts.MaxEdges, "maxhole", repair.DefaultMaxEdges, "number of edges of the largest hole to fill, negative for all")
+	dihedral := flags.Bool("dihedral", false, "fill holes minimizing the dihedral angles rather than the area")
+	flags.BoolVar(&holeOpts.Fair, "fair", false, "add vertices to the filled holes and smooth them")
+	flags.Parse(args)
+	if *in == "" {
+		return fmt.Errorf("repair: -in is required")
+	}
+
+	m, err := loadSTL("repair", *in, *weld)
+	if err != nil {
+		return err
+	}
+	oriented, flipped, err := repair.Orient(m)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("flipped %d triangles\n", len(flipped))
+	if *fillHoles {
+		if *dihedral {
+			holeOpts.Weight = repair.MinimumDihedral
+		}
+		var holes []repair.Hole
+		oriented, holes, err = repair.FillHoles(oriented, holeOpts)
+		if err != nil {
+			return err
+		}
+		numFilled := 0
+		for _, h := range holes {
+			if h.Filled {
+				numFilled++
+			}
+		}
+		fmt.Printf("filled %d of %d holes\n", numFilled, len(holes))
+	}
+	if err := stl.SaveSTLFile(oriented, *out); err != nil {
+		return fmt.Errorf("repair: %v", err)
+	}
+	return nil
+}
+
+// loadSTL loads the input of a command, merging its vertices within weld of each
+// other when weld is positive
+func loadSTL(command string, path string, weld float64) (cloudmesh.IndexedMesh, error) {
+	if weld <= 0 {
+		m, err := stl.LoadSTLFile(path)
+		if err != nil {
+			return m, fmt.Errorf("%s: %v", command, err)
+		}
+		return m, nil
+	}
+	m, numMerged, err := stl.LoadSTLFileWelded(path, float32(weld))
+	if err != nil {
+		return m, fmt.Errorf("%s: %v", command, err)
+	}
+	log.Printf("welded %d vertices", numMerged)
+	return m, nil
+}
 
+func runDemo() {
 	octahedron := shape.Octahedron(2000)
 	stl.WriteSTLMeshName(octahedron, "fancyOctahedron.stl")
-	_,_ = vsa.VSAVanilla(octahedron)
-
-	/*go func() {
-		log.Println(http.ListenAndServe("localhost:6060", nil))
-	}()
-	*/
-	//myMesh, _ := stl.LoadSTLFile("/Users/bluzytrix/Downloads/Charon 01.stl")
-	//myMesh := shape.CreatePlane(600)
-	//myMesh := shape.BasicCube()
-	//e := vsa.VSAVanilla(myMesh)
-	//for i := 0; i < len(e); i++ {
-	//	fmt.Printf("Proxy: %v\n", e[i])
-	//}
-
-	//stl.WriteSTLMesh(myMesh)
+	opts := vsa.DefaultOptions()
+	opts.Verbose = true
+	if _, err := vsa.Run(octahedron, opts); err != nil {
+		log.Fatal(err)
+	}
 }
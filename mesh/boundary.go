@@ -0,0 +1,118 @@
+package mesh
+
+import (
+	"errors"
+)
+
+// FindBoundaryEdges returns the boundary edges of the mesh, i.e., the edges that
+// belong to a single triangle, oriented as in that triangle.  An edge is interior if
+// one of the neighbors of its triangle in the neighborhood also uses both of its vertices.
+func FindBoundaryEdges(m Mesh, neighborhood MeshNeighborhood) ([][2]uint32, error) {
+	edges := make([][2]uint32, 0)
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return make([][2]uint32, 0), err
+		}
+		neighbors, err := neighborhood.GetTriangleNeighborsOfTriangle(t)
+		if err != nil {
+			return make([][2]uint32, 0), err
+		}
+		if len(neighbors) == 3 {
+			continue
+		}
+		neighborVertices := make([][]uint32, len(neighbors))
+		for i, n := range neighbors {
+			if neighborVertices[i], err = m.GetVertices(n); err != nil {
+				return make([][2]uint32, 0), err
+			}
+		}
+		for c := 0; c < 3; c++ {
+			a, b := vertices[c], vertices[(c+1)%3]
+			if a == b {
+				continue
+			}
+			shared := false
+			for _, nv := range neighborVertices {
+				if containsVertex(nv, a) && containsVertex(nv, b) {
+					shared = true
+					break
+				}
+			}
+			if !shared {
+				edges = append(edges, [2]uint32{a, b})
+			}
+		}
+	}
+	return edges, nil
+}
+
+func containsVertex(vertices []uint32, v uint32) bool {
+	return vertices[0] == v || vertices[1] == v || vertices[2] == v
+}
+
+// BoundaryVertices flags the vertices of the mesh that are on one of the edges
+func BoundaryVertices(m Mesh, edges [][2]uint32) ([]bool, error) {
+	boundary := make([]bool, m.GetNumVertices())
+	for _, e := range edges {
+		if e[0] >= m.GetNumVertices() || e[1] >= m.GetNumVertices() {
+			return boundary, errors.New("BoundaryVertices: vertex index is out of bounds")
+		}
+		boundary[e[0]] = true
+		boundary[e[1]] = true
+	}
+	return boundary, nil
+}
+
+// FindBoundaryLoops chains the boundary edges, as returned by FindBoundaryEdges, into
+// loops of vertices: each loop follows the edges head to tail, so it runs the way
+// its triangles are oriented.  A vertex where several loops meet starts as many
+// edges; they are chained in the order of the edges.  When the edges do not close
+// (e.g., around a triangle with inconsistent winding) the chain is returned open.
+func FindBoundaryLoops(edges [][2]uint32) [][]uint32 {
+	outgoing := make(map[uint32][]int, len(edges))
+	incoming := make(map[uint32]int, len(edges))
+	for i, e := range edges {
+		outgoing[e[0]] = append(outgoing[e[0]], i)
+		incoming[e[1]]++
+	}
+	// open chains start where no edge comes in
+	order := make([]int, 0, len(edges))
+	for i, e := range edges {
+		if incoming[e[0]] == 0 {
+			order = append(order, i)
+		}
+	}
+	for i := range edges {
+		order = append(order, i)
+	}
+
+	used := make([]bool, len(edges))
+	loops := make([][]uint32, 0)
+	for _, i := range order {
+		if used[i] {
+			continue
+		}
+		loop := make([]uint32, 0)
+		for e := i; ; {
+			used[e] = true
+			loop = append(loop, edges[e][0])
+			next := -1
+			for _, candidate := range outgoing[edges[e][1]] {
+				if !used[candidate] {
+					next = candidate
+					break
+				}
+			}
+			if next < 0 {
+				if edges[e][1] != loop[0] {
+					loop = append(loop, edges[e][1])
+				}
+				break
+			}
+			e = next
+		}
+		loops = append(loops, loop)
+	}
+	return loops
+}
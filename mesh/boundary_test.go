@@ -0,0 +1,88 @@
+package mesh
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFindBoundaryEdges(t *testing.T) {
+	// a closed mesh has no boundary
+	octahedron := createOctahedronMesh()
+	edges, err := FindBoundaryEdges(octahedron, CreateNeighborhood(octahedron))
+	if err != nil || len(edges) != 0 {
+		t.Errorf("Expected no boundary edges on the octahedron, got %v (%v)", edges, err)
+	}
+
+	// an n x n grid has 4n boundary edges, and (n-1)^2 interior vertices
+	grid := createBumpyGrid(5)
+	edges, err = FindBoundaryEdges(grid, CreateNeighborhood(grid))
+	if err != nil {
+		t.Fatalf("Error finding boundary edges: %v", err)
+	}
+	if len(edges) != 20 {
+		t.Errorf("Expected 20 boundary edges, got %v", len(edges))
+	}
+	boundary, err := BoundaryVertices(grid, edges)
+	if err != nil {
+		t.Fatalf("Error flagging boundary vertices: %v", err)
+	}
+	numInterior := 0
+	for v, b := range boundary {
+		x, y := v%6, v/6
+		onSide := x == 0 || y == 0 || x == 5 || y == 5
+		if b != onSide {
+			t.Errorf("Vertex %v (%v,%v): expected boundary %v, got %v", v, x, y, onSide, b)
+		}
+		if !b {
+			numInterior++
+		}
+	}
+	if numInterior != 16 {
+		t.Errorf("Expected 16 interior vertices, got %v", numInterior)
+	}
+
+	// the boundary edges keep the orientation of their triangle
+	single := myIndexedMesh{Indices: []uint32{0, 1, 2}, Vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}}
+	edges, _ = FindBoundaryEdges(single, CreateNeighborhood(single))
+	expected := [][2]uint32{{0, 1}, {1, 2}, {2, 0}}
+	if len(edges) != 3 {
+		t.Fatalf("Expected 3 boundary edges, got %v", edges)
+	}
+	for i := range expected {
+		if edges[i] != expected[i] {
+			t.Errorf("Expected edge %v, got %v", expected[i], edges[i])
+		}
+	}
+}
+
+func TestFindBoundaryLoops(t *testing.T) {
+	// the boundary of a grid is a single loop through its 4n side vertices
+	grid := createBumpyGrid(4)
+	edges, err := FindBoundaryEdges(grid, CreateNeighborhood(grid))
+	if err != nil {
+		t.Fatalf("Error finding boundary edges: %v", err)
+	}
+	loops := FindBoundaryLoops(edges)
+	if len(loops) != 1 || len(loops[0]) != 16 {
+		t.Fatalf("Expected a single loop of 16 vertices, got %v", loops)
+	}
+	// the loop follows the triangles: 0 to 1 along the bottom side
+	for i, v := range loops[0] {
+		if v == 0 && loops[0][(i+1)%16] != 1 {
+			t.Errorf("Expected the loop to go from 0 to 1, got %v", loops[0])
+		}
+	}
+
+	// two triangles touching at a vertex have two loops, and an open chain stays open
+	loops = FindBoundaryLoops([][2]uint32{{0, 1}, {1, 2}, {2, 0}, {0, 3}, {3, 4}, {4, 0}})
+	if len(loops) != 1 || len(loops[0]) != 6 {
+		t.Errorf("Expected the bowtie to be chained into a loop of 6 vertices, got %v", loops)
+	}
+	loops = FindBoundaryLoops([][2]uint32{{1, 2}, {5, 6}, {0, 1}})
+	if len(loops) != 2 || fmt.Sprint(loops[0]) != "[5 6]" || fmt.Sprint(loops[1]) != "[0 1 2]" {
+		t.Errorf("Expected the open chains [5 6] and [0 1 2], got %v", loops)
+	}
+	if loops := FindBoundaryLoops(nil); len(loops) != 0 {
+		t.Errorf("Expected no loops, got %v", loops)
+	}
+}
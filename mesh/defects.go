@@ -0,0 +1,128 @@
+package mesh
+
+import (
+	"fmt"
+)
+
+// DefectKind is the kind of a topological defect found by CreateNeighborhood
+type DefectKind int
+
+const (
+	// NonManifoldEdge is an edge shared by more than two triangles
+	NonManifoldEdge DefectKind = iota
+	// NonManifoldVertex is a vertex where several fans of triangles meet, e.g., the
+	// center of a bowtie
+	NonManifoldVertex
+	// InconsistentWinding is an edge whose two triangles run it in the same direction,
+	// i.e., one of them is flipped relative to the other
+	InconsistentWinding
+)
+
+func (k DefectKind) String() string {
+	switch k {
+	case NonManifoldEdge:
+		return "non-manifold edge"
+	case NonManifoldVertex:
+		return "non-manifold vertex"
+	case InconsistentWinding:
+		return "inconsistent winding"
+	}
+	return fmt.Sprintf("DefectKind(%d)", int(k))
+}
+
+// MarshalText writes the kind by name, e.g., in JSON
+func (k DefectKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Defect is a place where the mesh is not an oriented manifold
+type Defect struct {
+	Kind DefectKind `json:"kind"`
+	// Vertices are the two vertices of the edge, or the vertex
+	Vertices []uint32 `json:"vertices"`
+	// Triangles are the triangles that share the edge, or that use the vertex
+	Triangles []uint32 `json:"triangles"`
+}
+
+func (d Defect) String() string {
+	return fmt.Sprintf("%v %v (triangles %v)", d.Kind, d.Vertices, d.Triangles)
+}
+
+// DefectError is returned by CreateNeighborhoodStrict when the mesh has defects
+type DefectError struct {
+	Defects []Defect
+}
+
+func (e *DefectError) Error() string {
+	if len(e.Defects) == 1 {
+		return fmt.Sprintf("the mesh has a defect: %v", e.Defects[0])
+	}
+	return fmt.Sprintf("the mesh has %d defects, the first is %v", len(e.Defects), e.Defects[0])
+}
+
+// CreateNeighborhoodStrict returns the neighborhood of the mesh, or a *DefectError
+// listing its defects when it is not an oriented manifold
+func CreateNeighborhoodStrict(m Mesh) (MeshNeighborhood, error) {
+	neighborhood := CreateNeighborhood(m)
+	if defects := neighborhood.GetDefects(); len(defects) > 0 {
+		return neighborhood, &DefectError{Defects: defects}
+	}
+	return neighborhood, nil
+}
+
+// checkBar appends the defect of the edge (a, b) of triangle, shared by the triangles
+// of trisBar, if any.  Each defect is reported by the first of its triangles only.
+func checkBar(m Mesh, triangle uint32, a uint32, b uint32, trisBar []uint32, defects []Defect) []Defect {
+	if a == b {
+		return defects
+	}
+	if len(trisBar) > 2 {
+		if trisBar[0] == triangle {
+			defects = append(defects, Defect{Kind: NonManifoldEdge, Vertices: []uint32{a, b}, Triangles: trisBar})
+		}
+		return defects
+	}
+	for _, other := range trisBar {
+		if other <= triangle {
+			continue
+		}
+		vertices, _ := m.GetVertices(other)
+		for c := 0; c < 3; c++ {
+			if vertices[c] == a && vertices[(c+1)%3] == b {
+				defects = append(defects, Defect{Kind: InconsistentWinding, Vertices: []uint32{a, b}, Triangles: []uint32{triangle, other}})
+			}
+		}
+	}
+	return defects
+}
+
+// countFans returns the number of fans made by the triangles (vertex, xs[i], ys[i]),
+// i.e., the number of connected components of the edges (xs[i], ys[i]) around the vertex
+func countFans(vertex uint32, xs []uint32, ys []uint32) int {
+	parent := make(map[uint32]uint32, 2*len(xs))
+	var find func(w uint32) uint32
+	find = func(w uint32) uint32 {
+		if parent[w] != w {
+			parent[w] = find(parent[w])
+		}
+		return parent[w]
+	}
+	for i := range xs {
+		if xs[i] == vertex || ys[i] == vertex {
+			continue // degenerate
+		}
+		for _, w := range []uint32{xs[i], ys[i]} {
+			if _, ok := parent[w]; !ok {
+				parent[w] = w
+			}
+		}
+		parent[find(xs[i])] = find(ys[i])
+	}
+	numFans := 0
+	for w := range parent {
+		if find(w) == w {
+			numFans++
+		}
+	}
+	return numFans
+}
@@ -0,0 +1,46 @@
+package mesh
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefects(t *testing.T) {
+	octahedron := createOctahedronMesh()
+	if defects := CreateNeighborhood(octahedron).GetDefects(); len(defects) != 0 {
+		t.Errorf("Expected no defects on the octahedron, got %v", defects)
+	}
+	if _, err := CreateNeighborhoodStrict(octahedron); err != nil {
+		t.Errorf("Expected no error on the octahedron, got %v", err)
+	}
+
+	vertices := []float32{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, -1, 0, 0, 0, 1, -1, 0, 0, -1, -1, 0}
+	cases := []struct {
+		name     string
+		indices  []uint32
+		expected Defect
+	}{
+		{"three triangles on an edge", []uint32{0, 1, 2, 1, 0, 3, 0, 1, 4},
+			Defect{Kind: NonManifoldEdge, Vertices: []uint32{0, 1}, Triangles: []uint32{0, 1, 2}}},
+		{"flipped triangle", []uint32{0, 1, 2, 0, 1, 3},
+			Defect{Kind: InconsistentWinding, Vertices: []uint32{0, 1}, Triangles: []uint32{0, 1}}},
+		{"bowtie", []uint32{0, 1, 2, 0, 5, 6},
+			Defect{Kind: NonManifoldVertex, Vertices: []uint32{0}, Triangles: []uint32{0, 1}}},
+	}
+	for _, c := range cases {
+		m := myIndexedMesh{Indices: c.indices, Vertices: vertices}
+		defects := CreateNeighborhood(m).GetDefects()
+		if len(defects) != 1 {
+			t.Errorf("%s: expected a single defect, got %v", c.name, defects)
+			continue
+		}
+		if defects[0].String() != c.expected.String() {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, defects[0])
+		}
+		_, err := CreateNeighborhoodStrict(m)
+		var defectErr *DefectError
+		if !errors.As(err, &defectErr) || len(defectErr.Defects) != 1 {
+			t.Errorf("%s: expected a DefectError, got %v", c.name, err)
+		}
+	}
+}
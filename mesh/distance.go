@@ -0,0 +1,310 @@
+package mesh
+
+import (
+	"errors"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+)
+
+// ClosestPointGrid answers closest point queries on the surface of a mesh.  The
+// triangles are bucketed in a uniform grid by bounding box, and a query visits
+// the cells in growing rings around the query point until no closer triangle can exist.
+type ClosestPointGrid struct {
+	triangles [][3][3]float64
+	min       [3]float64
+	cellSize  float64
+	dims      [3]int
+	// cells in CSR layout: the triangles of cell c are cellTris[cellStart[c]:cellStart[c+1]]
+	cellStart []uint32
+	cellTris  []uint32
+}
+
+// NewClosestPointGrid builds the grid for the triangles of the mesh
+func NewClosestPointGrid(m Mesh) (*ClosestPointGrid, error) {
+	numTris := m.GetNumFacets()
+	if numTris == 0 {
+		return nil, errors.New("NewClosestPointGrid: the mesh has no triangles")
+	}
+	g := &ClosestPointGrid{triangles: make([][3][3]float64, numTris)}
+	min := [3]float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	max := [3]float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for t := uint32(0); t < numTris; t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return nil, err
+		}
+		for c, v := range vertices {
+			if v >= m.GetNumVertices() {
+				return nil, errors.New("NewClosestPointGrid: vertex index is out of bounds")
+			}
+			p, _ := m.GetPoint(v)
+			for i := 0; i < 3; i++ {
+				g.triangles[t][c][i] = float64(p[i])
+				min[i] = math.Min(min[i], float64(p[i]))
+				max[i] = math.Max(max[i], float64(p[i]))
+			}
+		}
+	}
+
+	// about one triangle per cell
+	extent := 0.0
+	volume := 1.0
+	for i := 0; i < 3; i++ {
+		extent = math.Max(extent, max[i]-min[i])
+	}
+	if extent == 0 {
+		extent = 1
+	}
+	for i := 0; i < 3; i++ {
+		volume *= math.Max(max[i]-min[i], extent*1e-3)
+	}
+	g.cellSize = math.Max(math.Cbrt(volume/float64(numTris)), extent/1024)
+	g.min = min
+	numCells := 1
+	for i := 0; i < 3; i++ {
+		g.dims[i] = int((max[i]-min[i])/g.cellSize) + 1
+		numCells *= g.dims[i]
+	}
+
+	// count, then fill
+	g.cellStart = make([]uint32, numCells+1)
+	g.forEachCell(func(t uint32, c int) { g.cellStart[c+1]++ })
+	for c := 0; c < numCells; c++ {
+		g.cellStart[c+1] += g.cellStart[c]
+	}
+	g.cellTris = make([]uint32, g.cellStart[numCells])
+	fill := make([]uint32, numCells)
+	copy(fill, g.cellStart[:numCells])
+	g.forEachCell(func(t uint32, c int) {
+		g.cellTris[fill[c]] = t
+		fill[c]++
+	})
+	return g, nil
+}
+
+// forEachCell calls f for every (triangle, cell) pair where the cell overlaps the triangle's bounding box
+func (g *ClosestPointGrid) forEachCell(f func(t uint32, c int)) {
+	for t, tri := range g.triangles {
+		var lo, hi [3]int
+		for i := 0; i < 3; i++ {
+			lo[i] = g.cellCoord(math.Min(tri[0][i], math.Min(tri[1][i], tri[2][i])), i)
+			hi[i] = g.cellCoord(math.Max(tri[0][i], math.Max(tri[1][i], tri[2][i])), i)
+		}
+		for x := lo[0]; x <= hi[0]; x++ {
+			for y := lo[1]; y <= hi[1]; y++ {
+				for z := lo[2]; z <= hi[2]; z++ {
+					f(uint32(t), g.cellIndex(x, y, z))
+				}
+			}
+		}
+	}
+}
+
+func (g *ClosestPointGrid) cellCoord(f float64, axis int) int {
+	c := int((f - g.min[axis]) / g.cellSize)
+	if c < 0 {
+		return 0
+	}
+	if c >= g.dims[axis] {
+		return g.dims[axis] - 1
+	}
+	return c
+}
+
+func (g *ClosestPointGrid) cellIndex(x, y, z int) int {
+	return (z*g.dims[1]+y)*g.dims[0] + x
+}
+
+// Closest returns the point of the surface closest to p, the triangle it lies on,
+// and its distance to p
+func (g *ClosestPointGrid) Closest(p [3]float64) (closest [3]float64, facet uint32, distance float64) {
+	var center [3]int
+	for i := 0; i < 3; i++ {
+		center[i] = g.cellCoord(p[i], i)
+	}
+	best := math.MaxFloat64
+	maxRing := g.dims[0]
+	if g.dims[1] > maxRing {
+		maxRing = g.dims[1]
+	}
+	if g.dims[2] > maxRing {
+		maxRing = g.dims[2]
+	}
+	for ring := 0; ring <= maxRing; ring++ {
+		for x := center[0] - ring; x <= center[0]+ring; x++ {
+			for y := center[1] - ring; y <= center[1]+ring; y++ {
+				for z := center[2] - ring; z <= center[2]+ring; z++ {
+					if x < 0 || y < 0 || z < 0 || x >= g.dims[0] || y >= g.dims[1] || z >= g.dims[2] {
+						continue
+					}
+					// only the shell of the ring is new
+					if abs(x-center[0]) != ring && abs(y-center[1]) != ring && abs(z-center[2]) != ring {
+						continue
+					}
+					c := g.cellIndex(x, y, z)
+					for _, t := range g.cellTris[g.cellStart[c]:g.cellStart[c+1]] {
+						q := ClosestPointOnTriangle(p, g.triangles[t])
+						d := distanceSquared(p, q)
+						if d < best {
+							best, closest, facet = d, q, t
+						}
+					}
+				}
+			}
+		}
+		// everything outside of the rings visited so far is at least this far away
+		if best < math.MaxFloat64 && math.Sqrt(best) <= g.ringDistance(p, center, ring) {
+			break
+		}
+	}
+	return closest, facet, math.Sqrt(best)
+}
+
+// ringDistance is the distance from p to the outside of the cells within ring of center
+func (g *ClosestPointGrid) ringDistance(p [3]float64, center [3]int, ring int) float64 {
+	d := math.MaxFloat64
+	for i := 0; i < 3; i++ {
+		lo := g.min[i] + float64(center[i]-ring)*g.cellSize
+		hi := g.min[i] + float64(center[i]+ring+1)*g.cellSize
+		if center[i]-ring > 0 {
+			d = math.Min(d, p[i]-lo)
+		}
+		if center[i]+ring+1 < g.dims[i] {
+			d = math.Min(d, hi-p[i])
+		}
+	}
+	return math.Max(d, 0)
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func distanceSquared(a [3]float64, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// ClosestPointOnTriangle returns the point of the triangle closest to p
+// (Ericson, Real-Time Collision Detection, 5.1.5)
+func ClosestPointOnTriangle(p [3]float64, tri [3][3]float64) [3]float64 {
+	a, b, c, q := auxmath.Vec3d(tri[0]), auxmath.Vec3d(tri[1]), auxmath.Vec3d(tri[2]), auxmath.Vec3d(p)
+
+	ab, ac, ap := b.Sub(a), c.Sub(a), q.Sub(a)
+	d1, d2 := ab.Dot(ap), ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a
+	}
+	bp := q.Sub(b)
+	d3, d4 := ab.Dot(bp), ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return a.Add(ab.Scale(d1 / (d1 - d3)))
+	}
+	cp := q.Sub(c)
+	d5, d6 := ab.Dot(cp), ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return a.Add(ac.Scale(d2 / (d2 - d6)))
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		return b.Add(c.Sub(b).Scale((d4 - d3) / ((d4 - d3) + (d5 - d6))))
+	}
+	denom := va + vb + vc
+	if denom == 0 {
+		// degenerate triangle: all of its points are on the edges
+		return a
+	}
+	return a.Add(ab.Scale(vb / denom)).Add(ac.Scale(vc / denom))
+}
+
+// Barycentric returns the weights of the corners of the triangle whose sum is the
+// point of the triangle closest to p.  A degenerate triangle puts all the weight on
+// its first corner.
+func Barycentric(p [3]float64, tri [3][3]float64) [3]float64 {
+	a := auxmath.Vec3d(tri[0])
+	ab, ac, aq := auxmath.Vec3d(tri[1]).Sub(a), auxmath.Vec3d(tri[2]).Sub(a), auxmath.Vec3d(ClosestPointOnTriangle(p, tri)).Sub(a)
+	d00, d01, d11 := ab.Dot(ab), ab.Dot(ac), ac.Dot(ac)
+	d20, d21 := aq.Dot(ab), aq.Dot(ac)
+	denom := d00*d11 - d01*d01
+	if denom <= 1e-12*d00*d11 {
+		return [3]float64{1, 0, 0}
+	}
+	v := (d11*d20 - d01*d21) / denom
+	w := (d00*d21 - d01*d20) / denom
+	return [3]float64{1 - v - w, v, w}
+}
+
+// Distance measures how far the surface of one mesh is from another.  It samples
+// the vertices and triangle centroids of from and returns the largest and the
+// mean distance of the samples to the surface of to.
+func Distance(from Mesh, to *ClosestPointGrid) (max float64, mean float64, err error) {
+	numSamples := 0
+	sample := func(p [3]float64) {
+		_, _, d := to.Closest(p)
+		max = math.Max(max, d)
+		mean += d
+		numSamples++
+	}
+	for v := uint32(0); v < from.GetNumVertices(); v++ {
+		p, err := from.GetPoint(v)
+		if err != nil {
+			return 0, 0, err
+		}
+		sample([3]float64{float64(p[0]), float64(p[1]), float64(p[2])})
+	}
+	for t := uint32(0); t < from.GetNumFacets(); t++ {
+		vertices, err := from.GetVertices(t)
+		if err != nil {
+			return 0, 0, err
+		}
+		var centroid [3]float64
+		for _, v := range vertices {
+			if v >= from.GetNumVertices() {
+				return 0, 0, errors.New("Distance: vertex index is out of bounds")
+			}
+			p, _ := from.GetPoint(v)
+			for i := 0; i < 3; i++ {
+				centroid[i] += float64(p[i]) / 3
+			}
+		}
+		sample(centroid)
+	}
+	if numSamples > 0 {
+		mean /= float64(numSamples)
+	}
+	return max, mean, nil
+}
+
+// HausdorffDistance approximates the symmetric Hausdorff distance between two
+// meshes (see Distance).  It returns the largest and the mean of the two one-sided distances.
+func HausdorffDistance(a Mesh, b Mesh) (max float64, mean float64, err error) {
+	gridA, err := NewClosestPointGrid(a)
+	if err != nil {
+		return 0, 0, err
+	}
+	gridB, err := NewClosestPointGrid(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxAB, meanAB, err := Distance(a, gridB)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxBA, meanBA, err := Distance(b, gridA)
+	if err != nil {
+		return 0, 0, err
+	}
+	return math.Max(maxAB, maxBA), math.Max(meanAB, meanBA), nil
+}
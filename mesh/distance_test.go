@@ -0,0 +1,110 @@
+package mesh
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+)
+
+// createBumpyGrid creates a grid of n x n squares with a bumpy height
+func createBumpyGrid(n int) cloudmesh.IndexedMesh {
+	m := cloudmesh.IndexedMesh{Indices: make([]uint32, 0), Vertices: make([]float32, 0)}
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			z := float32(math.Sin(float64(x)/3) * math.Cos(float64(y)/2))
+			m.Vertices = append(m.Vertices, float32(x), float32(y), z)
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			v := uint32(y*(n+1) + x)
+			m.Indices = append(m.Indices, v, v+1, v+uint32(n)+2, v, v+uint32(n)+2, v+uint32(n)+1)
+		}
+	}
+	return m
+}
+
+func TestClosestPointOnTriangle(t *testing.T) {
+	tri := [3][3]float64{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	cases := []struct{ p, expected [3]float64 }{
+		{[3]float64{.25, .25, 1}, [3]float64{.25, .25, 0}},
+		{[3]float64{-1, -1, 0}, [3]float64{0, 0, 0}},
+		{[3]float64{2, -1, 0}, [3]float64{1, 0, 0}},
+		{[3]float64{.5, -1, 3}, [3]float64{.5, 0, 0}},
+		{[3]float64{1, 1, 0}, [3]float64{.5, .5, 0}},
+		{[3]float64{-1, .5, 0}, [3]float64{0, .5, 0}},
+	}
+	for _, c := range cases {
+		got := ClosestPointOnTriangle(c.p, tri)
+		if distanceSquared(got, c.expected) > 1e-12 {
+			t.Errorf("Closest point to %v: expected %v, got %v", c.p, c.expected, got)
+		}
+	}
+}
+
+func TestBarycentric(t *testing.T) {
+	tri := [3][3]float64{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	cases := []struct{ p, expected [3]float64 }{
+		{[3]float64{0, 0, 1}, [3]float64{1, 0, 0}},
+		{[3]float64{.25, .5, -2}, [3]float64{.25, .25, .5}},
+		{[3]float64{3, -1, 0}, [3]float64{0, 1, 0}},
+		{[3]float64{1, 1, 0}, [3]float64{0, .5, .5}},
+	}
+	for _, c := range cases {
+		got := Barycentric(c.p, tri)
+		for i := 0; i < 3; i++ {
+			if math.Abs(got[i]-c.expected[i]) > 1e-9 {
+				t.Errorf("Barycentric(%v) = %v, expected %v", c.p, got, c.expected)
+				break
+			}
+		}
+	}
+	if got := Barycentric([3]float64{1, 1, 1}, [3][3]float64{{1, 1, 1}, {1, 1, 1}, {2, 2, 2}}); got != [3]float64{1, 0, 0} {
+		t.Errorf("Barycentric on a degenerate triangle = %v", got)
+	}
+}
+
+func TestClosestPointGrid(t *testing.T) {
+	m := createBumpyGrid(30)
+	g, err := NewClosestPointGrid(m)
+	if err != nil {
+		t.Fatalf("Error creating grid: %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		p := [3]float64{rng.Float64()*40 - 5, rng.Float64()*40 - 5, rng.Float64()*10 - 5}
+		_, facet, d := g.Closest(p)
+		best := math.MaxFloat64
+		for f := 0; f < len(g.triangles); f++ {
+			best = math.Min(best, math.Sqrt(distanceSquared(p, ClosestPointOnTriangle(p, g.triangles[f]))))
+		}
+		if math.Abs(d-best) > 1e-9 {
+			t.Fatalf("Closest distance to %v: expected %v, got %v (triangle %d)", p, best, d, facet)
+		}
+	}
+
+	if _, err := NewClosestPointGrid(cloudmesh.IndexedMesh{}); err == nil {
+		t.Errorf("Expected an error for an empty mesh")
+	}
+}
+
+func TestHausdorffDistance(t *testing.T) {
+	a := createBumpyGrid(10)
+	b := createBumpyGrid(10)
+	for i := 2; i < len(b.Vertices); i += 3 {
+		b.Vertices[i] += .5
+	}
+	max, mean, err := HausdorffDistance(a, b)
+	if err != nil {
+		t.Fatalf("Error computing the distance: %v", err)
+	}
+	if max > .5+1e-6 || mean > max || mean <= 0 {
+		t.Errorf("Expected distances of at most .5, got max %v and mean %v", max, mean)
+	}
+	max, _, _ = HausdorffDistance(a, a)
+	if max > 1e-9 {
+		t.Errorf("Expected no distance from a mesh to itself, got %v", max)
+	}
+}
@@ -19,3 +19,33 @@ type Mesh interface {
 	// Returnt the number of vertices in the mesh.
 	GetNumVertices() uint32
 }
+
+// MutableMesh -- a mesh that can be edited in place
+// Generators and repair tools build and edit meshes through it instead of the
+// slices of a particular mesh type.
+type MutableMesh interface {
+	Mesh
+
+	// Appends a vertex at x, y, z and returns its index.
+	AddVertex(x float32, y float32, z float32) uint32
+
+	// Moves the given vertex to the point p of size 3, returns an error if the
+	// vertex is out of range.
+	SetPoint(vertex uint32, p []float32) error
+
+	// Appends a triangle made of the three vertices.
+	AddTriangle(v1 uint32, v2 uint32, v3 uint32)
+
+	// Replaces the vertices of the given triangle with the three vertices, returns
+	// an error if the triangle is out of range.
+	SetVertices(facet uint32, vertices []uint32) error
+
+	// Removes the given triangles and keeps the others in order.  Returns the new
+	// index of every triangle, math.MaxUint32 for the removed ones, or an error if
+	// a triangle is out of range.  Vertices are never removed.
+	RemoveTriangles(facets []uint32) ([]uint32, error)
+
+	// Makes room for the given numbers of triangles and vertices in total, so that
+	// adding them does not reallocate.
+	Reserve(numFacets uint32, numVertices uint32)
+}
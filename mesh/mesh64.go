@@ -0,0 +1,75 @@
+package mesh
+
+import (
+	"errors"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+)
+
+// Mesh64 is a mesh with double precision vertices.  The 64 helpers below read its
+// points with GetPoint64, and the points of any other mesh with GetPoint, so that
+// algorithms can run in double precision on both.
+type Mesh64 interface {
+	Mesh
+	GetPoint64(vertex uint32) ([3]float64, error)
+}
+
+// GetPoint64 returns the position of a vertex of the mesh in double precision
+func GetPoint64(m Mesh, vertex uint32) ([3]float64, error) {
+	if m64, ok := m.(Mesh64); ok {
+		return m64.GetPoint64(vertex)
+	}
+	p, err := GetPointVec3(m, vertex)
+	return [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}, err
+}
+
+// GetTrianglePoints64 returns the positions of the corners of a triangle of the
+// mesh in double precision
+func GetTrianglePoints64(m Mesh, facet uint32) ([3][3]float64, error) {
+	var points [3][3]float64
+	vertices, err := GetTriangle(m, facet)
+	if err != nil {
+		return points, err
+	}
+	for i, v := range vertices {
+		if points[i], err = GetPoint64(m, v); err != nil {
+			return points, err
+		}
+	}
+	return points, nil
+}
+
+// ComputeNormal64 computes the normal of a triangle in a Mesh in double precision,
+// or returns an error when the triangle is degenerate
+func ComputeNormal64(m Mesh, triIndex uint32) ([3]float64, error) {
+	p, err := GetTrianglePoints64(m, triIndex)
+	if err != nil {
+		return [3]float64{}, err
+	}
+	n, area := auxmath.TriangleNormal(p[0], p[1], p[2])
+	if !(area > 0) || math.IsInf(area, 0) {
+		return [3]float64{}, errors.New("degenerate triangle detected")
+	}
+	return n, nil
+}
+
+// ComputeArea64 computes the area of a triangle in a Mesh in double precision
+func ComputeArea64(m Mesh, triIndex uint32) float64 {
+	p, _ := GetTrianglePoints64(m, triIndex)
+	_, area := auxmath.TriangleNormal(p[0], p[1], p[2])
+	return area
+}
+
+// ComputeCentroid64 computes the geometric center of a triangle in a Mesh in double precision
+func ComputeCentroid64(m Mesh, triangle uint32) ([3]float64, error) {
+	p, err := GetTrianglePoints64(m, triangle)
+	if err != nil {
+		return [3]float64{}, err
+	}
+	var center [3]float64
+	for i := 0; i < 3; i++ {
+		center[i] = (p[0][i] + p[1][i] + p[2][i]) / 3
+	}
+	return center, nil
+}
@@ -0,0 +1,75 @@
+package mesh
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+)
+
+// the double precision mesh can be used by both the float32 and the float64 helpers
+var _ Mesh64 = cloudmesh.IndexedMesh64{}
+var _ Vec3Mesh = cloudmesh.IndexedMesh64{}
+
+func TestMesh64Helpers(t *testing.T) {
+	// a millimeter triangle in the plane z = 100, five million units from the origin
+	far := cloudmesh.IndexedMesh64{
+		Vertices: []float64{5e6, 2e6, 100, 5e6 + .001, 2e6, 100, 5e6, 2e6 + .002, 100},
+		Indices:  []uint32{0, 1, 2},
+	}
+	normal, err := ComputeNormal64(far, 0)
+	if err != nil || math.Abs(normal[2]-1) > 1e-9 {
+		t.Errorf("Expected the normal (0, 0, 1), got %v: %v", normal, err)
+	}
+	if area := ComputeArea64(far, 0); math.Abs(area-1e-6) > 1e-12 {
+		t.Errorf("Expected an area of 1e-6, got %v", area)
+	}
+	if center, err := ComputeCentroid64(far, 0); err != nil || math.Abs(center[0]-(5e6+.001/3)) > 1e-9 {
+		t.Errorf("Expected the centroid at x = 5e6 + .001/3, got %v: %v", center, err)
+	}
+	// in float32 the triangle collapses
+	if _, err := ComputeNormalVec3(far, 0); err == nil {
+		t.Error("Expected the float32 triangle to be degenerate")
+	}
+
+	// a float32 mesh gives its own points
+	near := cloudmesh.IndexedMesh{Vertices: []float32{0, 0, 0, 2, 0, 0, 0, 2, 0}, Indices: []uint32{0, 1, 2}}
+	if area := ComputeArea64(near, 0); area != 2 {
+		t.Errorf("Expected an area of 2, got %v", area)
+	}
+	if _, err := ComputeNormal64(near, 1); err == nil {
+		t.Error("Expected an error as the triangle is out of bounds")
+	}
+	flat := cloudmesh.IndexedMesh{Vertices: []float32{0, 0, 0, 1, 0, 0, 2, 0, 0}, Indices: []uint32{0, 1, 2}}
+	if _, err := ComputeNormal64(flat, 0); err == nil {
+		t.Error("Expected the flat triangle to be degenerate")
+	}
+}
+
+func TestComputeHash64(t *testing.T) {
+	// two millimeter triangles five million units from the origin that are the same in float32
+	a := cloudmesh.IndexedMesh64{
+		Vertices: []float64{5e6, 2e6, 100, 5e6 + .001, 2e6, 100, 5e6, 2e6 + .002, 100},
+		Indices:  []uint32{0, 1, 2},
+	}
+	b := cloudmesh.IndexedMesh64{
+		Vertices: []float64{5e6, 2e6, 100, 5e6 + .002, 2e6, 100, 5e6, 2e6 + .002, 100},
+		Indices:  []uint32{0, 1, 2},
+	}
+	pa, _ := a.GetPoint(1)
+	pb, _ := b.GetPoint(1)
+	if pa[0] != pb[0] {
+		t.Fatal("Expected the meshes to be equal in float32")
+	}
+	hashA, err := ComputeHash(a)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hashB, _ := ComputeHash(b)
+	if hashA == hashB {
+		t.Error("Expected different hashes for meshes that differ below float32 precision")
+	}
+	if again, _ := ComputeHash(a); again != hashA {
+		t.Errorf("Expected the same hash for the same mesh, got %v and %v", hashA, again)
+	}
+}
@@ -2,9 +2,13 @@ package mesh
 
 import (
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"log"
 	"math"
+	"sort"
 )
 
 type MeshNeighborhood interface {
@@ -12,14 +16,33 @@ type MeshNeighborhood interface {
 	//If the input triangle index is out of range on the mesh, an error is returned.
 	GetTriangleNeighborsOfTriangle(tri uint32) ([]uint32, error)
 
-	//For future use:
-	//GetTriangleNeighborsOfVertex(tri uint32) ([]uint32,error)
+	//Returns the triangles that use the given vertex.  Around a manifold vertex they are
+	//in counterclockwise order, starting on the boundary when the vertex is on it;
+	//otherwise they are in increasing order.
+	//If the input vertex index is out of range on the mesh, an error is returned.
+	GetTriangleNeighborsOfVertex(vertex uint32) ([]uint32, error)
+
+	//Returns the vertices that share an edge with the given vertex (its one-ring), in
+	//the order of its triangles around a manifold vertex and in increasing order otherwise.
+	//If the input vertex index is out of range on the mesh, an error is returned.
+	GetVertexNeighborsOfVertex(vertex uint32) ([]uint32, error)
+
+	//Returns the non-manifold edges and vertices, and the edges with inconsistent
+	//winding, found while building the neighborhood.
+	GetDefects() []Defect
 }
 
-//myNeighborhood a precomputed table of neighboring triangles
+//myNeighborhood a precomputed table of neighboring triangles, and of the triangles
+//and vertices around each vertex in CSR layout: those of vertex v are
+//vertexTris[vertexTriStart[v]:vertexTriStart[v+1]] and vertexVerts[vertexVertStart[v]:vertexVertStart[v+1]]
 type myNeighborhood struct {
-	triNeighbors []uint32
-	m            Mesh
+	triNeighbors    []uint32
+	vertexTriStart  []uint32
+	vertexTris      []uint32
+	vertexVertStart []uint32
+	vertexVerts     []uint32
+	defects         []Defect
+	m               Mesh
 }
 
 //Note: this implementation assumes the triangles are internally labelled 0,...,n
@@ -49,6 +72,105 @@ func (neighb myNeighborhood) GetTriangleNeighborsOfTriangle(tri uint32) ([]uint3
 
 }
 
+//GetTriangleNeighborsOfVertex returns the triangles around the vertex.  The slice shares
+//its storage with the neighborhood and must not be modified.
+func (neighb myNeighborhood) GetTriangleNeighborsOfVertex(vertex uint32) ([]uint32, error) {
+	if vertex >= neighb.m.GetNumVertices() {
+		return []uint32{}, errors.New("GetTriangleNeighborsOfVertex:requested index is out of bounds")
+	}
+	start, end := neighb.vertexTriStart[vertex], neighb.vertexTriStart[vertex+1]
+	return neighb.vertexTris[start:end:end], nil
+}
+
+//GetVertexNeighborsOfVertex returns the one-ring of the vertex.  The slice shares
+//its storage with the neighborhood and must not be modified.
+func (neighb myNeighborhood) GetVertexNeighborsOfVertex(vertex uint32) ([]uint32, error) {
+	if vertex >= neighb.m.GetNumVertices() {
+		return []uint32{}, errors.New("GetVertexNeighborsOfVertex:requested index is out of bounds")
+	}
+	start, end := neighb.vertexVertStart[vertex], neighb.vertexVertStart[vertex+1]
+	return neighb.vertexVerts[start:end:end], nil
+}
+
+//GetDefects returns the defects of the mesh
+func (neighb myNeighborhood) GetDefects() []Defect {
+	return neighb.defects
+}
+
+//orderFan orders the triangles around vertex counterclockwise and returns them with the
+//one-ring of the vertex.  The triangle (vertex, x, y) is followed by the one that starts
+//with (vertex, y), so a single fan orders them all.  When they do not make a single fan,
+//the triangles keep their order, the one-ring is sorted, and the number of fans is
+//returned as well.
+func orderFan(m Mesh, vertex uint32, tris []uint32) ([]uint32, []uint32, int) {
+	if len(tris) == 0 {
+		return tris, []uint32{}, 0
+	}
+	xs := make([]uint32, len(tris))
+	ys := make([]uint32, len(tris))
+	byX := make(map[uint32]int, len(tris))
+	manifold := true
+	for i, t := range tris {
+		vertices, _ := m.GetVertices(t)
+		c := 0
+		for vertices[c] != vertex {
+			c++
+		}
+		xs[i], ys[i] = vertices[(c+1)%3], vertices[(c+2)%3]
+		if _, ok := byX[xs[i]]; ok || xs[i] == vertex || ys[i] == vertex {
+			manifold = false
+		}
+		byX[xs[i]] = i
+	}
+
+	// a fan on the boundary starts with the triangle whose x is no other triangle's y
+	first, numStarts := 0, 0
+	if manifold {
+		isY := make(map[uint32]bool, len(tris))
+		for _, y := range ys {
+			isY[y] = true
+		}
+		for i, x := range xs {
+			if !isY[x] {
+				first = i
+				numStarts++
+			}
+		}
+	}
+	if manifold && numStarts <= 1 {
+		ordered := make([]uint32, 0, len(tris))
+		ring := make([]uint32, 0, len(tris)+1)
+		for i := first; ; {
+			ordered = append(ordered, tris[i])
+			ring = append(ring, xs[i])
+			next, ok := byX[ys[i]]
+			if !ok || next == first || len(ordered) == len(tris) {
+				if numStarts == 1 {
+					ring = append(ring, ys[i])
+				}
+				break
+			}
+			i = next
+		}
+		if len(ordered) == len(tris) {
+			return ordered, ring, 1
+		}
+	}
+
+	ring := make([]uint32, 0, 2*len(tris))
+	seen := make(map[uint32]bool, 2*len(tris))
+	for i := range tris {
+		for _, w := range []uint32{xs[i], ys[i]} {
+			if w != vertex && !seen[w] {
+				seen[w] = true
+				ring = append(ring, w)
+			}
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i] < ring[j] })
+	return tris, ring, countFans(vertex, xs, ys)
+}
+
 //findIntersection Given two ORDERED sets a and b, this finds intersection
 //between them in O(n) time (where n = max(|a|,|b|)).
 func findIntersection(a []uint32, b []uint32) []uint32 {
@@ -101,6 +223,7 @@ func CreateNeighborhood(m Mesh) MeshNeighborhood {
 		}
 	}
 
+	defects := make([]Defect, 0)
 	for triangle := uint32(0); triangle < m.GetNumFacets(); triangle++ {
 		vertices2, _ := m.GetVertices(triangle)
 		//fmt.Printf("vertices: %d, %d, %d \n", vertices[0], vertices[1], vertices[2])
@@ -109,11 +232,14 @@ func CreateNeighborhood(m Mesh) MeshNeighborhood {
 		neighborhoodV1 := triNeighborsOfVertices[vertices2[1]].triNeighbors
 		neighborhoodV2 := triNeighborsOfVertices[vertices2[2]].triNeighbors
 
+		//a bar shared by more than two triangles keeps the last of them as the neighbor,
+		//and is reported as a defect
 		trisBar0 := findIntersection(neighborhoodV0, neighborhoodV1)
-		//if(len(trisBar0) > 2) //TODO: handle non-manifold bars
-
 		trisBar1 := findIntersection(neighborhoodV1, neighborhoodV2)
 		trisBar2 := findIntersection(neighborhoodV2, neighborhoodV0)
+		defects = checkBar(m, triangle, vertices2[0], vertices2[1], trisBar0, defects)
+		defects = checkBar(m, triangle, vertices2[1], vertices2[2], trisBar1, defects)
+		defects = checkBar(m, triangle, vertices2[2], vertices2[0], trisBar2, defects)
 
 		for neighb := 0; neighb < len(trisBar0); neighb++ {
 			if trisBar0[neighb] != triangle {
@@ -133,7 +259,25 @@ func CreateNeighborhood(m Mesh) MeshNeighborhood {
 			}
 		}
 	}
-	return myNeighborhood{triNeighbors: neighbArray, m: m}
+
+	//flatten the triangles around each vertex, in fan order, with the one-rings
+	numVertices := m.GetNumVertices()
+	vertexTriStart := make([]uint32, numVertices+1)
+	vertexTris := make([]uint32, 0, 3*m.GetNumFacets())
+	vertexVertStart := make([]uint32, numVertices+1)
+	vertexVerts := make([]uint32, 0, 3*m.GetNumFacets())
+	for vertex := uint32(0); vertex < numVertices; vertex++ {
+		tris, ring, numFans := orderFan(m, vertex, triNeighborsOfVertices[vertex].triNeighbors)
+		if numFans > 1 {
+			defects = append(defects, Defect{Kind: NonManifoldVertex, Vertices: []uint32{vertex}, Triangles: tris})
+		}
+		vertexTris = append(vertexTris, tris...)
+		vertexVerts = append(vertexVerts, ring...)
+		vertexTriStart[vertex+1] = uint32(len(vertexTris))
+		vertexVertStart[vertex+1] = uint32(len(vertexVerts))
+	}
+	return myNeighborhood{triNeighbors: neighbArray, vertexTriStart: vertexTriStart, vertexTris: vertexTris,
+		vertexVertStart: vertexVertStart, vertexVerts: vertexVerts, defects: defects, m: m}
 }
 
 // SerializeIndexedMesh takes a mesh, computes all points(Indices + Vertices)
@@ -158,71 +302,88 @@ func SerializeIndexedMesh(m Mesh) []float32 {
 	return returnVal
 }
 
+//ComputeHash returns a hex encoded SHA-256 digest of the triangles and points of a Mesh.
+//Two meshes have the same hash when they have the same vertices in the same order
+//and the same triangles in the same order, so it can be used as a cache key.
+//The points of a Mesh64 are hashed in double precision, so meshes that only differ
+//below float32 precision, e.g. far from the origin, get different hashes.
+func ComputeHash(m Mesh) (string, error) {
+	h := sha256.New()
+	word := make([]byte, 4)
+	binary.LittleEndian.PutUint32(word, m.GetNumVertices())
+	h.Write(word)
+	binary.LittleEndian.PutUint32(word, m.GetNumFacets())
+	h.Write(word)
+
+	m64, is64 := m.(Mesh64)
+	word64 := make([]byte, 8)
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		if is64 {
+			point, err := m64.GetPoint64(v)
+			if err != nil {
+				return "", err
+			}
+			for coord := 0; coord < 3; coord++ {
+				binary.LittleEndian.PutUint64(word64, math.Float64bits(point[coord]))
+				h.Write(word64)
+			}
+			continue
+		}
+		point, err := m.GetPoint(v)
+		if err != nil {
+			return "", err
+		}
+		for coord := 0; coord < 3; coord++ {
+			binary.LittleEndian.PutUint32(word, math.Float32bits(point[coord]))
+			h.Write(word)
+		}
+	}
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i < 3; i++ {
+			binary.LittleEndian.PutUint32(word, vertices[i])
+			h.Write(word)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 //ComputeArea computes the area of a triangle in a Mesh
 func ComputeArea(m Mesh, triIndex uint32) float32 {
-	vertices, _ := m.GetVertices(triIndex)
-
-	p1, _ := m.GetPoint(vertices[0])
-	p2, _ := m.GetPoint(vertices[1])
-	p3, _ := m.GetPoint(vertices[2])
-
-	return ComputeTriangleArea(p1, p2, p3)
+	return ComputeAreaVec3(m, triIndex)
 }
 
 //ComputeTriangleArea computes the area of a triangle defined by 3 3D points.
-//TODO: consider adding a ComputeAreaAndNormal func.  The implementation
-//of each of them uses a cross product calculation, which ought to be recycled for performance reasons.
 func ComputeTriangleArea(p1 []float32, p2 []float32, p3 []float32) float32 {
-	//half the magnitude of the cross product
-	u := []float32{p2[0] - p1[0], p2[1] - p1[1], p2[2] - p1[2]}
-	v := []float32{p3[0] - p1[0], p3[1] - p1[1], p3[2] - p1[2]}
-	if auxmath.Parallel(u, v, 1e-6) {
-		return 0
-	}
-	cross := auxmath.Cross(u, v)
-	return .5 * auxmath.Magnitude(cross)
+	return ComputeTriangleAreaVec3(toVec3(p1), toVec3(p2), toVec3(p3))
 }
 
 //ComputeNormal computes the normal of a triangle in a Mesh
 func ComputeNormal(m Mesh, triIndex uint32) ([]float32, error) {
-	vertices, _ := m.GetVertices(triIndex)
-
-	p1, _ := m.GetPoint(vertices[0])
-	p2, _ := m.GetPoint(vertices[1])
-	p3, _ := m.GetPoint(vertices[2])
-
-	return ComputeTriangleNormal(p1, p2, p3)
+	normal, err := ComputeNormalVec3(m, triIndex)
+	return normal.Slice(), err
 }
 
 //ComputeTriangleNormal computes the normal of a triangle defined by 3 3D points
 func ComputeTriangleNormal(p1 []float32, p2 []float32, p3 []float32) ([]float32, error) {
-	u := []float32{p2[0] - p1[0], p2[1] - p1[1], p2[2] - p1[2]}
-	v := []float32{p3[0] - p1[0], p3[1] - p1[1], p3[2] - p1[2]}
-	if auxmath.Parallel(u, v, 1e-6) {
-		return []float32{0, 0, 0}, errors.New("degenerate triangle detected")
+	normal, err := ComputeTriangleNormalVec3(toVec3(p1), toVec3(p2), toVec3(p3))
+	return normal.Slice(), err
+}
 
-	}
-	return auxmath.Normalize(auxmath.Cross(u, v)), nil
+//toVec3 converts the first 3 floats of a point
+func toVec3(p []float32) auxmath.Vec3 {
+	return auxmath.Vec3{p[0], p[1], p[2]}
 }
 
 // Compute the geometric center of a triangle given an indexed mesh and the
 // number of the triangle in the mesh indexed from 0
 func ComputeCentroid(m Mesh, triangle uint32) []float32 {
-
-	vertices, err := m.GetVertices(triangle)
+	center, err := ComputeCentroidVec3(m, triangle)
 	if err != nil {
-		log.Fatalf("Failed getting Vertices: %s", err)
-	}
-	center := make([]float32, 3, 3) //assumes this is initialized as {0,0,0}
-
-	for i := 0; i < 3; i++ {
-		vertexCoords, err := m.GetPoint(vertices[i])
-		if err != nil {
-			log.Fatalf("Failed to get a point: %s", err)
-		}
-		for coord := 0; coord < 3; coord++ {
-			center[coord] = center[coord] + vertexCoords[coord]/3.0
-		}
+		log.Fatalf("Failed getting the centroid: %s", err)
 	}
-	return center
+	return center.Slice()
 }
@@ -252,3 +252,82 @@ func TestComputeCentroid(t *testing.T) {
 	}
 	//retVal = ComputeCentroid(testMesh, 1)
 }
+
+func TestComputeHash(t *testing.T) {
+	theseVertices := []float32{0, 0, 0, 0, 1, 0, 1, 1, 0}
+	theseTriangles := []uint32{0, 1, 2}
+	testMesh := cloudmesh.IndexedMesh{Indices: theseTriangles, Vertices: theseVertices}
+	hash1, err := ComputeHash(testMesh)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hash2, _ := ComputeHash(testMesh)
+	if hash1 != hash2 {
+		t.Errorf("Expected the same hash for the same mesh, got %v and %v", hash1, hash2)
+	}
+
+	flipped := cloudmesh.IndexedMesh{Indices: []uint32{0, 2, 1}, Vertices: theseVertices}
+	hash3, _ := ComputeHash(flipped)
+	if hash1 == hash3 {
+		t.Error("Expected a different hash for a mesh with different triangles")
+	}
+}
+
+func TestGetNeighborsOfVertex(t *testing.T) {
+	// the top of the octahedron is surrounded by triangles 0 to 3
+	octahedron := createOctahedronMesh()
+	neighborhood := CreateNeighborhood(octahedron)
+	tris, err := neighborhood.GetTriangleNeighborsOfVertex(0)
+	if err != nil {
+		t.Fatalf("Error getting the triangles of vertex 0: %v", err)
+	}
+	ring, _ := neighborhood.GetVertexNeighborsOfVertex(0)
+	if fmt.Sprint(tris) != "[0 1 2 3]" || fmt.Sprint(ring) != "[1 2 3 4]" {
+		t.Errorf("Expected the triangles [0 1 2 3] and the vertices [1 2 3 4], got %v and %v", tris, ring)
+	}
+	for v := uint32(0); v < 6; v++ {
+		tris, _ := neighborhood.GetTriangleNeighborsOfVertex(v)
+		ring, _ := neighborhood.GetVertexNeighborsOfVertex(v)
+		if len(tris) != 4 || len(ring) != 4 {
+			t.Errorf("Expected 4 triangles and 4 vertices around vertex %v, got %v and %v", v, tris, ring)
+		}
+	}
+
+	// on a grid: an inner vertex, and a boundary vertex whose fan starts on the boundary
+	grid := createBumpyGrid(3)
+	neighborhood = CreateNeighborhood(grid)
+	ring, _ = neighborhood.GetVertexNeighborsOfVertex(5)
+	tris, _ = neighborhood.GetTriangleNeighborsOfVertex(5)
+	if len(ring) != 6 || len(tris) != 6 {
+		t.Fatalf("Expected 6 triangles and 6 vertices around vertex 5, got %v and %v", tris, ring)
+	}
+	// consecutive vertices of the one-ring make a triangle with the vertex
+	for i := range ring {
+		vertices, _ := grid.GetVertices(tris[i])
+		if !containsVertex(vertices, 5) || !containsVertex(vertices, ring[i]) || !containsVertex(vertices, ring[(i+1)%6]) {
+			t.Errorf("Expected triangle %v to use 5, %v and %v, got %v", tris[i], ring[i], ring[(i+1)%6], vertices)
+		}
+	}
+	tris, _ = neighborhood.GetTriangleNeighborsOfVertex(1)
+	ring, _ = neighborhood.GetVertexNeighborsOfVertex(1)
+	if fmt.Sprint(tris) != "[2 3 0]" || fmt.Sprint(ring) != "[2 6 5 0]" {
+		t.Errorf("Expected the triangles [2 3 0] and the vertices [2 6 5 0], got %v and %v", tris, ring)
+	}
+
+	// two triangles touching at a vertex are not a fan
+	bowtie := myIndexedMesh{Indices: []uint32{0, 3, 4, 0, 1, 2},
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, -1, 0, 0, -1, -1, 0}}
+	neighborhood = CreateNeighborhood(bowtie)
+	tris, _ = neighborhood.GetTriangleNeighborsOfVertex(0)
+	ring, _ = neighborhood.GetVertexNeighborsOfVertex(0)
+	if fmt.Sprint(tris) != "[0 1]" || fmt.Sprint(ring) != "[1 2 3 4]" {
+		t.Errorf("Expected the triangles [0 1] and the sorted vertices [1 2 3 4], got %v and %v", tris, ring)
+	}
+
+	if _, err := neighborhood.GetTriangleNeighborsOfVertex(5); err == nil {
+		t.Errorf("Expected an error for a vertex out of bounds")
+	}
+	if _, err := neighborhood.GetVertexNeighborsOfVertex(5); err == nil {
+		t.Errorf("Expected an error for a vertex out of bounds")
+	}
+}
@@ -0,0 +1,51 @@
+package mesh
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+)
+
+// the indexed mesh can be edited through the interface
+var _ MutableMesh = &cloudmesh.IndexedMesh{}
+
+// buildFan adds a fan of n triangles around a new vertex at the origin
+func buildFan(m MutableMesh, n int) {
+	m.Reserve(m.GetNumFacets()+uint32(n), m.GetNumVertices()+uint32(n)+1)
+	center := m.AddVertex(0, 0, 0)
+	first := m.GetNumVertices()
+	for i := 0; i < n; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		m.AddVertex(float32(math.Cos(angle)), float32(math.Sin(angle)), 0)
+	}
+	for i := 0; i < n; i++ {
+		m.AddTriangle(center, first+uint32(i), first+uint32(i+1)%uint32(n))
+	}
+}
+
+func TestMutableMesh(t *testing.T) {
+	m := cloudmesh.NewMesh()
+	buildFan(m, 6)
+	if m.GetNumFacets() != 6 || m.GetNumVertices() != 7 {
+		t.Fatalf("Expected a fan of 6 triangles and 7 vertices, got %d and %d", m.GetNumFacets(), m.GetNumVertices())
+	}
+	// lift the center, and close the fan with the two triangles it loses
+	var mutable MutableMesh = m
+	if err := mutable.SetPoint(0, []float32{0, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	newIndex, err := mutable.RemoveTriangles([]uint32{0, 5})
+	if err != nil || newIndex[1] != 0 || newIndex[0] != math.MaxUint32 {
+		t.Fatalf("Expected the triangles to be shifted down, got %v: %v", newIndex, err)
+	}
+	if err := mutable.SetVertices(0, []uint32{0, 1, 3}); err != nil {
+		t.Fatal(err)
+	}
+	for tri := uint32(0); tri < mutable.GetNumFacets(); tri++ {
+		normal, err := ComputeNormal(mutable, tri)
+		if err != nil || normal[2] <= 0 {
+			t.Errorf("Expected triangle %d to face up, got %v: %v", tri, normal, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package mesh
+
+import (
+	"errors"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+)
+
+// Vec3Mesh is a mesh that returns its triangles and points by value.  The Vec3
+// helpers below use it when a mesh implements it (cloudmesh.IndexedMesh does), and
+// fall back on GetVertices and GetPoint, which allocate, otherwise.
+type Vec3Mesh interface {
+	GetTriangle(facet uint32) ([3]uint32, error)
+	GetPointVec3(vertex uint32) (auxmath.Vec3, error)
+}
+
+// GetTriangle returns the vertices of a triangle of the mesh
+func GetTriangle(m Mesh, facet uint32) ([3]uint32, error) {
+	if vm, ok := m.(Vec3Mesh); ok {
+		return vm.GetTriangle(facet)
+	}
+	vertices, err := m.GetVertices(facet)
+	if err != nil {
+		return [3]uint32{}, err
+	}
+	return [3]uint32{vertices[0], vertices[1], vertices[2]}, nil
+}
+
+// GetPointVec3 returns the position of a vertex of the mesh
+func GetPointVec3(m Mesh, vertex uint32) (auxmath.Vec3, error) {
+	if vm, ok := m.(Vec3Mesh); ok {
+		return vm.GetPointVec3(vertex)
+	}
+	p, err := m.GetPoint(vertex)
+	if err != nil {
+		return auxmath.Vec3{}, err
+	}
+	return auxmath.ToVec3(p)
+}
+
+// GetTrianglePoints returns the positions of the corners of a triangle of the mesh
+func GetTrianglePoints(m Mesh, facet uint32) ([3]auxmath.Vec3, error) {
+	var points [3]auxmath.Vec3
+	vertices, err := GetTriangle(m, facet)
+	if err != nil {
+		return points, err
+	}
+	for i, v := range vertices {
+		if points[i], err = GetPointVec3(m, v); err != nil {
+			return points, err
+		}
+	}
+	return points, nil
+}
+
+// ComputeNormalVec3 computes the normal of a triangle in a Mesh (see ComputeNormal)
+func ComputeNormalVec3(m Mesh, triIndex uint32) (auxmath.Vec3, error) {
+	p, err := GetTrianglePoints(m, triIndex)
+	if err != nil {
+		return auxmath.Vec3{}, err
+	}
+	return ComputeTriangleNormalVec3(p[0], p[1], p[2])
+}
+
+// ComputeTriangleNormalVec3 computes the normal of a triangle defined by 3 3D points,
+// or returns an error when the triangle is degenerate
+func ComputeTriangleNormalVec3(p1 auxmath.Vec3, p2 auxmath.Vec3, p3 auxmath.Vec3) (auxmath.Vec3, error) {
+	u, v := p2.Sub(p1), p3.Sub(p1)
+	if u.Parallel(v, 1e-6) {
+		return auxmath.Vec3{}, errors.New("degenerate triangle detected")
+	}
+	return u.Cross(v).Normalize(), nil
+}
+
+// ComputeAreaVec3 computes the area of a triangle in a Mesh (see ComputeArea)
+func ComputeAreaVec3(m Mesh, triIndex uint32) float32 {
+	p, _ := GetTrianglePoints(m, triIndex)
+	return ComputeTriangleAreaVec3(p[0], p[1], p[2])
+}
+
+// ComputeTriangleAreaVec3 computes the area of a triangle defined by 3 3D points
+func ComputeTriangleAreaVec3(p1 auxmath.Vec3, p2 auxmath.Vec3, p3 auxmath.Vec3) float32 {
+	u, v := p2.Sub(p1), p3.Sub(p1)
+	if u.Parallel(v, 1e-6) {
+		return 0
+	}
+	return .5 * u.Cross(v).Magnitude()
+}
+
+// ComputeCentroidVec3 computes the geometric center of a triangle in a Mesh
+func ComputeCentroidVec3(m Mesh, triangle uint32) (auxmath.Vec3, error) {
+	p, err := GetTrianglePoints(m, triangle)
+	if err != nil {
+		return auxmath.Vec3{}, err
+	}
+	var center auxmath.Vec3
+	for i := 0; i < 3; i++ {
+		for coord := 0; coord < 3; coord++ {
+			center[coord] = center[coord] + p[i][coord]/3.0
+		}
+	}
+	return center, nil
+}
@@ -0,0 +1,75 @@
+package mesh
+
+import (
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+)
+
+// sliceMesh hides the Vec3 accessors of an indexed mesh
+type sliceMesh struct {
+	cloudmesh.IndexedMesh
+}
+
+func (m sliceMesh) GetTriangle() {}
+
+func TestVec3Helpers(t *testing.T) {
+	m := cloudmesh.IndexedMesh{
+		Vertices: []float32{0, 0, 0, 2, 0, 0, 0, 2, 0, 1, 0, 0},
+		Indices:  []uint32{0, 1, 2, 0, 3, 1},
+	}
+	for _, mesh := range []Mesh{m, sliceMesh{m}} {
+		normal, err := ComputeNormalVec3(mesh, 0)
+		if err != nil || normal != (auxmath.Vec3{0, 0, 1}) {
+			t.Errorf("%T: expected the normal (0, 0, 1) and got %v: %v", mesh, normal, err)
+		}
+		if area := ComputeAreaVec3(mesh, 0); area != 2 {
+			t.Errorf("%T: expected an area of 2 and got %v", mesh, area)
+		}
+		if center, err := ComputeCentroidVec3(mesh, 0); err != nil || center != (auxmath.Vec3{2.0 / 3, 2.0 / 3, 0}) {
+			t.Errorf("%T: expected the centroid (2/3, 2/3, 0) and got %v: %v", mesh, center, err)
+		}
+		// the degenerate triangle has no normal nor area
+		if _, err := ComputeNormalVec3(mesh, 1); err == nil || ComputeAreaVec3(mesh, 1) != 0 {
+			t.Errorf("%T: expected triangle 1 to be degenerate", mesh)
+		}
+		if _, err := GetTrianglePoints(mesh, 2); err == nil {
+			t.Errorf("%T: expected an error as the triangle is out of bounds", mesh)
+		}
+		// the slice versions agree
+		slice, _ := ComputeNormal(mesh, 0)
+		if slice[0] != normal[0] || slice[1] != normal[1] || slice[2] != normal[2] {
+			t.Errorf("%T: expected ComputeNormal to agree, got %v", mesh, slice)
+		}
+	}
+}
+
+func TestVec3HelpersDoNotAllocate(t *testing.T) {
+	var m Mesh = cloudmesh.IndexedMesh{
+		Vertices: []float32{0, 0, 0, 2, 0, 0, 0, 2, 0},
+		Indices:  []uint32{0, 1, 2},
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		ComputeNormalVec3(m, 0)
+		ComputeAreaVec3(m, 0)
+		ComputeCentroidVec3(m, 0)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocation, got %v per run", allocs)
+	}
+}
+
+func BenchmarkComputeNormalVec3(b *testing.B) {
+	var m Mesh = cloudmesh.IndexedMesh{Vertices: []float32{0, 0, 0, 2, 0, 0, 0, 2, 0}, Indices: []uint32{0, 1, 2}}
+	for i := 0; i < b.N; i++ {
+		ComputeNormalVec3(m, 0)
+	}
+}
+
+func BenchmarkComputeNormal(b *testing.B) {
+	var m Mesh = cloudmesh.IndexedMesh{Vertices: []float32{0, 0, 0, 2, 0, 0, 0, 2, 0}, Indices: []uint32{0, 1, 2}}
+	for i := 0; i < b.N; i++ {
+		ComputeNormal(m, 0)
+	}
+}
@@ -0,0 +1,188 @@
+package pointcloud
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// CloudNeighborhood is the point cloud counterpart of mesh.MeshNeighborhood
+type CloudNeighborhood interface {
+	//Returns the neighbors of the given point, closest first.
+	//If the input point index is out of range, an error is returned.
+	GetNeighborsOfPoint(point uint32) ([]uint32, error)
+}
+
+// knnNeighborhood a precomputed table of the k nearest neighbors of every point
+type knnNeighborhood struct {
+	offsets   []uint32 // the neighbors of point i are neighbors[offsets[i]:offsets[i+1]]
+	neighbors []uint32
+}
+
+func (neighb knnNeighborhood) GetNeighborsOfPoint(point uint32) ([]uint32, error) {
+	if int(point)+1 >= len(neighb.offsets) {
+		return []uint32{}, errors.New("GetNeighborsOfPoint:requested index is out of bounds")
+	}
+	return neighb.neighbors[neighb.offsets[point]:neighb.offsets[point+1]], nil
+}
+
+// cellKey is the integer coordinates of a grid cell
+type cellKey [3]int32
+
+// pointGrid buckets points into a uniform grid so that neighbor searches only
+// look at the cells around a point instead of the whole cloud.
+type pointGrid struct {
+	min      [3]float32
+	cellSize float32
+	cells    map[cellKey][]uint32
+}
+
+func (g *pointGrid) key(p []float32) cellKey {
+	var k cellKey
+	for i := 0; i < 3; i++ {
+		k[i] = int32(math.Floor(float64((p[i] - g.min[i]) / g.cellSize)))
+	}
+	return k
+}
+
+// newPointGrid sizes the cells so that a cell holds about pointsPerCell points
+func newPointGrid(c PointCloud, pointsPerCell int) *pointGrid {
+	numPoints := c.GetNumPoints()
+	g := &pointGrid{cells: make(map[cellKey][]uint32)}
+	if numPoints == 0 {
+		g.cellSize = 1
+		return g
+	}
+	max := [3]float32{}
+	for i := 0; i < 3; i++ {
+		g.min[i], max[i] = c.Points[i], c.Points[i]
+	}
+	for p := uint32(0); p < numPoints; p++ {
+		for i := 0; i < 3; i++ {
+			v := c.Points[3*p+uint32(i)]
+			if v < g.min[i] {
+				g.min[i] = v
+			}
+			if v > max[i] {
+				max[i] = v
+			}
+		}
+	}
+	// scanned points lie on surfaces, so size the cells as if the points covered the
+	// bounding box faces rather than its volume
+	diagonal := float32(0)
+	extent := [3]float32{}
+	for i := 0; i < 3; i++ {
+		extent[i] = max[i] - g.min[i]
+		diagonal += extent[i] * extent[i]
+	}
+	area := extent[0]*extent[1] + extent[1]*extent[2] + extent[2]*extent[0]
+	g.cellSize = float32(math.Sqrt(float64(area) * float64(pointsPerCell) / float64(numPoints)))
+	if g.cellSize <= 0 || math.IsNaN(float64(g.cellSize)) {
+		g.cellSize = float32(math.Sqrt(float64(diagonal))) / float32(numPoints)
+	}
+	if g.cellSize <= 0 {
+		g.cellSize = 1
+	}
+	for p := uint32(0); p < numPoints; p++ {
+		k := g.key(c.Points[3*p : 3*p+3])
+		g.cells[k] = append(g.cells[k], p)
+	}
+	return g
+}
+
+type candidate struct {
+	point  uint32
+	distSq float32
+}
+
+// nearest returns the k points closest to the given point, other than itself.
+// It searches rings of cells of growing radius until the k-th closest point found
+// is closer than anything the next ring could hold.  Once the rings span more cells
+// than the cloud has points, e.g., around an outlier far from the rest, it scans
+// the whole cloud instead.
+func (g *pointGrid) nearest(c PointCloud, point uint32, k int) []uint32 {
+	p := c.Points[3*point : 3*point+3]
+	center := g.key(p)
+	found := make([]candidate, 0, 2*k)
+	numPoints := int(c.GetNumPoints())
+
+	for ring := int32(0); ; ring++ {
+		if side := int64(2*ring + 1); side*side*side > int64(numPoints) {
+			found = found[:0]
+			for q := uint32(0); q < uint32(numPoints); q++ {
+				if q != point {
+					found = append(found, candidate{point: q, distSq: distSq(p, c.Points[3*q:3*q+3])})
+				}
+			}
+			break
+		}
+		for dx := -ring; dx <= ring; dx++ {
+			for dy := -ring; dy <= ring; dy++ {
+				for dz := -ring; dz <= ring; dz++ {
+					// only the shell of the ring; the inside was searched already
+					if abs32(dx) != ring && abs32(dy) != ring && abs32(dz) != ring {
+						continue
+					}
+					cell := g.cells[cellKey{center[0] + dx, center[1] + dy, center[2] + dz}]
+					for _, q := range cell {
+						if q == point {
+							continue
+						}
+						found = append(found, candidate{point: q, distSq: distSq(p, c.Points[3*q:3*q+3])})
+					}
+				}
+			}
+		}
+		if len(found) >= numPoints-1 {
+			break
+		}
+		if len(found) >= k {
+			sort.Slice(found, func(i, j int) bool { return found[i].distSq < found[j].distSq })
+			// any point outside of the searched rings is at least ring*cellSize away
+			reach := float32(ring) * g.cellSize
+			if found[k-1].distSq <= reach*reach {
+				break
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].distSq < found[j].distSq })
+	if len(found) > k {
+		found = found[:k]
+	}
+	retVal := make([]uint32, len(found))
+	for i := range found {
+		retVal[i] = found[i].point
+	}
+	return retVal
+}
+
+func abs32(x int32) int32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func distSq(a []float32, b []float32) float32 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// CreateKNNNeighborhood returns the k nearest neighbors of every point of the cloud.
+// The neighbors are found with a uniform grid, so the algorithm is about O(k*n*log(k)).
+func CreateKNNNeighborhood(c PointCloud, k int) CloudNeighborhood {
+	numPoints := c.GetNumPoints()
+	if k < 1 {
+		k = 1
+	}
+	g := newPointGrid(c, k)
+
+	offsets := make([]uint32, numPoints+1)
+	neighbors := make([]uint32, 0, int(numPoints)*k)
+	for p := uint32(0); p < numPoints; p++ {
+		neighbors = append(neighbors, g.nearest(c, p, k)...)
+		offsets[p+1] = uint32(len(neighbors))
+	}
+	return knnNeighborhood{offsets: offsets, neighbors: neighbors}
+}
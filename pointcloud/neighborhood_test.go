@@ -0,0 +1,62 @@
+package pointcloud
+
+import (
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func TestKNNNeighborhood(t *testing.T) {
+	c := &PointCloud{Points: shape.Grid(9, 0).Vertices}
+	neighborhood := CreateKNNNeighborhood(*c, 4)
+
+	// an interior point of the grid has exactly 4 neighbors at distance 1
+	center := uint32(5*10 + 5)
+	neighbors, err := neighborhood.GetNeighborsOfPoint(center)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(neighbors) != 4 {
+		t.Fatalf("Expected 4 neighbors and got %v", len(neighbors))
+	}
+	expected := map[uint32]bool{center - 1: true, center + 1: true, center - 10: true, center + 10: true}
+	for _, n := range neighbors {
+		if !expected[n] {
+			t.Errorf("Did not expect %v to be a neighbor of %v", n, center)
+		}
+	}
+
+	// a corner has 2 neighbors at distance 1 and then the diagonal one
+	corner, _ := neighborhood.GetNeighborsOfPoint(0)
+	if corner[2] != 11 {
+		t.Errorf("Expected the third neighbor of the corner to be 11 and got %v", corner[2])
+	}
+
+	if _, err := neighborhood.GetNeighborsOfPoint(100); err == nil {
+		t.Error("Expected an error as the point requested is out of bounds.")
+	}
+}
+
+func TestKNNNeighborhoodSmallCloud(t *testing.T) {
+	c := &PointCloud{Points: shape.Grid(1, 0).Vertices}
+	neighborhood := CreateKNNNeighborhood(*c, 8)
+	neighbors, _ := neighborhood.GetNeighborsOfPoint(0)
+	if len(neighbors) != 3 {
+		t.Errorf("Expected every other point to be a neighbor, got %v", neighbors)
+	}
+}
+
+// An outlier high above the middle of a grid must not make the search grow rings of
+// cells, sized for the grid, all the way down to it.
+func TestKNNNeighborhoodOutlier(t *testing.T) {
+	c := &PointCloud{Points: shape.Grid(59, 0).Vertices}
+	outlier := c.AddPoint(30, 30, 2000)
+	neighborhood := CreateKNNNeighborhood(*c, 3)
+	neighbors, err := neighborhood.GetNeighborsOfPoint(outlier)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(neighbors) != 3 || neighbors[0] != 30*60+30 {
+		t.Errorf("Expected the point of the grid below the outlier first, got %v", neighbors)
+	}
+}
@@ -0,0 +1,111 @@
+package pointcloud
+
+import (
+	"errors"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+)
+
+// FitPlane fits a plane to a set of points (x, y, z of every point) with principal
+// component analysis.  It returns the centroid of the points, the unit normal of
+// the plane (the direction of least variance) and the variance along that normal,
+// which is the mean squared distance of the points to the plane.
+func FitPlane(points []float32) (center []float32, normal []float32, variance float32, err error) {
+	numPoints := len(points) / 3
+	if numPoints < 3 {
+		return []float32{0, 0, 0}, []float32{0, 0, 0}, 0, errors.New("FitPlane: need at least 3 points")
+	}
+	var g [3]float64
+	for p := 0; p < numPoints; p++ {
+		for i := 0; i < 3; i++ {
+			g[i] += float64(points[3*p+i])
+		}
+	}
+	for i := 0; i < 3; i++ {
+		g[i] /= float64(numPoints)
+	}
+	var cov [3][3]float64
+	for p := 0; p < numPoints; p++ {
+		d := [3]float64{float64(points[3*p]) - g[0], float64(points[3*p+1]) - g[1], float64(points[3*p+2]) - g[2]}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				cov[i][j] += d[i] * d[j] / float64(numPoints)
+			}
+		}
+	}
+	values, vectors := auxmath.SymmetricEigen3(cov)
+	if values[1] <= 1e-12*(values[0]+values[1]+values[2]) {
+		// the points are on a line (or are all the same point), so any plane through them fits
+		return []float32{float32(g[0]), float32(g[1]), float32(g[2])}, []float32{0, 0, 0}, 0,
+			errors.New("FitPlane: the points are collinear")
+	}
+	center = []float32{float32(g[0]), float32(g[1]), float32(g[2])}
+	normal = []float32{float32(vectors[0][0]), float32(vectors[0][1]), float32(vectors[0][2])}
+	return center, normal, float32(values[0]), nil
+}
+
+// EstimateNormals sets the normal of every point of the cloud to the normal of the
+// plane fitted to the point and its neighbors.  The sign of a fitted normal is
+// arbitrary, so the normals are then oriented consistently by walking the
+// neighborhood graph, starting with a normal pointing away from the cloud centroid.
+func EstimateNormals(c *PointCloud, neighborhood CloudNeighborhood) error {
+	numPoints := c.GetNumPoints()
+	c.Normals = make([]float32, 3*numPoints)
+	centroid := make([]float32, 3)
+	for p := uint32(0); p < numPoints; p++ {
+		for i := 0; i < 3; i++ {
+			centroid[i] += c.Points[3*p+uint32(i)] / float32(numPoints)
+		}
+	}
+
+	patch := make([]float32, 0, 3*16)
+	for p := uint32(0); p < numPoints; p++ {
+		neighbors, err := neighborhood.GetNeighborsOfPoint(p)
+		if err != nil {
+			return err
+		}
+		patch = append(patch[:0], c.Points[3*p:3*p+3]...)
+		for _, n := range neighbors {
+			patch = append(patch, c.Points[3*n:3*n+3]...)
+		}
+		// a point without a plane keeps a zero normal
+		_, normal, _, _ := FitPlane(patch)
+		copy(c.Normals[3*p:3*p+3], normal)
+	}
+
+	oriented := make([]bool, numPoints)
+	for start := uint32(0); start < numPoints; start++ {
+		if oriented[start] {
+			continue
+		}
+		outward, _ := auxmath.Subtract(c.Points[3*start:3*start+3], centroid)
+		if dot, _ := auxmath.Dot(outward, c.Normals[3*start:3*start+3]); dot < 0 {
+			flipNormal(c, start)
+		}
+		oriented[start] = true
+		queue := []uint32{start}
+		for len(queue) > 0 {
+			//pop
+			curr := queue[0]
+			queue = queue[1:]
+			neighbors, _ := neighborhood.GetNeighborsOfPoint(curr)
+			for _, n := range neighbors {
+				if oriented[n] {
+					continue
+				}
+				if dot, _ := auxmath.Dot(c.Normals[3*curr:3*curr+3], c.Normals[3*n:3*n+3]); dot < 0 {
+					flipNormal(c, n)
+				}
+				oriented[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return nil
+}
+
+func flipNormal(c *PointCloud, point uint32) {
+	for i := uint32(0); i < 3; i++ {
+		c.Normals[3*point+i] = -c.Normals[3*point+i]
+	}
+}
@@ -0,0 +1,61 @@
+package pointcloud
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func TestFitPlane(t *testing.T) {
+	// points in the plane x + y + z = 3
+	points := []float32{
+		3, 0, 0,
+		0, 3, 0,
+		0, 0, 3,
+		1, 1, 1,
+		2, 1, 0}
+	center, normal, variance, err := FitPlane(points)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := float32(1 / math.Sqrt(3))
+	for i := 0; i < 3; i++ {
+		if math.Abs(math.Abs(float64(normal[i]))-float64(expected)) > 1e-5 {
+			t.Errorf("Expected the normal to be +-(1,1,1)/sqrt(3) and got %v", normal)
+			break
+		}
+	}
+	if variance > 1e-6 {
+		t.Errorf("Expected no variance along the normal and got %v", variance)
+	}
+	if math.Abs(float64(center[0]+center[1]+center[2]-3)) > 1e-5 {
+		t.Errorf("Expected the center to be in the plane and got %v", center)
+	}
+
+	if _, _, _, err := FitPlane([]float32{0, 0, 0, 1, 1, 1, 2, 2, 2}); err == nil {
+		t.Error("Expected an error for collinear points")
+	}
+}
+
+func TestEstimateNormals(t *testing.T) {
+	c := &PointCloud{Points: shape.Grid(9, 0).Vertices}
+	// lift the grid so that the cloud centroid is below it
+	for p := uint32(0); p < c.GetNumPoints(); p++ {
+		c.Points[3*p+2] = 5
+	}
+	c.AddPoint(4.5, 4.5, -100)
+	err := EstimateNormals(c, CreateKNNNeighborhood(*c, 8))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !c.HasNormals() {
+		t.Fatal("Expected a normal for every point")
+	}
+	for p := uint32(0); p < 100; p++ {
+		normal, _ := c.GetNormal(p)
+		if normal[2] < .99 {
+			t.Errorf("Point %v: expected the normal (0,0,1) and got %v", p, normal)
+		}
+	}
+}
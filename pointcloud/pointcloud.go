@@ -0,0 +1,57 @@
+// Package pointcloud implements a point cloud: a set of points in space
+// without any connectivity, as it comes out of a scanner.
+package pointcloud
+
+import (
+	"errors"
+)
+
+// A PointCloud holds the points, and optionally a normal for each point.
+type PointCloud struct {
+	Points  []float32 // x, y, z of every point
+	Normals []float32 // x, y, z of the normal of every point, empty until the normals are known
+}
+
+// NewPointCloud returns a pointer to an initialized point cloud
+func NewPointCloud() *PointCloud {
+	return &PointCloud{Points: make([]float32, 0), Normals: make([]float32, 0)}
+}
+
+// GetNumPoints returns the number of points in the point cloud
+func (c PointCloud) GetNumPoints() uint32 {
+	return uint32(len(c.Points) / 3)
+}
+
+// GetPoint returns the x, y, z of the given point
+func (c PointCloud) GetPoint(point uint32) ([]float32, error) {
+	if point >= c.GetNumPoints() {
+		return []float32{0, 0, 0}, errors.New("GetPoint:requested index is out of bounds")
+	}
+	return []float32{c.Points[3*point+0],
+		c.Points[3*point+1],
+		c.Points[3*point+2]}, nil
+}
+
+// HasNormals tells if there is a normal for every point
+func (c PointCloud) HasNormals() bool {
+	return len(c.Normals) == len(c.Points)
+}
+
+// GetNormal returns the normal of the given point
+func (c PointCloud) GetNormal(point uint32) ([]float32, error) {
+	if !c.HasNormals() {
+		return []float32{0, 0, 0}, errors.New("GetNormal:the point cloud has no normals")
+	}
+	if point >= c.GetNumPoints() {
+		return []float32{0, 0, 0}, errors.New("GetNormal:requested index is out of bounds")
+	}
+	return []float32{c.Normals[3*point+0],
+		c.Normals[3*point+1],
+		c.Normals[3*point+2]}, nil
+}
+
+// AddPoint appends a point to the point cloud and returns its index
+func (c *PointCloud) AddPoint(x float32, y float32, z float32) uint32 {
+	c.Points = append(c.Points, x, y, z)
+	return c.GetNumPoints() - 1
+}
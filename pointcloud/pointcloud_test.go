@@ -0,0 +1,27 @@
+package pointcloud
+
+import (
+	"testing"
+)
+
+func TestAddPoint(t *testing.T) {
+	c := NewPointCloud()
+	c.AddPoint(1, 2, 3)
+	index := c.AddPoint(4, 5, 6)
+	if index != 1 {
+		t.Errorf("Expected index 1 and got %v", index)
+	}
+	if c.GetNumPoints() != 2 {
+		t.Errorf("Expected 2 points and got %v", c.GetNumPoints())
+	}
+	point, err := c.GetPoint(1)
+	if err != nil || point[0] != 4 || point[1] != 5 || point[2] != 6 {
+		t.Errorf("Expected (4,5,6) and got %v, %v", point, err)
+	}
+	if _, err := c.GetPoint(2); err == nil {
+		t.Error("Expected an error as the point requested is out of bounds.")
+	}
+	if _, err := c.GetNormal(0); err == nil {
+		t.Error("Expected an error as the cloud has no normals.")
+	}
+}
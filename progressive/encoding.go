@@ -0,0 +1,227 @@
+package progressive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// EncodingVersion is the version of the binary format written by Encode
+const EncodingVersion = 1
+
+var magic = [4]byte{'C', 'M', 'P', 'M'}
+
+// Encode writes the base mesh followed by the vertex splits.  Counts and indices
+// are written as unsigned varints, positions as little endian float32.
+// The mesh is left at its current level.
+func (pm *ProgressiveMesh) Encode(w io.Writer) error {
+	level := pm.level
+	defer func() {
+		for pm.level > level && pm.Coarsen() {
+		}
+		for pm.level < level && pm.Refine() {
+		}
+	}()
+	for pm.Coarsen() {
+	}
+
+	bw := bufio.NewWriter(w)
+	e := encoder{w: bw}
+	e.bytes(magic[:])
+	e.uvarint(EncodingVersion)
+	e.uvarint(uint64(pm.baseVertices))
+	e.uvarint(uint64(pm.baseFacets))
+	e.uvarint(uint64(len(pm.splits)))
+	for _, f := range pm.vertices[:3*pm.baseVertices] {
+		e.float(f)
+	}
+	for _, i := range pm.indices[:3*pm.baseFacets] {
+		e.uvarint(uint64(i))
+	}
+	for i, s := range pm.splits {
+		first := pm.numFacets
+		pm.Refine()
+		e.uvarint(uint64(s.Vertex))
+		e.vector(s.Position)
+		e.vector(s.CoarsePosition)
+		e.vector(s.NewPosition)
+		e.uvarint(uint64(s.NumNewFacets))
+		for _, v := range pm.indices[3*first : 3*pm.numFacets] {
+			e.uvarint(uint64(v))
+		}
+		e.uvarint(uint64(len(s.MovedCorners)))
+		for _, c := range s.MovedCorners {
+			e.uvarint(uint64(c))
+		}
+		if e.err != nil {
+			return fmt.Errorf("progressive.Encode: split %d: %v", i, e.err)
+		}
+	}
+	if e.err != nil {
+		return fmt.Errorf("progressive.Encode: %v", e.err)
+	}
+	return bw.Flush()
+}
+
+// Decode reads a progressive mesh written by Encode.  The mesh returned is at its base level.
+// Decode reads r to the end, so that the counts of the header can be checked against the
+// size of the input before anything is allocated for them.
+func Decode(r io.Reader) (*ProgressiveMesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("progressive.Decode: %v", err)
+	}
+	d := decoder{r: bytes.NewReader(data)}
+	var header [4]byte
+	d.bytes(header[:])
+	if d.err != nil {
+		return nil, fmt.Errorf("progressive.Decode: %v", d.err)
+	}
+	if header != magic {
+		return nil, errors.New("progressive.Decode: not a progressive mesh")
+	}
+	if version := d.uvarint(); version != EncodingVersion {
+		return nil, fmt.Errorf("progressive.Decode: unsupported version %d", version)
+	}
+	baseVertices := d.count()
+	baseFacets := d.count()
+	numSplits := d.count()
+	// every vertex takes 3 floats, every triangle at least 3 bytes and every split
+	// at least a byte for each of its 3 varints on top of its 3 vectors
+	d.fits(uint64(baseVertices)*12 + uint64(baseFacets)*3 + uint64(numSplits)*(3+36))
+	if d.err != nil {
+		return nil, fmt.Errorf("progressive.Decode: %v", d.err)
+	}
+
+	pm := &ProgressiveMesh{
+		baseVertices: baseVertices,
+		baseFacets:   baseFacets,
+		numFacets:    baseFacets,
+		vertices:     make([]float32, 0),
+		indices:      make([]uint32, 0),
+		splits:       make([]VertexSplit, 0),
+	}
+	for i := uint32(0); i < 3*baseVertices && d.err == nil; i++ {
+		pm.vertices = append(pm.vertices, d.float())
+	}
+	for i := uint32(0); i < 3*baseFacets && d.err == nil; i++ {
+		pm.indices = append(pm.indices, d.index(baseVertices))
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("progressive.Decode: %v", d.err)
+	}
+	for i := uint32(0); i < numSplits; i++ {
+		numVertices := baseVertices + i
+		s := VertexSplit{Vertex: d.index(numVertices)}
+		s.Position = d.vector()
+		s.CoarsePosition = d.vector()
+		s.NewPosition = d.vector()
+		s.NumNewFacets = d.count()
+		d.fits(uint64(s.NumNewFacets) * 3)
+		for j := uint32(0); j < 3*s.NumNewFacets && d.err == nil; j++ {
+			pm.indices = append(pm.indices, d.index(numVertices+1))
+		}
+		numMoved := d.count()
+		d.fits(uint64(numMoved))
+		s.MovedCorners = make([]uint32, 0)
+		for j := uint32(0); j < numMoved && d.err == nil; j++ {
+			s.MovedCorners = append(s.MovedCorners, d.index(uint32(len(pm.indices))))
+		}
+		if d.err != nil {
+			return nil, fmt.Errorf("progressive.Decode: split %d: %v", i, d.err)
+		}
+		pm.splits = append(pm.splits, s)
+		// the position of the new vertex is set by Refine
+		pm.vertices = append(pm.vertices, 0, 0, 0)
+	}
+	return pm, nil
+}
+
+type encoder struct {
+	w   io.Writer
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) uvarint(x uint64) {
+	n := binary.PutUvarint(e.buf[:], x)
+	e.bytes(e.buf[:n])
+}
+
+func (e *encoder) float(f float32) {
+	binary.LittleEndian.PutUint32(e.buf[:4], math.Float32bits(f))
+	e.bytes(e.buf[:4])
+}
+
+func (e *encoder) vector(v [3]float32) {
+	for _, f := range v {
+		e.float(f)
+	}
+}
+
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *decoder) bytes(b []byte) {
+	if d.err == nil {
+		_, d.err = io.ReadFull(d.r, b)
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.err = err
+	}
+	return x
+}
+
+// fits checks that the rest of the input holds at least size bytes
+func (d *decoder) fits(size uint64) {
+	if size > uint64(d.r.Len()) && d.err == nil {
+		d.err = fmt.Errorf("%d bytes are needed but only %d are left", size, d.r.Len())
+	}
+}
+
+// count reads a count that must fit in a uint32
+func (d *decoder) count() uint32 {
+	x := d.uvarint()
+	if x > math.MaxUint32/3 && d.err == nil {
+		d.err = fmt.Errorf("count %d is too large", x)
+	}
+	return uint32(x)
+}
+
+// index reads an index that must be below limit
+func (d *decoder) index(limit uint32) uint32 {
+	x := d.uvarint()
+	if x >= uint64(limit) && d.err == nil {
+		d.err = fmt.Errorf("index %d is out of range", x)
+	}
+	return uint32(x)
+}
+
+func (d *decoder) float() float32 {
+	var b [4]byte
+	d.bytes(b[:])
+	return math.Float32frombits(binary.LittleEndian.Uint32(b[:]))
+}
+
+func (d *decoder) vector() [3]float32 {
+	return [3]float32{d.float(), d.float(), d.float()}
+}
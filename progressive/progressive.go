@@ -0,0 +1,248 @@
+// Package progressive implements progressive meshes (Hoppe): a coarse base mesh
+// followed by an ordered list of vertex splits that refine it back, one vertex at
+// a time, to the full resolution mesh.
+package progressive
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/qem"
+)
+
+// VertexSplit is the inverse of an edge collapse.  It adds one vertex, whose
+// index is the number of vertices before the split, and the triangles that
+// were removed by the collapse.
+type VertexSplit struct {
+	Vertex uint32 // the vertex that splits
+	// Position and CoarsePosition are the positions of Vertex after and before the split
+	Position       [3]float32
+	CoarsePosition [3]float32
+	NewPosition    [3]float32 // position of the new vertex
+	NumNewFacets   uint32     // number of triangles added by the split
+	// MovedCorners are the corners (3*triangle + 0, 1 or 2) that move from Vertex to the new vertex
+	MovedCorners []uint32
+}
+
+// ProgressiveMesh is a mesh that can be refined or coarsened one vertex split at a time.
+// The vertices and triangles are ordered so that the mesh at any level is made of the
+// first GetNumVertices vertices and the first GetNumFacets triangles, so a
+// ProgressiveMesh is itself a mesh.Mesh at its current level.
+type ProgressiveMesh struct {
+	// vertices and indices of the full resolution mesh.  The active part holds the
+	// current level; an inactive triangle holds its corners as of the split that adds it.
+	vertices     []float32
+	indices      []uint32
+	baseVertices uint32
+	baseFacets   uint32
+	splits       []VertexSplit
+	level        int
+	numFacets    uint32
+}
+
+// Build simplifies the mesh with the QEM simplifier down to baseFacets triangles
+// (or as far as it can go) and records the collapses as vertex splits.
+// The ProgressiveMesh returned is at its base level.
+func Build(m mesh.Mesh, baseFacets uint32) (*ProgressiveMesh, error) {
+	if baseFacets < 1 {
+		baseFacets = 1
+	}
+	collapses := make([]qem.Collapse, 0)
+	_, err := qem.Simplify(m, qem.Options{TargetFacets: baseFacets, OnCollapse: func(c qem.Collapse) {
+		collapses = append(collapses, c)
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("progressive.Build: %v", err)
+	}
+
+	numVertices := m.GetNumVertices()
+	numTris := m.GetNumFacets()
+	const unused = math.MaxUint32
+
+	// the triangles that survive make the base mesh, then come the triangles of each
+	// split, which are those of the collapses in reverse order
+	newTri := make([]uint32, numTris)
+	removedTri := make([]bool, numTris)
+	newVertex := make([]uint32, numVertices)
+	removedVertex := make([]bool, numVertices)
+	for i := range newTri {
+		newTri[i] = unused
+	}
+	for i := range newVertex {
+		newVertex[i] = unused
+	}
+	for _, c := range collapses {
+		removedVertex[c.Removed] = true
+		for _, t := range c.RemovedFacets {
+			removedTri[t] = true
+		}
+	}
+
+	pm := &ProgressiveMesh{splits: make([]VertexSplit, 0, len(collapses))}
+	triOrder := make([]uint32, 0, numTris)
+	for t := uint32(0); t < numTris; t++ {
+		vertices, _ := m.GetVertices(t)
+		if vertices[0] == vertices[1] || vertices[1] == vertices[2] || vertices[2] == vertices[0] {
+			continue // the simplifier ignores degenerate triangles
+		}
+		// a vertex that is used and never removed is in the base mesh
+		for _, v := range vertices {
+			if newVertex[v] == unused && !removedVertex[v] {
+				newVertex[v] = pm.baseVertices
+				pm.baseVertices++
+			}
+		}
+		if !removedTri[t] {
+			newTri[t] = uint32(len(triOrder))
+			triOrder = append(triOrder, t)
+		}
+	}
+	pm.baseFacets = uint32(len(triOrder))
+	nextVertex := pm.baseVertices
+	for i := len(collapses) - 1; i >= 0; i-- {
+		newVertex[collapses[i].Removed] = nextVertex
+		nextVertex++
+		for _, t := range collapses[i].RemovedFacets {
+			newTri[t] = uint32(len(triOrder))
+			triOrder = append(triOrder, t)
+		}
+	}
+
+	// start from the full resolution mesh and replay the collapses to get to the base mesh
+	pm.vertices = make([]float32, 3*nextVertex)
+	for v := uint32(0); v < numVertices; v++ {
+		if newVertex[v] == unused {
+			continue // not used by any triangle
+		}
+		p, err := m.GetPoint(v)
+		if err != nil {
+			return nil, fmt.Errorf("progressive.Build: %v", err)
+		}
+		copy(pm.vertices[3*newVertex[v]:], p)
+	}
+	pm.indices = make([]uint32, 3*len(triOrder))
+	for i, t := range triOrder {
+		vertices, _ := m.GetVertices(t)
+		for c := 0; c < 3; c++ {
+			pm.indices[3*i+c] = newVertex[vertices[c]]
+		}
+	}
+	pm.numFacets = uint32(len(triOrder))
+	pm.level = len(collapses)
+	splits := make([]VertexSplit, len(collapses))
+	for i, c := range collapses {
+		split := VertexSplit{
+			Vertex:         newVertex[c.Kept],
+			Position:       c.KeptPosition,
+			CoarsePosition: c.Position,
+			NewPosition:    c.RemovedPosition,
+			NumNewFacets:   uint32(len(c.RemovedFacets)),
+			MovedCorners:   make([]uint32, len(c.MovedCorners)),
+		}
+		for j, corner := range c.MovedCorners {
+			split.MovedCorners[j] = 3*newTri[corner/3] + corner%3
+		}
+		splits[len(collapses)-1-i] = split
+	}
+	pm.splits = splits
+	for pm.Coarsen() {
+	}
+	return pm, nil
+}
+
+// NumSplits returns the number of vertex splits between the base and the full resolution mesh
+func (pm *ProgressiveMesh) NumSplits() int {
+	return len(pm.splits)
+}
+
+// Level returns the number of vertex splits applied to the base mesh
+func (pm *ProgressiveMesh) Level() int {
+	return pm.level
+}
+
+// Split returns the i-th vertex split
+func (pm *ProgressiveMesh) Split(i int) VertexSplit {
+	return pm.splits[i]
+}
+
+// Refine applies the next vertex split.  It returns false at full resolution.
+func (pm *ProgressiveMesh) Refine() bool {
+	if pm.level >= len(pm.splits) {
+		return false
+	}
+	s := pm.splits[pm.level]
+	newVertex := pm.baseVertices + uint32(pm.level)
+	copy(pm.vertices[3*s.Vertex:3*s.Vertex+3], s.Position[:])
+	copy(pm.vertices[3*newVertex:3*newVertex+3], s.NewPosition[:])
+	for _, c := range s.MovedCorners {
+		pm.indices[c] = newVertex
+	}
+	pm.numFacets += s.NumNewFacets
+	pm.level++
+	return true
+}
+
+// Coarsen undoes the last vertex split.  It returns false at the base level.
+func (pm *ProgressiveMesh) Coarsen() bool {
+	if pm.level <= 0 {
+		return false
+	}
+	pm.level--
+	s := pm.splits[pm.level]
+	pm.numFacets -= s.NumNewFacets
+	for _, c := range s.MovedCorners {
+		pm.indices[c] = s.Vertex
+	}
+	copy(pm.vertices[3*s.Vertex:3*s.Vertex+3], s.CoarsePosition[:])
+	return true
+}
+
+// SetNumFacets refines or coarsens the mesh to the finest level with at most
+// numFacets triangles (or to the base level if it has more).  The cost is
+// proportional to the number of splits applied or undone.
+func (pm *ProgressiveMesh) SetNumFacets(numFacets uint32) {
+	for pm.numFacets > numFacets && pm.Coarsen() {
+	}
+	for pm.level < len(pm.splits) && pm.numFacets+pm.splits[pm.level].NumNewFacets <= numFacets {
+		pm.Refine()
+	}
+}
+
+// GetNumFacets returns the number of triangles at the current level
+func (pm *ProgressiveMesh) GetNumFacets() uint32 {
+	return pm.numFacets
+}
+
+// GetNumVertices returns the number of vertices at the current level
+func (pm *ProgressiveMesh) GetNumVertices() uint32 {
+	return pm.baseVertices + uint32(pm.level)
+}
+
+// GetVertices returns the vertices of a triangle at the current level
+func (pm *ProgressiveMesh) GetVertices(facet uint32) ([]uint32, error) {
+	if facet >= pm.numFacets {
+		return []uint32{0, 0, 0}, errors.New("GetVertices:requested index is out of bounds")
+	}
+	return []uint32{pm.indices[3*facet], pm.indices[3*facet+1], pm.indices[3*facet+2]}, nil
+}
+
+// GetPoint returns the position of a vertex at the current level
+func (pm *ProgressiveMesh) GetPoint(vertex uint32) ([]float32, error) {
+	if vertex >= pm.GetNumVertices() {
+		return []float32{0, 0, 0}, errors.New("GetPoint:requested index is out of bounds")
+	}
+	return []float32{pm.vertices[3*vertex], pm.vertices[3*vertex+1], pm.vertices[3*vertex+2]}, nil
+}
+
+// ToIndexedMesh returns a copy of the mesh at the current level
+func (pm *ProgressiveMesh) ToIndexedMesh() cloudmesh.IndexedMesh {
+	retVal := cloudmesh.IndexedMesh{
+		Indices:  make([]uint32, 3*pm.numFacets),
+		Vertices: make([]float32, 3*pm.GetNumVertices())}
+	copy(retVal.Indices, pm.indices)
+	copy(retVal.Vertices, pm.vertices)
+	return retVal
+}
@@ -0,0 +1,245 @@
+package progressive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// triangleKeys returns the triangles of a mesh as sorted strings of their corner
+// positions, rotated so that the comparison does not depend on the numbering
+func triangleKeys(t *testing.T, m cloudmesh.IndexedMesh) []string {
+	keys := make([]string, 0, m.GetNumFacets())
+	for f := uint32(0); f < m.GetNumFacets(); f++ {
+		vertices, _ := m.GetVertices(f)
+		corners := make([][3]float32, 3)
+		first := 0
+		for c, v := range vertices {
+			p, err := m.GetPoint(v)
+			if err != nil {
+				t.Fatalf("Error getting point: %v", err)
+			}
+			copy(corners[c][:], p)
+			if less(corners[c], corners[first]) {
+				first = c
+			}
+		}
+		key := ""
+		for c := 0; c < 3; c++ {
+			p := corners[(first+c)%3]
+			key += string(rune(0)) + formatPoint(p)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func less(a, b [3]float32) bool {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func formatPoint(p [3]float32) string {
+	var buf bytes.Buffer
+	for _, f := range p {
+		bits := math.Float32bits(f)
+		buf.WriteByte(byte(bits))
+		buf.WriteByte(byte(bits >> 8))
+		buf.WriteByte(byte(bits >> 16))
+		buf.WriteByte(byte(bits >> 24))
+	}
+	return buf.String()
+}
+
+func checkValid(t *testing.T, pm *ProgressiveMesh) {
+	for f := uint32(0); f < pm.GetNumFacets(); f++ {
+		vertices, err := pm.GetVertices(f)
+		if err != nil {
+			t.Fatalf("Error getting vertices: %v", err)
+		}
+		for _, v := range vertices {
+			if v >= pm.GetNumVertices() {
+				t.Fatalf("Level %d: triangle %d uses vertex %d of %d", pm.Level(), f, v, pm.GetNumVertices())
+			}
+		}
+		if vertices[0] == vertices[1] || vertices[1] == vertices[2] || vertices[2] == vertices[0] {
+			t.Fatalf("Level %d: triangle %d is degenerate %v", pm.Level(), f, vertices)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	sphere := shape.Sphere(800, 100)
+	pm, err := Build(sphere, 8)
+	if err != nil {
+		t.Fatalf("Error building progressive mesh: %v", err)
+	}
+	if pm.Level() != 0 || pm.GetNumFacets() > 20 {
+		t.Errorf("Expected a coarse base mesh, got level %d with %d triangles", pm.Level(), pm.GetNumFacets())
+	}
+	checkValid(t, pm)
+	for pm.Refine() {
+		checkValid(t, pm)
+	}
+	if pm.Level() != pm.NumSplits() {
+		t.Errorf("Expected level %d, got %d", pm.NumSplits(), pm.Level())
+	}
+	if pm.GetNumFacets() != sphere.GetNumFacets() || pm.GetNumVertices() != sphere.GetNumVertices() {
+		t.Fatalf("Expected %d triangles and %d vertices, got %d and %d", sphere.GetNumFacets(),
+			sphere.GetNumVertices(), pm.GetNumFacets(), pm.GetNumVertices())
+	}
+	expected := triangleKeys(t, sphere)
+	got := triangleKeys(t, pm.ToIndexedMesh())
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Fatalf("Full resolution mesh differs from the original at triangle %d", i)
+		}
+	}
+}
+
+func TestSetNumFacets(t *testing.T) {
+	pm, err := Build(shape.Sphere(800, 100), 8)
+	if err != nil {
+		t.Fatalf("Error building progressive mesh: %v", err)
+	}
+	base := pm.ToIndexedMesh()
+	for _, target := range []uint32{100, 500, 101, 800, 10000, 300, 0} {
+		pm.SetNumFacets(target)
+		checkValid(t, pm)
+		if target < base.GetNumFacets() {
+			if pm.Level() != 0 {
+				t.Errorf("Expected the base mesh for %d triangles, got level %d", target, pm.Level())
+			}
+			continue
+		}
+		if pm.GetNumFacets() > target {
+			t.Errorf("Expected at most %d triangles, got %d", target, pm.GetNumFacets())
+		}
+		if pm.Level() < pm.NumSplits() && pm.GetNumFacets()+pm.Split(pm.Level()).NumNewFacets <= target {
+			t.Errorf("Expected the finest level with at most %d triangles, got %d", target, pm.GetNumFacets())
+		}
+	}
+	expected := triangleKeys(t, base)
+	got := triangleKeys(t, pm.ToIndexedMesh())
+	if len(expected) != len(got) {
+		t.Fatalf("Expected %d triangles back at the base level, got %d", len(expected), len(got))
+	}
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Fatalf("Base mesh differs after refining and coarsening at triangle %d", i)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	pm, err := Build(shape.Sphere(800, 100), 8)
+	if err != nil {
+		t.Fatalf("Error building progressive mesh: %v", err)
+	}
+	pm.SetNumFacets(300)
+	level := pm.Level()
+	var buf bytes.Buffer
+	if err := pm.Encode(&buf); err != nil {
+		t.Fatalf("Error encoding: %v", err)
+	}
+	if pm.Level() != level {
+		t.Errorf("Expected Encode to keep level %d, got %d", level, pm.Level())
+	}
+	// float32 positions alone would take 12 bytes per vertex for every split
+	if buf.Len() > 40*int(pm.GetNumVertices()+uint32(pm.NumSplits())) {
+		t.Errorf("Encoding is larger than expected: %d bytes", buf.Len())
+	}
+
+	decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+	if decoded.NumSplits() != pm.NumSplits() {
+		t.Fatalf("Expected %d splits, got %d", pm.NumSplits(), decoded.NumSplits())
+	}
+	pm.SetNumFacets(0)
+	for {
+		a := pm.ToIndexedMesh()
+		b := decoded.ToIndexedMesh()
+		if len(a.Indices) != len(b.Indices) || len(a.Vertices) != len(b.Vertices) {
+			t.Fatalf("Level %d: sizes differ", pm.Level())
+		}
+		for i := range a.Indices {
+			if a.Indices[i] != b.Indices[i] {
+				t.Fatalf("Level %d: indices differ", pm.Level())
+			}
+		}
+		for i := range a.Vertices {
+			if a.Vertices[i] != b.Vertices[i] {
+				t.Fatalf("Level %d: vertices differ", pm.Level())
+			}
+		}
+		if !pm.Refine() {
+			break
+		}
+		decoded.Refine()
+	}
+
+	if _, err := Decode(bytes.NewReader(buf.Bytes()[:buf.Len()/2])); err == nil {
+		t.Errorf("Expected an error decoding a truncated stream")
+	}
+	if _, err := Decode(bytes.NewReader([]byte("solid"))); err == nil {
+		t.Errorf("Expected an error decoding something else")
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	header := func(counts ...uint64) []byte {
+		b := append([]byte{}, magic[:]...)
+		for _, c := range append([]uint64{EncodingVersion}, counts...) {
+			b = binary.AppendUvarint(b, c)
+		}
+		return b
+	}
+	corrupt := map[string][]byte{
+		// 3 * 1431655765 wraps around in 32 bits
+		"huge split count": header(1, 0, 1431655765),
+		"huge base":        header(math.MaxUint32/3, math.MaxUint32/3, 0),
+		"too many splits":  append(header(3, 1, 10), make([]byte, 36+3)...),
+		"count overflow":   header(1, 0, math.MaxUint64),
+		"index overflow":   append(header(3, 1, 0), append(make([]byte, 36), 0, 1, 3)...),
+	}
+	for name, b := range corrupt {
+		if _, err := Decode(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	pm, err := Build(shape.Sphere(50, 100), 8)
+	if err != nil {
+		f.Fatalf("Error building progressive mesh: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := pm.Encode(&buf); err != nil {
+		f.Fatalf("Error encoding: %v", err)
+	}
+	f.Add(buf.Bytes())
+	f.Add([]byte("CMPM\x01\x01\x00\xd5\xaa\xd5\xaa\x05"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		decoded, err := Decode(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		// whatever decodes must refine all the way without going out of bounds
+		for decoded.Refine() {
+		}
+		decoded.ToIndexedMesh()
+	})
+}
@@ -0,0 +1,106 @@
+package qem
+
+import (
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// boundaryPoints returns the positions of the boundary vertices of the mesh, and its boundary edges
+func boundaryPoints(t *testing.T, m cloudmesh.IndexedMesh) (map[[3]float32]bool, [][2][3]float64) {
+	edges, err := mesh.FindBoundaryEdges(m, mesh.CreateNeighborhood(m))
+	if err != nil {
+		t.Fatalf("Error finding the boundary: %v", err)
+	}
+	points := make(map[[3]float32]bool)
+	segments := make([][2][3]float64, 0, len(edges))
+	for _, e := range edges {
+		var segment [2][3]float64
+		for i, v := range e {
+			p, _ := m.GetPoint(v)
+			points[[3]float32{p[0], p[1], p[2]}] = true
+			segment[i] = [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}
+		}
+		segments = append(segments, segment)
+	}
+	return points, segments
+}
+
+func TestLockBoundary(t *testing.T) {
+	tile := shape.Grid(20, 3)
+	before, _ := boundaryPoints(t, tile)
+
+	// without locking the boundary quadrics only keep it roughly in place
+	free, err := Simplify(tile, Options{TargetFacets: 100})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	freeBoundary, _ := boundaryPoints(t, free)
+	if len(freeBoundary) == len(before) {
+		t.Errorf("Expected the boundary to be simplified without locking")
+	}
+
+	locked, err := Simplify(tile, Options{TargetFacets: 100, LockBoundary: true})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	checkSimplified(t, locked, 100, len(before))
+	after, _ := boundaryPoints(t, locked)
+	if len(after) != len(before) {
+		t.Errorf("Expected %d boundary vertices, got %d", len(before), len(after))
+	}
+	for p := range before {
+		if !after[p] {
+			t.Errorf("Boundary vertex %v moved or disappeared", p)
+		}
+	}
+}
+
+func TestBoundaryTolerance(t *testing.T) {
+	tile := shape.Grid(20, 3)
+	before, beforeSegments := boundaryPoints(t, tile)
+	const tolerance = .2
+	result, err := Simplify(tile, Options{TargetFacets: 100, LockBoundary: true, BoundaryTolerance: tolerance})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	after, afterSegments := boundaryPoints(t, result)
+	if len(after) >= len(before) {
+		t.Errorf("Expected the tolerance to let the boundary simplify, got %d vertices out of %d", len(after), len(before))
+	}
+	// every input boundary vertex is within the tolerance of the new boundary, and the other way around
+	within := func(p [3]float32, segments [][2][3]float64) bool {
+		q := [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}
+		for _, s := range segments {
+			if distanceToSegment(q, s[0], s[1]) <= tolerance+1e-5 {
+				return true
+			}
+		}
+		return false
+	}
+	for p := range before {
+		if !within(p, afterSegments) {
+			t.Errorf("Input boundary vertex %v is further than %v from the simplified boundary", p, tolerance)
+		}
+	}
+	for p := range after {
+		if !within(p, beforeSegments) {
+			t.Errorf("Boundary vertex %v is further than %v from the input boundary", p, tolerance)
+		}
+	}
+}
+
+// checkSimplified checks that the mesh got to the target, or stopped because of
+// the boundary, and has no degenerate triangle
+func checkSimplified(t *testing.T, m cloudmesh.IndexedMesh, target uint32, numBoundary int) {
+	if m.GetNumFacets() > target && m.GetNumFacets() > uint32(2*numBoundary) {
+		t.Errorf("Expected about %d triangles, got %d", target, m.GetNumFacets())
+	}
+	for f := uint32(0); f < m.GetNumFacets(); f++ {
+		if mesh.ComputeArea(m, f) == 0 {
+			t.Errorf("Triangle %d is degenerate", f)
+		}
+	}
+}
@@ -0,0 +1,592 @@
+// Package qem implements the quadric error metric (QEM) edge-collapse simplifier
+// of Garland and Heckbert, "Surface Simplification Using Quadric Error Metrics".
+package qem
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// Options configures a QEM simplification.  At least one of TargetFacets and
+// MaxError must be set; when both are, the first one reached stops the simplifier.
+type Options struct {
+	// TargetFacets stops the simplifier once the mesh has at most this many triangles.
+	TargetFacets uint32
+	// MaxError stops the simplifier before it collapses an edge with a larger quadric error.
+	// The quadric error of a point is the sum of its squared distances to the planes it
+	// replaces, each weighted by the area of its triangle: an area times a squared
+	// distance, which grows with the fourth power of the scale of the mesh.
+	MaxError float64
+	// BoundaryWeight scales the penalty planes that keep the boundary of an open mesh
+	// in place.  0 uses the default of 1000.
+	BoundaryWeight float64
+	// LockBoundary keeps the boundary of an open mesh exactly where it is: boundary
+	// vertices never move and boundary edges are never collapsed, so that adjacent
+	// tiles still line up after simplification.
+	LockBoundary bool
+	// BoundaryTolerance relaxes LockBoundary: boundary vertices may move and boundary
+	// edges may collapse as long as every input boundary vertex stays within this
+	// distance of the simplified boundary.
+	BoundaryTolerance float64
+	// OnCollapse, if set, is called after every edge collapse.
+	OnCollapse func(c Collapse)
+}
+
+// Collapse records one edge collapse, with everything needed to undo it.
+// Vertices and triangles are identified by their index in the input mesh.
+type Collapse struct {
+	Kept    uint32 // the vertex that survives the collapse
+	Removed uint32 // the vertex merged into Kept
+	// KeptPosition and RemovedPosition are the positions of the two vertices before
+	// the collapse, and Position is the position of Kept after it.
+	KeptPosition    [3]float32
+	RemovedPosition [3]float32
+	Position        [3]float32
+	// RemovedFacets are the triangles that shared the edge and disappeared.
+	RemovedFacets []uint32
+	// MovedCorners are the corners (3*triangle + 0, 1 or 2) that referenced Removed
+	// and now reference Kept.
+	MovedCorners []uint32
+	// Error is the quadric error of the collapse
+	Error float64
+}
+
+// minNormalCosine is the smallest allowed cosine between the normal of a triangle
+// before and after a collapse.  Anything below it counts as a flip.
+const minNormalCosine = 0
+
+// Simplify collapses the edges of the mesh in the order of increasing quadric error
+// until Options.TargetFacets or Options.MaxError is reached, and returns the result.
+// A collapse is skipped if it would make the mesh non-manifold (it fails the link
+// condition) or flip the normal of a triangle.
+func Simplify(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, error) {
+	if opts.TargetFacets == 0 && opts.MaxError <= 0 {
+		return *cloudmesh.NewMesh(), errors.New("qem.Simplify: a target number of triangles or a max error is required")
+	}
+	d, err := newDecimator(m, opts)
+	if err != nil {
+		return *cloudmesh.NewMesh(), err
+	}
+	d.run()
+	return d.toIndexedMesh(), nil
+}
+
+// decimator holds the state of a simplification
+type decimator struct {
+	opts      Options
+	positions [][3]float64
+	quadrics  []Quadric
+	// vertexTris[v] lists the live triangles that use vertex v
+	vertexTris   [][]uint32
+	vertexAlive  []bool
+	vertexStamps []uint32 // bumped every time a vertex moves, to spot stale candidates
+	tris         [][3]uint32
+	triAlive     []bool
+	numTris      uint32
+	candidates   candidateQueue
+	// locked flags the boundary vertices when the boundary is locked.  With a
+	// tolerance boundaryPoints[v] holds the input boundary vertices merged into v,
+	// and boundaryEdges[v] the input boundary edges around them.
+	locked         []bool
+	boundaryPoints [][][3]float64
+	boundaryEdges  [][][2][3]float64
+	// scratch space for neighbor queries, so that they stay linear in the valence
+	marks   []uint32
+	markGen uint32
+	counts  []uint32
+}
+
+func newDecimator(m mesh.Mesh, opts Options) (*decimator, error) {
+	numVertices := m.GetNumVertices()
+	numTris := m.GetNumFacets()
+	d := &decimator{
+		opts:         opts,
+		positions:    make([][3]float64, numVertices),
+		quadrics:     make([]Quadric, numVertices),
+		vertexTris:   make([][]uint32, numVertices),
+		vertexAlive:  make([]bool, numVertices),
+		vertexStamps: make([]uint32, numVertices),
+		tris:         make([][3]uint32, numTris),
+		triAlive:     make([]bool, numTris),
+		marks:        make([]uint32, numVertices),
+		counts:       make([]uint32, numVertices),
+	}
+	if d.opts.BoundaryWeight <= 0 {
+		d.opts.BoundaryWeight = 1000
+	}
+	for v := uint32(0); v < numVertices; v++ {
+		p, err := m.GetPoint(v)
+		if err != nil {
+			return nil, fmt.Errorf("qem.Simplify: %v", err)
+		}
+		d.positions[v] = [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}
+	}
+	for t := uint32(0); t < numTris; t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return nil, fmt.Errorf("qem.Simplify: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if vertices[i] >= numVertices {
+				return nil, fmt.Errorf("qem.Simplify: triangle %d references vertex %d out of bounds", t, vertices[i])
+			}
+			d.tris[t][i] = vertices[i]
+		}
+		if vertices[0] == vertices[1] || vertices[1] == vertices[2] || vertices[2] == vertices[0] {
+			// a triangle with a repeated vertex has no area and no plane; drop it
+			continue
+		}
+		d.triAlive[t] = true
+		d.numTris++
+		for i := 0; i < 3; i++ {
+			d.vertexTris[vertices[i]] = append(d.vertexTris[vertices[i]], t)
+			d.vertexAlive[vertices[i]] = true
+		}
+	}
+	if opts.LockBoundary {
+		edges, err := mesh.FindBoundaryEdges(m, mesh.CreateNeighborhood(m))
+		if err != nil {
+			return nil, fmt.Errorf("qem.Simplify: %v", err)
+		}
+		if d.locked, err = mesh.BoundaryVertices(m, edges); err != nil {
+			return nil, fmt.Errorf("qem.Simplify: %v", err)
+		}
+		if opts.BoundaryTolerance > 0 {
+			d.boundaryPoints = make([][][3]float64, numVertices)
+			d.boundaryEdges = make([][][2][3]float64, numVertices)
+			for v, locked := range d.locked {
+				if locked {
+					d.boundaryPoints[v] = [][3]float64{d.positions[v]}
+				}
+			}
+			for _, e := range edges {
+				segment := [2][3]float64{d.positions[e[0]], d.positions[e[1]]}
+				d.boundaryEdges[e[0]] = append(d.boundaryEdges[e[0]], segment)
+				d.boundaryEdges[e[1]] = append(d.boundaryEdges[e[1]], segment)
+			}
+		}
+	}
+	d.initQuadrics()
+	d.initCandidates()
+	return d, nil
+}
+
+// initQuadrics gives every vertex the area weighted quadrics of the planes of its
+// triangles, plus a heavy plane through every boundary edge perpendicular to its
+// triangle, so that collapses slide along the boundary instead of eating into it.
+func (d *decimator) initQuadrics() {
+	for t, alive := range d.triAlive {
+		if !alive {
+			continue
+		}
+		tri := d.tris[t]
+		n, area := auxmath.TriangleNormal(d.positions[tri[0]], d.positions[tri[1]], d.positions[tri[2]])
+		if area == 0 {
+			continue
+		}
+		q := PlaneQuadric(n, -n.Dot(d.positions[tri[0]]), area)
+		for i := 0; i < 3; i++ {
+			d.quadrics[tri[i]].Add(q)
+		}
+		for i := 0; i < 3; i++ {
+			a, b := tri[i], tri[(i+1)%3]
+			if len(d.sharedTris(a, b)) != 1 {
+				continue
+			}
+			edge := auxmath.Vec3d(d.positions[b]).Sub(d.positions[a])
+			edgeLength := edge.Magnitude()
+			if edgeLength == 0 {
+				continue
+			}
+			perp := edge.Cross(n).Normalize()
+			bq := PlaneQuadric(perp, -perp.Dot(d.positions[a]), d.opts.BoundaryWeight*edgeLength*edgeLength)
+			d.quadrics[a].Add(bq)
+			d.quadrics[b].Add(bq)
+		}
+	}
+}
+
+// sharedTris returns the live triangles that use both a and b
+func (d *decimator) sharedTris(a uint32, b uint32) []uint32 {
+	shared := make([]uint32, 0, 2)
+	for _, t := range d.vertexTris[a] {
+		tri := d.tris[t]
+		if tri[0] == b || tri[1] == b || tri[2] == b {
+			shared = append(shared, t)
+		}
+	}
+	return shared
+}
+
+// neighbors returns the vertices that share an edge with v.
+// On return d.marks[w] == d.markGen for every neighbor w.
+func (d *decimator) neighbors(v uint32) []uint32 {
+	d.markGen++
+	retVal := make([]uint32, 0, 8)
+	for _, t := range d.vertexTris[v] {
+		for _, w := range d.tris[t] {
+			if w != v && d.marks[w] != d.markGen {
+				d.marks[w] = d.markGen
+				retVal = append(retVal, w)
+			}
+		}
+	}
+	return retVal
+}
+
+// commonNeighbors returns the vertices that share an edge with both a and b
+func (d *decimator) commonNeighbors(a uint32, b uint32) []uint32 {
+	d.neighbors(a)
+	common := make([]uint32, 0, 2)
+	for _, t := range d.vertexTris[b] {
+		for _, w := range d.tris[t] {
+			if w != a && w != b && d.marks[w] == d.markGen {
+				d.marks[w] = 0 // count it once
+				common = append(common, w)
+			}
+		}
+	}
+	return common
+}
+
+// isBoundary tells if v is on a boundary edge, i.e., an edge used by only one triangle
+func (d *decimator) isBoundary(v uint32) bool {
+	// every triangle of v uses two of its edges; count how often each neighbor shows up
+	for _, t := range d.vertexTris[v] {
+		for _, w := range d.tris[t] {
+			if w != v {
+				d.counts[w]++
+			}
+		}
+	}
+	boundary := false
+	for _, t := range d.vertexTris[v] {
+		for _, w := range d.tris[t] {
+			if w != v {
+				if d.counts[w] == 1 {
+					boundary = true
+				}
+				d.counts[w] = 0
+			}
+		}
+	}
+	return boundary
+}
+
+// boundaryNeighbors returns the vertices that share a boundary edge with v
+func (d *decimator) boundaryNeighbors(v uint32) []uint32 {
+	for _, t := range d.vertexTris[v] {
+		for _, w := range d.tris[t] {
+			if w != v {
+				d.counts[w]++
+			}
+		}
+	}
+	retVal := make([]uint32, 0, 2)
+	for _, t := range d.vertexTris[v] {
+		for _, w := range d.tris[t] {
+			if w != v {
+				if d.counts[w] == 1 {
+					retVal = append(retVal, w)
+				}
+				d.counts[w] = 0
+			}
+		}
+	}
+	return retVal
+}
+
+// boundaryStays tells if collapsing b into a at p respects the locked boundary.
+// Without a tolerance a locked vertex can only absorb an interior vertex and stay
+// in place.  With one, p must stay within the tolerance of the input boundary, and
+// the input boundary vertices around the edge within the tolerance of the boundary
+// edges they end up next to.
+func (d *decimator) boundaryStays(a uint32, b uint32, p [3]float64) bool {
+	if !d.locked[a] && !d.locked[b] {
+		return true
+	}
+	if d.opts.BoundaryTolerance <= 0 {
+		return !d.locked[b] && p == d.positions[a]
+	}
+
+	// the boundary edges around the merged vertex and its boundary neighbors, after the collapse
+	moved := func(v uint32) [3]float64 {
+		if v == a || v == b {
+			return p
+		}
+		return d.positions[v]
+	}
+	around := make([]uint32, 0, 4)
+	for _, v := range []uint32{a, b} {
+		for _, w := range d.boundaryNeighbors(v) {
+			if w != a && w != b {
+				around = append(around, w)
+			}
+		}
+	}
+	segments := make([][2][3]float64, 0, 8)
+	points := make([][3]float64, 0)
+	points = append(points, d.boundaryPoints[a]...)
+	points = append(points, d.boundaryPoints[b]...)
+	for _, w := range around {
+		segments = append(segments, [2][3]float64{p, d.positions[w]})
+		for _, x := range d.boundaryNeighbors(w) {
+			if x != a && x != b {
+				segments = append(segments, [2][3]float64{d.positions[w], moved(x)})
+			}
+		}
+		points = append(points, d.boundaryPoints[w]...)
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	input := append(append(make([][2][3]float64, 0), d.boundaryEdges[a]...), d.boundaryEdges[b]...)
+	if !withinTolerance(p, input, d.opts.BoundaryTolerance) {
+		return false
+	}
+	for _, q := range points {
+		if !withinTolerance(q, segments, d.opts.BoundaryTolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// withinTolerance tells if q is within the tolerance of one of the segments
+func withinTolerance(q [3]float64, segments [][2][3]float64, tolerance float64) bool {
+	for _, s := range segments {
+		if distanceToSegment(q, s[0], s[1]) <= tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// distanceToSegment returns the distance from q to the segment [a, b]
+func distanceToSegment(q [3]float64, a [3]float64, b [3]float64) float64 {
+	ab := auxmath.Vec3d(b).Sub(a)
+	t := 0.0
+	if l := ab.Dot(ab); l > 0 {
+		t = math.Max(0, math.Min(1, auxmath.Vec3d(q).Sub(a).Dot(ab)/l))
+	}
+	return auxmath.Vec3d(q).Sub(ab.Scale(t).Add(a)).Magnitude()
+}
+
+func (d *decimator) initCandidates() {
+	d.candidates = make(candidateQueue, 0, 3*d.numTris/2)
+	for v := range d.vertexTris {
+		for _, w := range d.neighbors(uint32(v)) {
+			if uint32(v) < w {
+				d.candidates = append(d.candidates, d.evaluate(uint32(v), w))
+			}
+		}
+	}
+	heap.Init(&d.candidates)
+}
+
+// evaluate computes where the edge (a, b) would collapse to and at what error
+func (d *decimator) evaluate(a uint32, b uint32) candidate {
+	q := d.quadrics[a].Plus(d.quadrics[b])
+	if d.locked != nil && d.opts.BoundaryTolerance <= 0 && (d.locked[a] || d.locked[b]) {
+		// the locked vertex stays, where it is
+		if d.locked[b] {
+			a, b = b, a
+		}
+		return candidate{a: a, b: b, stampA: d.vertexStamps[a], stampB: d.vertexStamps[b],
+			position: d.positions[a], cost: q.Evaluate(d.positions[a])}
+	}
+	pa, pb := d.positions[a], d.positions[b]
+	mid := [3]float64{(pa[0] + pb[0]) / 2, (pa[1] + pb[1]) / 2, (pa[2] + pb[2]) / 2}
+
+	best, bestError := pa, q.Evaluate(pa)
+	for _, p := range [][3]float64{pb, mid} {
+		if e := q.Evaluate(p); e < bestError {
+			best, bestError = p, e
+		}
+	}
+	if p, ok := q.Minimizer(); ok && auxmath.Vec3d(p).Sub(mid).Magnitude() <= 2*auxmath.Vec3d(pb).Sub(pa).Magnitude() {
+		// the optimal point, unless the system is so badly conditioned that it lands far from the edge
+		if e := q.Evaluate(p); e <= bestError {
+			best, bestError = p, e
+		}
+	}
+	return candidate{a: a, b: b, stampA: d.vertexStamps[a], stampB: d.vertexStamps[b], position: best, cost: bestError}
+}
+
+// isStale tells if a vertex of the candidate moved or died since it was evaluated
+func (d *decimator) isStale(c candidate) bool {
+	return !d.vertexAlive[c.a] || !d.vertexAlive[c.b] ||
+		d.vertexStamps[c.a] != c.stampA || d.vertexStamps[c.b] != c.stampB
+}
+
+// canCollapse checks the link condition and the normals around the edge (a, b)
+// when both are moved to p.
+func (d *decimator) canCollapse(a uint32, b uint32, p [3]float64) bool {
+	shared := d.sharedTris(a, b)
+	if len(shared) == 0 || len(shared) > 2 {
+		return false
+	}
+	// link condition: the vertices adjacent to both a and b must be exactly the
+	// vertices opposite to the edge, or the collapse pinches the surface
+	opposite := make(map[uint32]bool, 2)
+	for _, t := range shared {
+		for _, w := range d.tris[t] {
+			if w != a && w != b {
+				opposite[w] = true
+			}
+		}
+	}
+	common := d.commonNeighbors(a, b)
+	for _, w := range common {
+		if !opposite[w] {
+			return false
+		}
+	}
+	if len(common) != len(opposite) {
+		return false
+	}
+	// an interior edge between two boundary vertices would join two boundaries
+	if len(shared) == 2 && d.isBoundary(a) && d.isBoundary(b) {
+		return false
+	}
+	if d.locked != nil && !d.boundaryStays(a, b, p) {
+		return false
+	}
+	// an opposite vertex loses an edge; left with only two it would make a fin
+	for w := range opposite {
+		if len(d.neighbors(w)) <= 3 && !d.isBoundary(w) {
+			return false
+		}
+	}
+
+	for _, v := range []uint32{a, b} {
+		for _, t := range d.vertexTris[v] {
+			if t == shared[0] || (len(shared) == 2 && t == shared[1]) {
+				continue
+			}
+			tri := d.tris[t]
+			before := [3][3]float64{d.positions[tri[0]], d.positions[tri[1]], d.positions[tri[2]]}
+			after := before
+			for i := 0; i < 3; i++ {
+				if tri[i] == a || tri[i] == b {
+					after[i] = p
+				}
+			}
+			n0, area0 := auxmath.TriangleNormal(before[0], before[1], before[2])
+			n1, area1 := auxmath.TriangleNormal(after[0], after[1], after[2])
+			if area1 <= 1e-12*area0 || (area0 > 0 && n0.Dot(n1) < minNormalCosine) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// collapse merges b into a at position p
+func (d *decimator) collapse(a uint32, b uint32, p [3]float64, cost float64) {
+	record := Collapse{Kept: a, Removed: b, Error: cost,
+		KeptPosition: toFloat32(d.positions[a]), RemovedPosition: toFloat32(d.positions[b]), Position: toFloat32(p)}
+
+	for _, t := range d.sharedTris(a, b) {
+		d.triAlive[t] = false
+		d.numTris--
+		record.RemovedFacets = append(record.RemovedFacets, t)
+		for _, w := range d.tris[t] {
+			d.vertexTris[w] = removeTri(d.vertexTris[w], t)
+		}
+	}
+	for _, t := range d.vertexTris[b] {
+		for i := 0; i < 3; i++ {
+			if d.tris[t][i] == b {
+				d.tris[t][i] = a
+				record.MovedCorners = append(record.MovedCorners, 3*t+uint32(i))
+			}
+		}
+		d.vertexTris[a] = append(d.vertexTris[a], t)
+	}
+	d.vertexTris[b] = nil
+	d.vertexAlive[b] = false
+	if d.locked != nil && d.locked[b] {
+		d.locked[a] = true
+		if d.boundaryPoints != nil {
+			d.boundaryPoints[a] = append(d.boundaryPoints[a], d.boundaryPoints[b]...)
+			d.boundaryEdges[a] = append(d.boundaryEdges[a], d.boundaryEdges[b]...)
+			d.boundaryPoints[b], d.boundaryEdges[b] = nil, nil
+		}
+	}
+	if len(d.vertexTris[a]) == 0 {
+		d.vertexAlive[a] = false
+	}
+	d.positions[a] = p
+	d.quadrics[a].Add(d.quadrics[b])
+	d.vertexStamps[a]++
+
+	for _, w := range d.neighbors(a) {
+		heap.Push(&d.candidates, d.evaluate(a, w))
+	}
+	if d.opts.OnCollapse != nil {
+		d.opts.OnCollapse(record)
+	}
+}
+
+func removeTri(tris []uint32, t uint32) []uint32 {
+	for i := range tris {
+		if tris[i] == t {
+			tris[i] = tris[len(tris)-1]
+			return tris[:len(tris)-1]
+		}
+	}
+	return tris
+}
+
+func toFloat32(p [3]float64) [3]float32 {
+	return [3]float32{float32(p[0]), float32(p[1]), float32(p[2])}
+}
+
+// run collapses edges until a stopping criterion is reached or nothing can be collapsed
+func (d *decimator) run() {
+	for d.candidates.Len() > 0 && d.numTris > d.opts.TargetFacets {
+		c := heap.Pop(&d.candidates).(candidate)
+		if d.isStale(c) {
+			continue
+		}
+		if d.opts.MaxError > 0 && c.cost > d.opts.MaxError {
+			// every candidate left costs at least as much
+			break
+		}
+		if !d.canCollapse(c.a, c.b, c.position) {
+			// the edge gets evaluated again if its neighborhood changes
+			continue
+		}
+		d.collapse(c.a, c.b, c.position, c.cost)
+	}
+}
+
+// toIndexedMesh returns the live triangles, keeping only the vertices they use
+func (d *decimator) toIndexedMesh() cloudmesh.IndexedMesh {
+	newIndex := make([]uint32, len(d.positions))
+	for v := range newIndex {
+		newIndex[v] = math.MaxUint32
+	}
+	retVal := cloudmesh.IndexedMesh{Indices: make([]uint32, 0, 3*d.numTris), Vertices: make([]float32, 0)}
+	for t, alive := range d.triAlive {
+		if !alive {
+			continue
+		}
+		for _, v := range d.tris[t] {
+			if newIndex[v] == math.MaxUint32 {
+				newIndex[v] = retVal.GetNumVertices()
+				p := toFloat32(d.positions[v])
+				retVal.Vertices = append(retVal.Vertices, p[0], p[1], p[2])
+			}
+			retVal.Indices = append(retVal.Indices, newIndex[v])
+		}
+	}
+	return retVal
+}
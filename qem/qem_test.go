@@ -0,0 +1,194 @@
+package qem
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// checkClosedManifold checks that every triangle of the mesh has 3 neighbors
+// and that every edge is used once in each direction.
+func checkClosedManifold(t *testing.T, m cloudmesh.IndexedMesh) {
+	neighborhood := mesh.CreateNeighborhood(m)
+	for tri := uint32(0); tri < m.GetNumFacets(); tri++ {
+		neighbs, _ := neighborhood.GetTriangleNeighborsOfTriangle(tri)
+		if len(neighbs) != 3 {
+			t.Errorf("Expected triangle %v to have 3 neighbors and got %v", tri, len(neighbs))
+		}
+	}
+	edges := make(map[[2]uint32]int)
+	for tri := uint32(0); tri < m.GetNumFacets(); tri++ {
+		vertices, _ := m.GetVertices(tri)
+		for i := 0; i < 3; i++ {
+			edges[[2]uint32{vertices[i], vertices[(i+1)%3]}]++
+		}
+	}
+	for e, count := range edges {
+		if count != 1 || edges[[2]uint32{e[1], e[0]}] != 1 {
+			t.Errorf("Expected the edge %v to be used once in each direction", e)
+		}
+	}
+}
+
+// signedVolume is positive for a closed mesh with outward normals
+func signedVolume(m cloudmesh.IndexedMesh) float64 {
+	volume := float64(0)
+	for tri := uint32(0); tri < m.GetNumFacets(); tri++ {
+		vertices, _ := m.GetVertices(tri)
+		a, _ := m.GetPoint(vertices[0])
+		b, _ := m.GetPoint(vertices[1])
+		c, _ := m.GetPoint(vertices[2])
+		volume += float64(a[0]*(b[1]*c[2]-b[2]*c[1])-a[1]*(b[0]*c[2]-b[2]*c[0])+a[2]*(b[0]*c[1]-b[1]*c[0])) / 6
+	}
+	return volume
+}
+
+func TestSimplifyOctahedron(t *testing.T) {
+	// shape.Octahedron only adds vertices inside the 8 faces, so all of them can go for free
+	octahedron := shape.Octahedron(500)
+	result, err := Simplify(octahedron, Options{TargetFacets: 8})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	if result.GetNumFacets() != 8 {
+		t.Errorf("Expected 8 triangles and got %v", result.GetNumFacets())
+	}
+	if result.GetNumVertices() != 6 {
+		t.Errorf("Expected 6 vertices and got %v", result.GetNumVertices())
+	}
+	for v := uint32(0); v < result.GetNumVertices(); v++ {
+		p, _ := result.GetPoint(v)
+		r := math.Sqrt(float64(p[0]*p[0] + p[1]*p[1] + p[2]*p[2]))
+		if math.Abs(r-100) > 1e-2 {
+			t.Errorf("Expected vertex %v to be a corner of the octahedron, got %v", v, p)
+		}
+	}
+	checkClosedManifold(t, result)
+}
+
+func TestSimplifySphere(t *testing.T) {
+	sphere := shape.Sphere(2000, 100)
+	before := signedVolume(sphere)
+	result, err := Simplify(sphere, Options{TargetFacets: 100})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	if result.GetNumFacets() > 100 {
+		t.Errorf("Expected at most 100 triangles and got %v", result.GetNumFacets())
+	}
+	checkClosedManifold(t, result)
+	if after := signedVolume(result); after <= 0 || math.Abs(after-before) > .2*before {
+		t.Errorf("Expected the volume to stay close to %v and got %v", before, after)
+	}
+}
+
+func TestSimplifyMaxError(t *testing.T) {
+	// none of the cube's edges can be collapsed without error
+	cube := shape.BasicCube()
+	result, err := Simplify(cube, Options{MaxError: 1e-6})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	if result.GetNumFacets() != 12 {
+		t.Errorf("Expected the 12 triangles of the cube and got %v", result.GetNumFacets())
+	}
+
+	if _, err := Simplify(cube, Options{}); err == nil {
+		t.Error("Expected an error without a target or a max error")
+	}
+}
+
+// The quadric error is an area times a squared distance: scaling a mesh by 10
+// scales the errors by 10^4, and not by 10^2 as a squared distance would.
+func TestMaxErrorUnit(t *testing.T) {
+	firstError := func(m cloudmesh.IndexedMesh) float64 {
+		first := -1.0
+		_, err := Simplify(m, Options{TargetFacets: m.GetNumFacets() - 2, OnCollapse: func(c Collapse) {
+			if first < 0 {
+				first = c.Error
+			}
+		}})
+		if err != nil || first <= 0 {
+			t.Fatalf("Expected a collapse with an error, got %v (%v)", first, err)
+		}
+		return first
+	}
+	sphere := shape.Sphere(800, 100)
+	scaled := shape.Sphere(800, 100)
+	for i := range scaled.Vertices {
+		scaled.Vertices[i] *= 10
+	}
+	e, scaledError := firstError(sphere), firstError(scaled)
+	if ratio := scaledError / e; math.Abs(ratio-1e4) > 10 {
+		t.Errorf("Expected the error to scale by 10^4, got %v", ratio)
+	}
+	result, err := Simplify(scaled, Options{MaxError: 100 * e})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	if result.GetNumFacets() != scaled.GetNumFacets() {
+		t.Errorf("Expected no collapse below a max error of 100 times the unscaled one, got %v triangles", result.GetNumFacets())
+	}
+}
+
+func TestSimplifyPlane(t *testing.T) {
+	// the boundary quadrics keep the outline of an open strip in place
+	plane := shape.CreatePlane(100)
+	result, err := Simplify(plane, Options{TargetFacets: 2})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	if result.GetNumFacets() != 2 {
+		t.Errorf("Expected 2 triangles and got %v", result.GetNumFacets())
+	}
+	area := float32(0)
+	for tri := uint32(0); tri < result.GetNumFacets(); tri++ {
+		area += mesh.ComputeArea(result, tri)
+	}
+	if math.Abs(float64(area)-50) > 1e-3 {
+		t.Errorf("Expected the area of the strip, 50, and got %v", area)
+	}
+}
+
+func TestOnCollapse(t *testing.T) {
+	octahedron := shape.Octahedron(100)
+	removed := 0
+	collapses := 0
+	result, _ := Simplify(octahedron, Options{TargetFacets: 50, OnCollapse: func(c Collapse) {
+		collapses++
+		removed += len(c.RemovedFacets)
+		if c.Kept == c.Removed {
+			t.Errorf("Expected two different vertices in a collapse, got %v", c.Kept)
+		}
+	}})
+	if int(octahedron.GetNumFacets())-removed != int(result.GetNumFacets()) {
+		t.Errorf("Expected the collapses to remove %v triangles and they removed %v",
+			int(octahedron.GetNumFacets())-int(result.GetNumFacets()), removed)
+	}
+	if int(octahedron.GetNumVertices())-collapses != int(result.GetNumVertices()) {
+		t.Errorf("Expected one vertex less per collapse")
+	}
+}
+
+func TestQuadric(t *testing.T) {
+	// the planes x=1, y=2 and z=3 meet at (1,2,3)
+	q := PlaneQuadric([3]float64{1, 0, 0}, -1, 1)
+	q.Add(PlaneQuadric([3]float64{0, 1, 0}, -2, 1))
+	q = q.Plus(PlaneQuadric([3]float64{0, 0, 1}, -3, 1))
+	p, ok := q.Minimizer()
+	if !ok {
+		t.Fatal("Expected the quadric to have a minimizer")
+	}
+	if math.Abs(p[0]-1) > 1e-9 || math.Abs(p[1]-2) > 1e-9 || math.Abs(p[2]-3) > 1e-9 {
+		t.Errorf("Expected (1,2,3) and got %v", p)
+	}
+	if e := q.Evaluate([3]float64{2, 2, 3}); math.Abs(e-1) > 1e-9 {
+		t.Errorf("Expected an error of 1 and got %v", e)
+	}
+	if _, ok := PlaneQuadric([3]float64{1, 0, 0}, 0, 1).Minimizer(); ok {
+		t.Error("Did not expect a single plane to have a minimizer")
+	}
+}
@@ -0,0 +1,64 @@
+package qem
+
+import (
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+)
+
+// Quadric is the symmetric 4x4 matrix Q of the quadric error metric, stored as
+// its upper triangle.  The error of a point v is v^T*Q*v with v = (x, y, z, 1).
+type Quadric [10]float64
+
+// PlaneQuadric returns the quadric of the squared distance to the plane n*x + d = 0
+// scaled by weight.  n must be a unit vector.
+func PlaneQuadric(n [3]float64, d float64, weight float64) Quadric {
+	a, b, c := n[0], n[1], n[2]
+	return Quadric{
+		weight * a * a, weight * a * b, weight * a * c, weight * a * d,
+		weight * b * b, weight * b * c, weight * b * d,
+		weight * c * c, weight * c * d,
+		weight * d * d}
+}
+
+// Add adds o to q
+func (q *Quadric) Add(o Quadric) {
+	for i := range q {
+		q[i] += o[i]
+	}
+}
+
+// Plus returns q + o
+func (q Quadric) Plus(o Quadric) Quadric {
+	q.Add(o)
+	return q
+}
+
+// Evaluate returns the error v^T*Q*v of the point v
+func (q Quadric) Evaluate(v [3]float64) float64 {
+	x, y, z := v[0], v[1], v[2]
+	e := q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+	// rounding can take an exact fit slightly below 0
+	return math.Max(e, 0)
+}
+
+// Minimizer returns the point with the smallest error, if the quadric has a unique one
+func (q Quadric) Minimizer() ([3]float64, bool) {
+	a := [3][3]float64{
+		{q[0], q[1], q[2]},
+		{q[1], q[4], q[5]},
+		{q[2], q[5], q[7]}}
+	scale := math.Abs(q[0]) + math.Abs(q[4]) + math.Abs(q[7])
+	if scale == 0 {
+		return [3]float64{}, false
+	}
+	// a nearly singular system puts the minimizer far away along a flat valley of the quadric
+	x, err := auxmath.Solve3x3(a, [3]float64{-q[3], -q[6], -q[8]}, 1e-9*scale*scale*scale)
+	if err != nil {
+		return x, false
+	}
+	return x, true
+}
@@ -0,0 +1,25 @@
+package qem
+
+// candidate is an edge (a, b) that may be collapsed to position at the given cost.
+// The stamps are those of a and b when the candidate was evaluated; if either
+// vertex moved since then the candidate is stale and is skipped.
+type candidate struct {
+	a, b           uint32
+	stampA, stampB uint32
+	position       [3]float64
+	cost           float64
+}
+
+// candidateQueue is a min-heap of candidates ordered by cost
+type candidateQueue []candidate
+
+func (q candidateQueue) Len() int            { return len(q) }
+func (q candidateQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q candidateQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *candidateQueue) Push(x interface{}) { *q = append(*q, x.(candidate)) }
+func (q *candidateQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,486 @@
+// Package remesh implements isotropic remeshing (Botsch and Kobbelt, "A Remeshing
+// Approach to Multiresolution Modeling"): long edges are split, short ones collapsed,
+// edges are flipped towards regular valences and the vertices are relaxed
+// tangentially and projected back onto the input, until the triangles are close to
+// equilateral with edges of the target length.
+package remesh
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// Options configures a remeshing
+type Options struct {
+	// TargetEdgeLength is the edge length to aim for
+	TargetEdgeLength float64
+	// Iterations is the number of split, collapse, flip and relax rounds.  0 uses the default of 5.
+	Iterations int
+}
+
+// Remesh returns an isotropic remeshing of the mesh.  The boundary of an open mesh
+// stays where it is: its edges are split like the others but never collapsed or
+// flipped, and its vertices never move.  Non-manifold edges are kept the same way.
+func Remesh(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, error) {
+	if !(opts.TargetEdgeLength > 0) {
+		return *cloudmesh.NewMesh(), errors.New("remesh.Remesh: the target edge length must be positive")
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = 5
+	}
+	grid, err := mesh.NewClosestPointGrid(m)
+	if err != nil {
+		return *cloudmesh.NewMesh(), fmt.Errorf("remesh.Remesh: %v", err)
+	}
+	r, err := newRemesher(m, grid)
+	if err != nil {
+		return *cloudmesh.NewMesh(), err
+	}
+	high := 4 * opts.TargetEdgeLength / 3
+	low := 4 * opts.TargetEdgeLength / 5
+	for i := 0; i < opts.Iterations; i++ {
+		r.splitLongEdges(high)
+		r.collapseShortEdges(low, high)
+		r.flipEdges()
+		r.relax()
+	}
+	return r.toIndexedMesh(), nil
+}
+
+// remesher holds the mesh being remeshed
+type remesher struct {
+	positions [][3]float64
+	// vertexTris[v] lists the live triangles that use vertex v
+	vertexTris  [][]uint32
+	vertexAlive []bool
+	// locked flags the vertices on the boundary (or on a non-manifold edge), which never move
+	locked   []bool
+	tris     [][3]uint32
+	triAlive []bool
+	grid     *mesh.ClosestPointGrid
+}
+
+func newRemesher(m mesh.Mesh, grid *mesh.ClosestPointGrid) (*remesher, error) {
+	numVertices := m.GetNumVertices()
+	r := &remesher{
+		positions:   make([][3]float64, numVertices),
+		vertexTris:  make([][]uint32, numVertices),
+		vertexAlive: make([]bool, numVertices),
+		locked:      make([]bool, numVertices),
+		tris:        make([][3]uint32, 0, m.GetNumFacets()),
+		triAlive:    make([]bool, 0, m.GetNumFacets()),
+		grid:        grid,
+	}
+	for v := uint32(0); v < numVertices; v++ {
+		p, err := m.GetPoint(v)
+		if err != nil {
+			return nil, fmt.Errorf("remesh.Remesh: %v", err)
+		}
+		r.positions[v] = [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}
+	}
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return nil, fmt.Errorf("remesh.Remesh: %v", err)
+		}
+		if vertices[0] >= numVertices || vertices[1] >= numVertices || vertices[2] >= numVertices {
+			return nil, fmt.Errorf("remesh.Remesh: triangle %d references a vertex out of bounds", t)
+		}
+		if vertices[0] == vertices[1] || vertices[1] == vertices[2] || vertices[2] == vertices[0] {
+			continue
+		}
+		r.addTri([3]uint32{vertices[0], vertices[1], vertices[2]})
+	}
+	for _, tri := range r.tris {
+		for i := 0; i < 3; i++ {
+			a, b := tri[i], tri[(i+1)%3]
+			if len(r.sharedTris(a, b)) != 2 {
+				r.locked[a], r.locked[b] = true, true
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *remesher) addTri(tri [3]uint32) uint32 {
+	t := uint32(len(r.tris))
+	r.tris = append(r.tris, tri)
+	r.triAlive = append(r.triAlive, true)
+	for _, v := range tri {
+		r.vertexTris[v] = append(r.vertexTris[v], t)
+		r.vertexAlive[v] = true
+	}
+	return t
+}
+
+func (r *remesher) addVertex(p [3]float64, locked bool) uint32 {
+	r.positions = append(r.positions, p)
+	r.vertexTris = append(r.vertexTris, make([]uint32, 0, 6))
+	r.vertexAlive = append(r.vertexAlive, true)
+	r.locked = append(r.locked, locked)
+	return uint32(len(r.positions) - 1)
+}
+
+// sharedTris returns the live triangles that use both a and b
+func (r *remesher) sharedTris(a uint32, b uint32) []uint32 {
+	shared := make([]uint32, 0, 2)
+	for _, t := range r.vertexTris[a] {
+		tri := r.tris[t]
+		if tri[0] == b || tri[1] == b || tri[2] == b {
+			shared = append(shared, t)
+		}
+	}
+	return shared
+}
+
+// neighbors returns the vertices that share an edge with v
+func (r *remesher) neighbors(v uint32) []uint32 {
+	retVal := make([]uint32, 0, 8)
+	for _, t := range r.vertexTris[v] {
+		for _, w := range r.tris[t] {
+			if w != v && !contains(retVal, w) {
+				retVal = append(retVal, w)
+			}
+		}
+	}
+	return retVal
+}
+
+func contains(vertices []uint32, v uint32) bool {
+	for _, w := range vertices {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// edges returns every edge of the live triangles once
+func (r *remesher) edges() [][2]uint32 {
+	edges := make([][2]uint32, 0, 3*len(r.tris)/2)
+	for t, tri := range r.tris {
+		if !r.triAlive[t] {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			a, b := tri[i], tri[(i+1)%3]
+			// an edge shows up from a to b with a < b, unless no triangle has it in that direction
+			if a < b || !r.hasDirectedEdge(b, a) {
+				edges = append(edges, [2]uint32{a, b})
+			}
+		}
+	}
+	return edges
+}
+
+// hasDirectedEdge tells if a live triangle goes from a to b
+func (r *remesher) hasDirectedEdge(a uint32, b uint32) bool {
+	for _, t := range r.vertexTris[a] {
+		if _, ok := oppositeOf(r.tris[t], a, b); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *remesher) edgeLength(a uint32, b uint32) float64 {
+	return auxmath.Vec3d(r.positions[a]).Sub(r.positions[b]).Magnitude()
+}
+
+// splitLongEdges splits the edges longer than high at their midpoint
+func (r *remesher) splitLongEdges(high float64) {
+	for _, e := range r.edges() {
+		a, b := e[0], e[1]
+		if r.edgeLength(a, b) <= high {
+			continue
+		}
+		shared := r.sharedTris(a, b)
+		if len(shared) == 0 {
+			continue // already split
+		}
+		pa, pb := r.positions[a], r.positions[b]
+		mid := r.addVertex([3]float64{(pa[0] + pb[0]) / 2, (pa[1] + pb[1]) / 2, (pa[2] + pb[2]) / 2},
+			len(shared) != 2)
+		for _, t := range shared {
+			// the triangle (u, w, c) with the edge (u, w) becomes (u, mid, c) and (mid, w, c)
+			tri := r.tris[t]
+			i := 0
+			for ; i < 3; i++ {
+				if (tri[i] == a || tri[i] == b) && (tri[(i+1)%3] == a || tri[(i+1)%3] == b) {
+					break
+				}
+			}
+			u, w, c := tri[i], tri[(i+1)%3], tri[(i+2)%3]
+			r.tris[t] = [3]uint32{u, mid, c}
+			r.vertexTris[w] = removeTri(r.vertexTris[w], t)
+			r.vertexTris[mid] = append(r.vertexTris[mid], t)
+			r.addTri([3]uint32{mid, w, c})
+		}
+	}
+}
+
+// collapseShortEdges collapses the edges shorter than low, unless that makes an edge longer than high
+func (r *remesher) collapseShortEdges(low float64, high float64) {
+	for _, e := range r.edges() {
+		a, b := e[0], e[1]
+		if !r.vertexAlive[a] || !r.vertexAlive[b] || r.edgeLength(a, b) >= low {
+			continue
+		}
+		if r.locked[a] && r.locked[b] {
+			continue
+		}
+		if r.locked[b] {
+			a, b = b, a
+		}
+		p := r.positions[a]
+		if !r.locked[a] {
+			pb := r.positions[b]
+			p = [3]float64{(p[0] + pb[0]) / 2, (p[1] + pb[1]) / 2, (p[2] + pb[2]) / 2}
+		}
+		if r.canCollapse(a, b, p, high) {
+			r.collapse(a, b, p)
+		}
+	}
+}
+
+// canCollapse checks the link condition, the length of the new edges and the normals
+// around the edge (a, b) when both are moved to p
+func (r *remesher) canCollapse(a uint32, b uint32, p [3]float64, high float64) bool {
+	shared := r.sharedTris(a, b)
+	if len(shared) != 2 {
+		return false
+	}
+	opposite := make([]uint32, 0, 2)
+	for _, t := range shared {
+		for _, w := range r.tris[t] {
+			if w != a && w != b {
+				opposite = append(opposite, w)
+			}
+		}
+	}
+	neighborsA := r.neighbors(a)
+	numCommon := 0
+	for _, w := range r.neighbors(b) {
+		if w != a && contains(neighborsA, w) {
+			if !contains(opposite, w) {
+				return false
+			}
+			numCommon++
+		}
+		if auxmath.Vec3d(p).Sub(r.positions[w]).Magnitude() > high {
+			return false
+		}
+	}
+	if numCommon != len(opposite) {
+		return false
+	}
+	for _, w := range neighborsA {
+		if auxmath.Vec3d(p).Sub(r.positions[w]).Magnitude() > high {
+			return false
+		}
+	}
+	for _, w := range opposite {
+		if len(r.neighbors(w)) <= 3 {
+			return false
+		}
+	}
+	for _, v := range []uint32{a, b} {
+		for _, t := range r.vertexTris[v] {
+			if t == shared[0] || t == shared[1] {
+				continue
+			}
+			tri := r.tris[t]
+			before := [3][3]float64{r.positions[tri[0]], r.positions[tri[1]], r.positions[tri[2]]}
+			after := before
+			for i := 0; i < 3; i++ {
+				if tri[i] == a || tri[i] == b {
+					after[i] = p
+				}
+			}
+			n0, area0 := auxmath.TriangleNormal(before[0], before[1], before[2])
+			n1, area1 := auxmath.TriangleNormal(after[0], after[1], after[2])
+			if area1 <= 1e-12*area0 || n0.Dot(n1) < .5 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// collapse merges b into a at position p
+func (r *remesher) collapse(a uint32, b uint32, p [3]float64) {
+	for _, t := range r.sharedTris(a, b) {
+		r.triAlive[t] = false
+		for _, w := range r.tris[t] {
+			r.vertexTris[w] = removeTri(r.vertexTris[w], t)
+		}
+	}
+	for _, t := range r.vertexTris[b] {
+		for i := 0; i < 3; i++ {
+			if r.tris[t][i] == b {
+				r.tris[t][i] = a
+			}
+		}
+		r.vertexTris[a] = append(r.vertexTris[a], t)
+	}
+	r.vertexTris[b] = nil
+	r.vertexAlive[b] = false
+	r.positions[a] = p
+}
+
+// targetValence is 6 inside and 4 on the boundary
+func (r *remesher) targetValence(v uint32) int {
+	if r.locked[v] {
+		return 4
+	}
+	return 6
+}
+
+// flipEdges flips the interior edges whose flip brings the valences of the four
+// vertices involved closer to their targets
+func (r *remesher) flipEdges() {
+	for _, e := range r.edges() {
+		a, b := e[0], e[1]
+		shared := r.sharedTris(a, b)
+		if len(shared) != 2 || (r.locked[a] && r.locked[b]) {
+			continue
+		}
+		// orient the triangles as (a, b, c) and (b, a, d)
+		t1, t2 := shared[0], shared[1]
+		c, ok1 := oppositeOf(r.tris[t1], a, b)
+		d, ok2 := oppositeOf(r.tris[t2], b, a)
+		if !ok1 || !ok2 {
+			t1, t2 = t2, t1
+			c, ok1 = oppositeOf(r.tris[t1], a, b)
+			d, ok2 = oppositeOf(r.tris[t2], b, a)
+			if !ok1 || !ok2 {
+				continue // inconsistent orientation
+			}
+		}
+		if c == d || contains(r.neighbors(c), d) {
+			continue
+		}
+		va, vb := len(r.neighbors(a)), len(r.neighbors(b))
+		vc, vd := len(r.neighbors(c)), len(r.neighbors(d))
+		if va <= 3 || vb <= 3 {
+			continue
+		}
+		deviation := func(v uint32, valence int) int {
+			return abs(valence - r.targetValence(v))
+		}
+		before := deviation(a, va) + deviation(b, vb) + deviation(c, vc) + deviation(d, vd)
+		after := deviation(a, va-1) + deviation(b, vb-1) + deviation(c, vc+1) + deviation(d, vd+1)
+		if after >= before {
+			continue
+		}
+		// the new triangles must not fold over
+		pa, pb, pc, pd := r.positions[a], r.positions[b], r.positions[c], r.positions[d]
+		n1, area1 := auxmath.TriangleNormal(pa, pb, pc)
+		n2, area2 := auxmath.TriangleNormal(pb, pa, pd)
+		m1, newArea1 := auxmath.TriangleNormal(pc, pa, pd)
+		m2, newArea2 := auxmath.TriangleNormal(pd, pb, pc)
+		if area1 == 0 || area2 == 0 || newArea1 == 0 || newArea2 == 0 {
+			continue
+		}
+		n := n1.Add(n2).Normalize()
+		if n.Dot(m1) < .5 || n.Dot(m2) < .5 {
+			continue
+		}
+		r.tris[t1] = [3]uint32{c, a, d}
+		r.tris[t2] = [3]uint32{d, b, c}
+		r.vertexTris[a] = removeTri(r.vertexTris[a], t2)
+		r.vertexTris[b] = removeTri(r.vertexTris[b], t1)
+		r.vertexTris[c] = append(r.vertexTris[c], t2)
+		r.vertexTris[d] = append(r.vertexTris[d], t1)
+	}
+}
+
+// oppositeOf returns the third vertex of tri if it goes from a to b
+func oppositeOf(tri [3]uint32, a uint32, b uint32) (uint32, bool) {
+	for i := 0; i < 3; i++ {
+		if tri[i] == a && tri[(i+1)%3] == b {
+			return tri[(i+2)%3], true
+		}
+	}
+	return 0, false
+}
+
+// relax moves every free vertex towards the centroid of its neighbors, within its
+// tangent plane, and projects it back onto the input surface
+func (r *remesher) relax() {
+	moved := make([][3]float64, len(r.positions))
+	copy(moved, r.positions)
+	for v := range r.positions {
+		if !r.vertexAlive[v] || r.locked[v] {
+			continue
+		}
+		neighbors := r.neighbors(uint32(v))
+		if len(neighbors) == 0 {
+			continue
+		}
+		var q [3]float64
+		var n auxmath.Vec3d
+		for _, w := range neighbors {
+			for i := 0; i < 3; i++ {
+				q[i] += r.positions[w][i] / float64(len(neighbors))
+			}
+		}
+		for _, t := range r.vertexTris[v] {
+			tri := r.tris[t]
+			normal, area := auxmath.TriangleNormal(r.positions[tri[0]], r.positions[tri[1]], r.positions[tri[2]])
+			n = n.Add(normal.Scale(area))
+		}
+		n = n.Normalize()
+		p := r.positions[v]
+		// remove the normal component of the move
+		h := n.Dot(auxmath.Vec3d(p).Sub(q))
+		target := n.Scale(h).Add(q)
+		moved[v], _, _ = r.grid.Closest(target)
+	}
+	r.positions = moved
+}
+
+// toIndexedMesh returns the live triangles, keeping only the vertices they use
+func (r *remesher) toIndexedMesh() cloudmesh.IndexedMesh {
+	newIndex := make([]uint32, len(r.positions))
+	for v := range newIndex {
+		newIndex[v] = math.MaxUint32
+	}
+	retVal := cloudmesh.IndexedMesh{Indices: make([]uint32, 0, 3*len(r.tris)), Vertices: make([]float32, 0)}
+	for t, alive := range r.triAlive {
+		if !alive {
+			continue
+		}
+		for _, v := range r.tris[t] {
+			if newIndex[v] == math.MaxUint32 {
+				newIndex[v] = retVal.GetNumVertices()
+				p := r.positions[v]
+				retVal.Vertices = append(retVal.Vertices, float32(p[0]), float32(p[1]), float32(p[2]))
+			}
+			retVal.Indices = append(retVal.Indices, newIndex[v])
+		}
+	}
+	return retVal
+}
+
+func removeTri(tris []uint32, t uint32) []uint32 {
+	for i := range tris {
+		if tris[i] == t {
+			tris[i] = tris[len(tris)-1]
+			return tris[:len(tris)-1]
+		}
+	}
+	return tris
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,125 @@
+package remesh
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/qem"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// quality returns the mean edge length and the fraction of triangles whose smallest angle is below 30 degrees
+func quality(m cloudmesh.IndexedMesh) (float64, float64) {
+	sum, count, bad := 0.0, 0, 0
+	for f := uint32(0); f < m.GetNumFacets(); f++ {
+		vertices, _ := m.GetVertices(f)
+		var p [3]auxmath.Vec3d
+		for i, v := range vertices {
+			q, _ := m.GetPoint(v)
+			p[i] = auxmath.Vec3d{float64(q[0]), float64(q[1]), float64(q[2])}
+		}
+		minAngle := math.Pi
+		for i := 0; i < 3; i++ {
+			u, w := p[(i+1)%3].Sub(p[i]), p[(i+2)%3].Sub(p[i])
+			sum += u.Magnitude()
+			count++
+			minAngle = math.Min(minAngle, math.Acos(u.Dot(w)/(u.Magnitude()*w.Magnitude())))
+		}
+		if minAngle < math.Pi/6 {
+			bad++
+		}
+	}
+	return sum / float64(count), float64(bad) / float64(m.GetNumFacets())
+}
+
+// checkClosed checks that every edge is used once in each direction
+func checkClosed(t *testing.T, m cloudmesh.IndexedMesh) {
+	edges := make(map[[2]uint32]int)
+	for f := uint32(0); f < m.GetNumFacets(); f++ {
+		vertices, _ := m.GetVertices(f)
+		for c := 0; c < 3; c++ {
+			edges[[2]uint32{vertices[c], vertices[(c+1)%3]}]++
+		}
+	}
+	for e, count := range edges {
+		if count != 1 || edges[[2]uint32{e[1], e[0]}] != 1 {
+			t.Fatalf("Edge %v is used %d times and its twin %d times", e, count, edges[[2]uint32{e[1], e[0]}])
+		}
+	}
+}
+
+func TestRemeshSphere(t *testing.T) {
+	// the decimated sphere has long, skinny triangles
+	decimated, err := qem.Simplify(shape.Sphere(8000, 100), qem.Options{TargetFacets: 500})
+	if err != nil {
+		t.Fatalf("Simplify failed: %v", err)
+	}
+	_, badBefore := quality(decimated)
+
+	const target = 10
+	result, err := Remesh(decimated, Options{TargetEdgeLength: target})
+	if err != nil {
+		t.Fatalf("Remesh failed: %v", err)
+	}
+	checkClosed(t, result)
+	mean, bad := quality(result)
+	t.Logf("%d triangles, mean edge %v, %v bad triangles (%v before)", result.GetNumFacets(), mean, bad, badBefore)
+	if mean < .8*target || mean > 1.2*target {
+		t.Errorf("Expected a mean edge length close to %v, got %v", target, mean)
+	}
+	if bad > badBefore/4 {
+		t.Errorf("Expected fewer badly shaped triangles, got %v (%v before)", bad, badBefore)
+	}
+	// the vertices lie on the decimated sphere
+	grid, err := mesh.NewClosestPointGrid(decimated)
+	if err != nil {
+		t.Fatalf("Error building the grid: %v", err)
+	}
+	for v := uint32(0); v < result.GetNumVertices(); v++ {
+		p, _ := result.GetPoint(v)
+		if _, _, d := grid.Closest([3]float64{float64(p[0]), float64(p[1]), float64(p[2])}); d > 1e-3 {
+			t.Fatalf("Vertex %v is %v off the surface", v, d)
+		}
+	}
+}
+
+func TestRemeshKeepsBoundary(t *testing.T) {
+	tile := shape.Grid(10, 1)
+	result, err := Remesh(tile, Options{TargetEdgeLength: .5, Iterations: 3})
+	if err != nil {
+		t.Fatalf("Remesh failed: %v", err)
+	}
+	edges, err := mesh.FindBoundaryEdges(result, mesh.CreateNeighborhood(result))
+	if err != nil {
+		t.Fatalf("Error finding the boundary: %v", err)
+	}
+	// the boundary edges of length 1 are split in two
+	if len(edges) != 80 {
+		t.Errorf("Expected 80 boundary edges, got %v", len(edges))
+	}
+	for _, e := range edges {
+		for _, v := range e {
+			p, _ := result.GetPoint(v)
+			x, y := float64(p[0]), float64(p[1])
+			if x != 0 && y != 0 && x != 10 && y != 10 {
+				t.Fatalf("Boundary vertex %v moved off the side of the tile", p)
+			}
+		}
+	}
+	mean, bad := quality(result)
+	if mean < .4 || mean > .6 || bad > .05 {
+		t.Errorf("Expected well shaped triangles with edges of about .5, got %v and %v bad", mean, bad)
+	}
+}
+
+func TestRemeshErrors(t *testing.T) {
+	if _, err := Remesh(shape.BasicCube(), Options{}); err == nil {
+		t.Errorf("Expected an error without a target edge length")
+	}
+	if _, err := Remesh(cloudmesh.IndexedMesh{}, Options{TargetEdgeLength: 1}); err == nil {
+		t.Errorf("Expected an error for an empty mesh")
+	}
+}
@@ -0,0 +1,422 @@
+package repair
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// TriangulationWeight is what the triangulation of a hole minimizes
+type TriangulationWeight int
+
+const (
+	// MinimumArea minimizes the area of the triangles
+	MinimumArea TriangulationWeight = iota
+	// MinimumDihedral minimizes the largest dihedral angle between neighboring
+	// triangles, including the triangles around the hole, then the area (Liepa)
+	MinimumDihedral
+)
+
+// HoleOptions are the options of FillHoles
+type HoleOptions struct {
+	Weight TriangulationWeight
+	// MaxEdges is the number of edges of the largest hole to fill.  0 stands for
+	// DefaultMaxEdges and a negative value fills every hole.  The triangulation takes
+	// time cubic in the number of edges, and the outer border of an open mesh is a
+	// loop like any other, so the limit keeps it from being capped by accident.
+	MaxEdges int
+	// Fair adds vertices inside the holes, so that their triangles are about as large
+	// as the ones around them, and smooths them into a membrane over the hole
+	Fair bool
+}
+
+// Hole is a boundary loop of the mesh
+type Hole struct {
+	// Loop is the vertices of the loop, in the direction of its boundary edges
+	Loop   []uint32 `json:"loop"`
+	Filled bool     `json:"filled"`
+	// NumTriangles and NumVertices are the triangles and vertices added to fill the hole
+	NumTriangles int `json:"numTriangles"`
+	NumVertices  int `json:"numVertices"`
+}
+
+// DefaultMaxEdges is the number of edges of the largest hole filled when MaxEdges is 0
+const DefaultMaxEdges = 256
+
+// fairingIterations is the number of smoothing sweeps over the vertices added in a hole
+const fairingIterations = 100
+
+// FillHoles returns a copy of the mesh with its holes filled, and the boundary loops
+// it found.  A loop is left open when it is larger than MaxEdges, when its boundary
+// edges do not close (e.g., around a non-manifold vertex), or when every triangulation
+// of it would join two of its vertices that the mesh already joins.
+func FillHoles(m mesh.Mesh, opts HoleOptions) (cloudmesh.IndexedMesh, []Hole, error) {
+	retVal, err := copyMesh(m)
+	if err != nil {
+		return *cloudmesh.NewMesh(), nil, fmt.Errorf("repair.FillHoles: %v", err)
+	}
+	edges, err := mesh.FindBoundaryEdges(retVal, mesh.CreateNeighborhood(retVal))
+	if err != nil {
+		return *cloudmesh.NewMesh(), nil, fmt.Errorf("repair.FillHoles: %v", err)
+	}
+
+	maxEdges := opts.MaxEdges
+	if maxEdges == 0 {
+		maxEdges = DefaultMaxEdges
+	}
+
+	f := filler{
+		points:   make([]auxmath.Vec3d, retVal.GetNumVertices()),
+		opposite: make(map[[2]uint32]uint32, len(edges)),
+		edges:    make(map[[2]uint32]bool, 3*retVal.GetNumFacets()),
+	}
+	for v := range f.points {
+		f.points[v], _ = mesh.GetPoint64(retVal, uint32(v))
+	}
+	for _, e := range edges {
+		f.opposite[e] = math.MaxUint32
+	}
+	for t := uint32(0); t < retVal.GetNumFacets(); t++ {
+		tri, _ := retVal.GetTriangle(t)
+		for i := 0; i < 3; i++ {
+			e := [2]uint32{tri[i], tri[(i+1)%3]}
+			if _, ok := f.opposite[e]; ok {
+				f.opposite[e] = tri[(i+2)%3]
+			}
+		}
+		f.addEdges(tri)
+	}
+
+	loops := mesh.FindBoundaryLoops(edges)
+	holes := make([]Hole, len(loops))
+	numVertices := len(f.points)
+	for h, loop := range loops {
+		holes[h].Loop = loop
+		n := len(loop)
+		if _, closed := f.opposite[[2]uint32{loop[n-1], loop[0]}]; !closed || n < 3 || (maxEdges > 0 && n > maxEdges) {
+			continue
+		}
+		tris, ok := f.triangulate(loop, opts.Weight)
+		if !ok {
+			continue
+		}
+		if opts.Fair {
+			tris = f.refine(loop, tris)
+			f.fair(tris, numVertices)
+		}
+		for _, tri := range tris {
+			retVal.AddTriangle(tri[0], tri[1], tri[2])
+			f.addEdges(tri)
+		}
+		holes[h].Filled = true
+		holes[h].NumTriangles = len(tris)
+		holes[h].NumVertices = len(f.points) - numVertices
+		numVertices = len(f.points)
+	}
+	for _, p := range f.points[retVal.GetNumVertices():] {
+		retVal.AddVertex(float32(p[0]), float32(p[1]), float32(p[2]))
+	}
+	return retVal, holes, nil
+}
+
+// copyMesh returns a copy of m, checking its vertex indices
+func copyMesh(m mesh.Mesh) (cloudmesh.IndexedMesh, error) {
+	retVal := *cloudmesh.NewMesh()
+	retVal.Reserve(m.GetNumFacets(), m.GetNumVertices())
+	for v := uint32(0); v < m.GetNumVertices(); v++ {
+		p, err := m.GetPoint(v)
+		if err != nil {
+			return retVal, err
+		}
+		retVal.AddVertex(p[0], p[1], p[2])
+	}
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return retVal, err
+		}
+		for _, v := range vertices {
+			if v >= m.GetNumVertices() {
+				return retVal, errors.New("vertex index is out of bounds")
+			}
+		}
+		retVal.AddTriangle(vertices[0], vertices[1], vertices[2])
+	}
+	return retVal, nil
+}
+
+// filler holds the positions of the mesh, the vertex opposite to each boundary edge,
+// and the edges of the mesh, which a fill must not add again
+type filler struct {
+	points   []auxmath.Vec3d
+	opposite map[[2]uint32]uint32
+	edges    map[[2]uint32]bool
+}
+
+// addEdges adds the edges of a triangle to the edges of the mesh
+func (f *filler) addEdges(tri [3]uint32) {
+	for i := 0; i < 3; i++ {
+		f.edges[undirected(tri[i], tri[(i+1)%3])] = true
+	}
+}
+
+// hasEdge tells if the mesh has an edge between a and b
+func (f *filler) hasEdge(a uint32, b uint32) bool {
+	return f.edges[undirected(a, b)]
+}
+
+func undirected(a uint32, b uint32) [2]uint32 {
+	if a > b {
+		return [2]uint32{b, a}
+	}
+	return [2]uint32{a, b}
+}
+
+// triangulate returns the triangulation of the loop that minimizes the weight, by
+// dynamic programming over the sub-polygons from loop[i] to loop[j] (Barequet and Sharir).
+// The triangle of loop[i], loop[k] and loop[j] (i < k < j) is (loop[i], loop[j], loop[k]),
+// which runs the boundary edges the other way.  A chord between two vertices of the loop
+// that the mesh already joins would make that edge non-manifold, so it is never used;
+// the second return value is false when the loop can't be triangulated without one.
+func (f *filler) triangulate(loop []uint32, weight TriangulationWeight) ([][3]uint32, bool) {
+	n := len(loop)
+	area := make([][]float64, n)
+	angle := make([][]float64, n)
+	split := make([][]int, n)
+	for i := range area {
+		area[i] = make([]float64, n)
+		angle[i] = make([]float64, n)
+		split[i] = make([]int, n)
+	}
+	// the normal of the triangle next to the edge from loop[i] to loop[j]: around the
+	// hole for neighboring vertices, and in the triangulation of the sub-polygon otherwise
+	neighborNormal := func(i, j int) auxmath.Vec3d {
+		if j == i+1 {
+			a, b := loop[i], loop[j]
+			return f.normal(a, b, f.opposite[[2]uint32{a, b}])
+		}
+		return f.normal(loop[i], loop[j], loop[split[i][j]])
+	}
+	for gap := 2; gap < n; gap++ {
+		for i := 0; i+gap < n; i++ {
+			j := i + gap
+			bestArea, bestAngle := math.Inf(1), math.Inf(1)
+			if !(i == 0 && j == n-1) && f.hasEdge(loop[i], loop[j]) {
+				// no triangle may have this chord as a side
+				area[i][j], angle[i][j] = bestArea, bestAngle
+				continue
+			}
+			for k := i + 1; k < j; k++ {
+				if math.IsInf(area[i][k], 1) || math.IsInf(area[k][j], 1) {
+					continue
+				}
+				triArea := f.area(loop[i], loop[j], loop[k])
+				a := area[i][k] + area[k][j] + triArea
+				maxAngle := 0.0
+				if weight == MinimumDihedral {
+					normal := f.normal(loop[i], loop[j], loop[k])
+					maxAngle = math.Max(angle[i][k], angle[k][j])
+					maxAngle = math.Max(maxAngle, dihedral(normal, neighborNormal(i, k)))
+					maxAngle = math.Max(maxAngle, dihedral(normal, neighborNormal(k, j)))
+					if i == 0 && j == n-1 {
+						// the last boundary edge, from loop[n-1] to loop[0]
+						a, b := loop[n-1], loop[0]
+						maxAngle = math.Max(maxAngle, dihedral(normal, f.normal(a, b, f.opposite[[2]uint32{a, b}])))
+					}
+				}
+				if maxAngle < bestAngle-1e-9 || (maxAngle < bestAngle+1e-9 && a < bestArea) {
+					bestArea, bestAngle, split[i][j] = a, maxAngle, k
+				}
+			}
+			area[i][j], angle[i][j] = bestArea, bestAngle
+		}
+	}
+
+	if math.IsInf(area[0][n-1], 1) {
+		return nil, false
+	}
+	tris := make([][3]uint32, 0, n-2)
+	var collect func(i, j int)
+	collect = func(i, j int) {
+		if j-i < 2 {
+			return
+		}
+		k := split[i][j]
+		tris = append(tris, [3]uint32{loop[i], loop[j], loop[k]})
+		collect(i, k)
+		collect(k, j)
+	}
+	collect(0, n-1)
+	return tris, true
+}
+
+// refine splits the triangles of the hole at their centroid while they are larger
+// than the edges around them, and flips their edges to keep them Delaunay (Liepa)
+func (f *filler) refine(loop []uint32, tris [][3]uint32) [][3]uint32 {
+	// the target edge length around each vertex: the mean of its boundary edges
+	scale := make(map[uint32]float64, len(loop))
+	for i, v := range loop {
+		prev, next := loop[(i+len(loop)-1)%len(loop)], loop[(i+1)%len(loop)]
+		scale[v] = (f.distance(v, prev) + f.distance(v, next)) / 2
+	}
+	onLoop := make(map[[2]uint32]bool, len(loop))
+	for i, v := range loop {
+		next := loop[(i+1)%len(loop)]
+		onLoop[[2]uint32{v, next}], onLoop[[2]uint32{next, v}] = true, true
+	}
+
+	for round := 0; round < 20; round++ {
+		numSplits := 0
+		for t := 0; t < len(tris); t++ {
+			tri := tris[t]
+			var c auxmath.Vec3d
+			s := 0.0
+			for _, v := range tri {
+				for i := 0; i < 3; i++ {
+					c[i] += f.points[v][i] / 3
+				}
+				s += scale[v] / 3
+			}
+			large := true
+			for _, v := range tri {
+				d := math.Sqrt2 * c.Sub(f.points[v]).Magnitude()
+				if d <= s || d <= scale[v] {
+					large = false
+				}
+			}
+			if !large {
+				continue
+			}
+			f.points = append(f.points, c)
+			nv := uint32(len(f.points) - 1)
+			scale[nv] = s
+			tris[t] = [3]uint32{tri[0], tri[1], nv}
+			tris = append(tris, [3]uint32{tri[1], tri[2], nv}, [3]uint32{tri[2], tri[0], nv})
+			numSplits++
+		}
+		f.relax(tris, onLoop)
+		if numSplits == 0 {
+			break
+		}
+	}
+	return tris
+}
+
+// relax flips the inner edges of the triangles whose opposite angles add up to more
+// than pi, until none is left
+func (f *filler) relax(tris [][3]uint32, onLoop map[[2]uint32]bool) {
+	for sweep := 0; sweep < 100; sweep++ {
+		edges := make(map[[2]uint32]int, 3*len(tris))
+		for t, tri := range tris {
+			for i := 0; i < 3; i++ {
+				edges[[2]uint32{tri[i], tri[(i+1)%3]}] = t
+			}
+		}
+		numFlips := 0
+		for t := range tris {
+			for i := 0; i < 3; i++ {
+				tri := tris[t]
+				a, b, c := tri[i], tri[(i+1)%3], tri[(i+2)%3]
+				u, ok := edges[[2]uint32{b, a}]
+				if !ok || onLoop[[2]uint32{a, b}] {
+					continue
+				}
+				d := uint32(0)
+				for _, w := range tris[u] {
+					if w != a && w != b {
+						d = w
+					}
+				}
+				if _, exists := edges[[2]uint32{c, d}]; exists || c == d || f.hasEdge(c, d) {
+					continue
+				}
+				if f.angle(c, a, b)+f.angle(d, b, a) <= math.Pi+1e-9 {
+					continue
+				}
+				tris[t], tris[u] = [3]uint32{c, a, d}, [3]uint32{d, b, c}
+				for _, e := range [][2]uint32{{a, b}, {b, a}, {b, c}, {c, a}, {a, d}, {d, b}} {
+					delete(edges, e)
+				}
+				edges[[2]uint32{c, a}], edges[[2]uint32{a, d}], edges[[2]uint32{d, c}] = t, t, t
+				edges[[2]uint32{d, b}], edges[[2]uint32{b, c}], edges[[2]uint32{c, d}] = u, u, u
+				numFlips++
+			}
+		}
+		if numFlips == 0 {
+			return
+		}
+	}
+}
+
+// fair moves each vertex added to the hole (those from first on) to the centroid of
+// its neighbors, which makes a membrane over the hole
+func (f *filler) fair(tris [][3]uint32, first int) {
+	neighbors := make(map[uint32][]uint32)
+	for _, tri := range tris {
+		for i := 0; i < 3; i++ {
+			v, w := tri[i], tri[(i+1)%3]
+			if int(v) >= first {
+				neighbors[v] = append(neighbors[v], w)
+			}
+			if int(w) >= first {
+				neighbors[w] = append(neighbors[w], v)
+			}
+		}
+	}
+	// every sweep reads the positions of the previous one, so the order of the map doesn't matter
+	moved := make(map[uint32]auxmath.Vec3d, len(neighbors))
+	for iteration := 0; iteration < fairingIterations; iteration++ {
+		for v, ring := range neighbors {
+			var c auxmath.Vec3d
+			for _, w := range ring {
+				for i := 0; i < 3; i++ {
+					c[i] += f.points[w][i] / float64(len(ring))
+				}
+			}
+			moved[v] = c
+		}
+		for v, c := range moved {
+			f.points[v] = c
+		}
+	}
+}
+
+func (f *filler) distance(a uint32, b uint32) float64 {
+	return f.points[a].Sub(f.points[b]).Magnitude()
+}
+
+func (f *filler) area(a uint32, b uint32, c uint32) float64 {
+	_, area := auxmath.TriangleNormal(f.points[a], f.points[b], f.points[c])
+	return area
+}
+
+// normal returns the unit normal of the triangle (a, b, c), or zero when it is degenerate
+func (f *filler) normal(a uint32, b uint32, c uint32) auxmath.Vec3d {
+	if c == math.MaxUint32 {
+		return auxmath.Vec3d{}
+	}
+	n, _ := auxmath.TriangleNormal(f.points[a], f.points[b], f.points[c])
+	return n
+}
+
+// angle returns the angle at v of the triangle (v, a, b)
+func (f *filler) angle(v uint32, a uint32, b uint32) float64 {
+	u, w := f.points[a].Sub(f.points[v]), f.points[b].Sub(f.points[v])
+	l := u.Magnitude() * w.Magnitude()
+	if l == 0 {
+		return 0
+	}
+	return math.Acos(math.Max(-1, math.Min(1, u.Dot(w)/l)))
+}
+
+// dihedral returns the angle between two unit normals, or 0 when either is unknown
+func dihedral(n1 auxmath.Vec3d, n2 auxmath.Vec3d) float64 {
+	if n1.Magnitude() == 0 || n2.Magnitude() == 0 {
+		return 0
+	}
+	return math.Acos(math.Max(-1, math.Min(1, n1.Dot(n2))))
+}
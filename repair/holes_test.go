@@ -0,0 +1,197 @@
+package repair
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/validate"
+)
+
+// removeTriangles returns a copy of the mesh without the triangles for which remove is true
+func removeTriangles(m cloudmesh.IndexedMesh, remove func(t uint32) bool) cloudmesh.IndexedMesh {
+	retVal := cloudmesh.IndexedMesh{Indices: make([]uint32, 0, len(m.Indices)), Vertices: m.Vertices}
+	for t := uint32(0); t < m.GetNumFacets(); t++ {
+		if !remove(t) {
+			retVal.Indices = append(retVal.Indices, m.Indices[3*t:3*t+3]...)
+		}
+	}
+	return retVal
+}
+
+// centroidZ returns the z coordinate of the centroid of triangle t
+func centroidZ(m cloudmesh.IndexedMesh, t uint32) float32 {
+	z := float32(0)
+	for _, v := range m.Indices[3*t : 3*t+3] {
+		z += m.Vertices[3*v+2] / 3
+	}
+	return z
+}
+
+// distance returns the distance between the vertices a and b
+func distance(m cloudmesh.IndexedMesh, a uint32, b uint32) float64 {
+	p, _ := m.GetPoint(a)
+	q, _ := m.GetPoint(b)
+	dx, dy, dz := float64(p[0]-q[0]), float64(p[1]-q[1]), float64(p[2]-q[2])
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+func TestFillHolesCube(t *testing.T) {
+	cube := shape.BasicCube()
+	// remove the two triangles of a face, and one of another
+	open := removeTriangles(cube, func(t uint32) bool { return t == 0 || t == 1 || t == 6 })
+	if validate.Validate(open).Watertight {
+		t.Fatal("Expected the cube to be open")
+	}
+	for _, weight := range []TriangulationWeight{MinimumArea, MinimumDihedral} {
+		filled, holes, err := FillHoles(open, HoleOptions{Weight: weight})
+		if err != nil {
+			t.Fatalf("FillHoles failed: %v", err)
+		}
+		if len(holes) != 2 || !holes[0].Filled || !holes[1].Filled {
+			t.Fatalf("Expected two filled holes, got %+v", holes)
+		}
+		if holes[0].NumTriangles+holes[1].NumTriangles != 3 || filled.GetNumFacets() != 12 || filled.GetNumVertices() != 8 {
+			t.Errorf("Expected the three triangles back, got %+v", holes)
+		}
+		r := validate.Validate(filled)
+		if !r.Watertight || !r.Valid() || r.Genus != 0 {
+			t.Errorf("Expected a watertight cube, got %+v", r)
+		}
+		if volume := signedVolume(filled, []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, make([]bool, 12)); volume <= 0 {
+			t.Errorf("Expected the fill to be oriented outward, got a volume of %v", volume)
+		}
+	}
+
+	// the largest hole is left open
+	_, holes, err := FillHoles(open, HoleOptions{MaxEdges: 3})
+	if err != nil {
+		t.Fatalf("FillHoles failed: %v", err)
+	}
+	for _, h := range holes {
+		if h.Filled != (len(h.Loop) == 3) {
+			t.Errorf("Expected only the hole of three edges to be filled, got %+v", holes)
+		}
+	}
+}
+
+func TestFillHolesSphere(t *testing.T) {
+	sphere := shape.Sphere(2000, 100)
+	open := removeTriangles(sphere, func(t uint32) bool { return centroidZ(sphere, t) > 50 })
+	numRemoved := sphere.GetNumFacets() - open.GetNumFacets()
+	if r := validate.Validate(open); r.NumBoundaryLoops != 1 || numRemoved == 0 {
+		t.Fatalf("Expected a single hole, got %d loops", r.NumBoundaryLoops)
+	}
+
+	for _, weight := range []TriangulationWeight{MinimumArea, MinimumDihedral} {
+		filled, holes, err := FillHoles(open, HoleOptions{Weight: weight})
+		if err != nil {
+			t.Fatalf("FillHoles failed: %v", err)
+		}
+		if len(holes) != 1 || holes[0].NumTriangles != len(holes[0].Loop)-2 || holes[0].NumVertices != 0 {
+			t.Errorf("Expected the hole to be triangulated without new vertices, got %+v", holes)
+		}
+		if r := validate.Validate(filled); !r.Watertight || r.Genus != 0 {
+			t.Errorf("Expected a watertight sphere, got %+v", r)
+		}
+	}
+
+	// fairing adds vertices to match the triangles around the hole, on a smooth patch
+	// that bulges no further than the cap that was removed
+	filled, holes, err := FillHoles(open, HoleOptions{Weight: MinimumDihedral, Fair: true})
+	if err != nil {
+		t.Fatalf("FillHoles failed: %v", err)
+	}
+	if holes[0].NumVertices == 0 || holes[0].NumTriangles != len(holes[0].Loop)-2+2*holes[0].NumVertices {
+		t.Errorf("Expected a disk of new vertices in the hole, got %+v", holes[0])
+	}
+	again, _, _ := FillHoles(open, HoleOptions{Weight: MinimumDihedral, Fair: true})
+	for i := range filled.Vertices {
+		if filled.Vertices[i] != again.Vertices[i] {
+			t.Fatalf("Expected fairing to give the same vertices every time")
+		}
+	}
+	if r := validate.Validate(filled); !r.Watertight || r.Genus != 0 || len(r.ZeroAreaTriangles) != 0 {
+		t.Errorf("Expected a watertight sphere, got %+v", r)
+	}
+	// the edges of the fill are about as long as the edges of the loop
+	loop := holes[0].Loop
+	loopLength, fillLength := 0.0, 0.0
+	for i, v := range loop {
+		loopLength += distance(filled, v, loop[(i+1)%len(loop)]) / float64(len(loop))
+	}
+	fill := filled.Indices[len(open.Indices):]
+	for c := 0; c < len(fill); c += 3 {
+		for i := 0; i < 3; i++ {
+			fillLength += distance(filled, fill[c+i], fill[c+(i+1)%3]) / float64(len(fill))
+		}
+	}
+	if fillLength < loopLength/2 || fillLength > 2*loopLength {
+		t.Errorf("Expected edges of about %v in the hole, got %v", loopLength, fillLength)
+	}
+	for v := open.GetNumVertices(); v < filled.GetNumVertices(); v++ {
+		p, _ := filled.GetPoint(v)
+		if r := math.Sqrt(float64(p[0]*p[0] + p[1]*p[1] + p[2]*p[2])); p[2] < 40 || r > 100 {
+			t.Errorf("Expected vertex %d inside the removed cap, got %v", v, p)
+		}
+	}
+}
+
+func TestFillHolesOpen(t *testing.T) {
+	// the border of an open mesh is a loop too large to fill unless asked
+	plane := shape.CreatePlane(2000)
+	filled, holes, err := FillHoles(plane, HoleOptions{})
+	if err != nil {
+		t.Fatalf("FillHoles failed: %v", err)
+	}
+	if len(holes) != 1 || holes[0].Filled || filled.GetNumFacets() != plane.GetNumFacets() {
+		t.Errorf("Expected the border of the plane to be left open, got %d holes", len(holes))
+	}
+	_, holes, err = FillHoles(shape.Grid(4, 0), HoleOptions{MaxEdges: -1})
+	if err != nil || len(holes) != 1 || !holes[0].Filled {
+		t.Errorf("Expected the border of the grid to be filled, got %+v: %v", holes, err)
+	}
+}
+
+func TestTriangulateExistingEdges(t *testing.T) {
+	// the square 0, 1, 2, 3, where the mesh already joins 1 and 3 elsewhere
+	f := filler{
+		points:   []auxmath.Vec3d{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+		opposite: map[[2]uint32]uint32{},
+		edges:    map[[2]uint32]bool{},
+	}
+	f.addEdges([3]uint32{1, 3, 0})
+	loop := []uint32{0, 1, 2, 3}
+	for _, weight := range []TriangulationWeight{MinimumArea, MinimumDihedral} {
+		tris, ok := f.triangulate(loop, weight)
+		if !ok || len(tris) != 2 {
+			t.Fatalf("Expected 2 triangles, got %v", tris)
+		}
+		for _, tri := range tris {
+			for i := 0; i < 3; i++ {
+				if undirected(tri[i], tri[(i+1)%3]) == undirected(1, 3) {
+					t.Errorf("Expected the chord from 0 to 2, got %v", tris)
+				}
+			}
+		}
+	}
+	// with both diagonals taken there is no way to fill the square
+	f.addEdges([3]uint32{0, 2, 3})
+	if tris, ok := f.triangulate(loop, MinimumArea); ok {
+		t.Errorf("Expected no triangulation, got %v", tris)
+	}
+}
+
+func TestFillHolesErrors(t *testing.T) {
+	bad := cloudmesh.IndexedMesh{Indices: []uint32{0, 1, 7}, Vertices: make([]float32, 9)}
+	if _, _, err := FillHoles(bad, HoleOptions{}); err == nil {
+		t.Error("Expected an error for a vertex out of bounds")
+	}
+	closed := shape.BasicCube()
+	filled, holes, err := FillHoles(closed, HoleOptions{})
+	if err != nil || len(holes) != 0 || filled.GetNumFacets() != 12 {
+		t.Errorf("Expected a closed mesh to be left alone, got %d holes: %v", len(holes), err)
+	}
+}
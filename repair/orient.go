@@ -0,0 +1,130 @@
+// Package repair fixes common defects of meshes loaded from STL files
+package repair
+
+import (
+	"fmt"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// Orient returns a copy of the mesh whose triangles agree on their orientation, and
+// the triangles it flipped.  The orientation spreads from triangle to neighboring
+// triangle over each connected component.  A closed component is then oriented
+// outward, so that it encloses a positive volume, and an open component keeps the
+// orientation of the majority of its triangles.
+//
+// A non-orientable component (e.g., a Moebius strip) cannot agree everywhere: the
+// first orientation that reaches a triangle is kept.
+func Orient(m mesh.Mesh) (cloudmesh.IndexedMesh, []uint32, error) {
+	retVal, err := copyMesh(m)
+	if err != nil {
+		return *cloudmesh.NewMesh(), nil, fmt.Errorf("repair.Orient: %v", err)
+	}
+	numTris := retVal.GetNumFacets()
+
+	neighborhood := mesh.CreateNeighborhood(retVal)
+	flip := make([]bool, numTris)
+	visited := make([]bool, numTris)
+	component := make([]uint32, 0)
+	for seed := uint32(0); seed < numTris; seed++ {
+		if visited[seed] {
+			continue
+		}
+		// spread the orientation of the seed over its component
+		visited[seed] = true
+		component = append(component[:0], seed)
+		closed := true
+		for i := 0; i < len(component); i++ {
+			t := component[i]
+			neighbors, _ := neighborhood.GetTriangleNeighborsOfTriangle(t)
+			if len(neighbors) < 3 {
+				closed = false
+			}
+			for _, n := range neighbors {
+				if visited[n] {
+					continue
+				}
+				consistent, ok := consistentWinding(retVal, t, n)
+				if !ok {
+					continue
+				}
+				visited[n] = true
+				if consistent {
+					flip[n] = flip[t]
+				} else {
+					flip[n] = !flip[t]
+				}
+				component = append(component, n)
+			}
+		}
+
+		// pick which of the two orientations of the component to keep
+		reverse := false
+		if closed {
+			reverse = signedVolume(retVal, component, flip) < 0
+		} else {
+			numFlipped := 0
+			for _, t := range component {
+				if flip[t] {
+					numFlipped++
+				}
+			}
+			reverse = 2*numFlipped > len(component)
+		}
+		if reverse {
+			for _, t := range component {
+				flip[t] = !flip[t]
+			}
+		}
+	}
+
+	flipped := make([]uint32, 0)
+	for t := uint32(0); t < numTris; t++ {
+		if flip[t] {
+			vertices, _ := retVal.GetVertices(t)
+			retVal.SetVertices(t, []uint32{vertices[0], vertices[2], vertices[1]})
+			flipped = append(flipped, t)
+		}
+	}
+	return retVal, flipped, nil
+}
+
+// consistentWinding tells whether the triangles t and n, which share an edge, run it
+// in opposite directions as they should.  ok is false when either is degenerate or
+// they do not share an edge.
+func consistentWinding(m cloudmesh.IndexedMesh, t uint32, n uint32) (consistent bool, ok bool) {
+	tv, _ := m.GetTriangle(t)
+	nv, _ := m.GetTriangle(n)
+	if tv[0] == tv[1] || tv[1] == tv[2] || tv[2] == tv[0] || nv[0] == nv[1] || nv[1] == nv[2] || nv[2] == nv[0] {
+		return false, false
+	}
+	for i := 0; i < 3; i++ {
+		a, b := tv[i], tv[(i+1)%3]
+		for j := 0; j < 3; j++ {
+			if nv[j] == b && nv[(j+1)%3] == a {
+				return true, true
+			}
+			if nv[j] == a && nv[(j+1)%3] == b {
+				return false, true
+			}
+		}
+	}
+	return false, false
+}
+
+// signedVolume returns six times the volume enclosed by the triangles, with the
+// flagged triangles flipped
+func signedVolume(m cloudmesh.IndexedMesh, tris []uint32, flip []bool) float64 {
+	volume := 0.0
+	for _, t := range tris {
+		q, _ := mesh.GetTrianglePoints64(m, t)
+		p := [3]auxmath.Vec3d{q[0], q[1], q[2]}
+		if flip[t] {
+			p[1], p[2] = p[2], p[1]
+		}
+		volume += p[0].Dot(p[1].Cross(p[2]))
+	}
+	return volume
+}
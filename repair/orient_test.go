@@ -0,0 +1,90 @@
+package repair
+
+import (
+	"fmt"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/validate"
+)
+
+// flipTriangles returns a copy of the mesh with the triangles flipped
+func flipTriangles(m cloudmesh.IndexedMesh, tris ...uint32) cloudmesh.IndexedMesh {
+	retVal := cloudmesh.IndexedMesh{Indices: append([]uint32{}, m.Indices...), Vertices: m.Vertices}
+	for _, t := range tris {
+		retVal.Indices[3*t+1], retVal.Indices[3*t+2] = retVal.Indices[3*t+2], retVal.Indices[3*t+1]
+	}
+	return retVal
+}
+
+func TestOrientClosed(t *testing.T) {
+	cube := shape.BasicCube()
+	if volume := signedVolume(cube, []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, make([]bool, 12)); volume <= 0 {
+		t.Fatalf("Expected the cube to be oriented outward, got a volume of %v", volume)
+	}
+	cases := []struct {
+		name  string
+		flips []uint32
+	}{
+		{"a few flipped triangles", []uint32{1, 4, 9}},
+		{"inside out", []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{"mostly inside out", []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	}
+	for _, c := range cases {
+		oriented, flipped, err := Orient(flipTriangles(cube, c.flips...))
+		if err != nil {
+			t.Fatalf("%s: Orient failed: %v", c.name, err)
+		}
+		if fmt.Sprint(flipped) != fmt.Sprint(c.flips) {
+			t.Errorf("%s: expected the triangles %v to be flipped, got %v", c.name, c.flips, flipped)
+		}
+		if fmt.Sprint(oriented.Indices) != fmt.Sprint(cube.Indices) {
+			t.Errorf("%s: expected the cube back, got %v", c.name, oriented.Indices)
+		}
+	}
+}
+
+func TestOrientOpen(t *testing.T) {
+	grid := shape.Grid(3, 0)
+	// the majority wins
+	oriented, flipped, err := Orient(flipTriangles(grid, 0, 5, 6, 17))
+	if err != nil {
+		t.Fatalf("Orient failed: %v", err)
+	}
+	if fmt.Sprint(flipped) != "[0 5 6 17]" || !validate.Validate(oriented).Oriented {
+		t.Errorf("Expected the triangles [0 5 6 17] to be flipped back, got %v", flipped)
+	}
+
+	// two components are oriented separately
+	two := cloudmesh.IndexedMesh{Indices: append([]uint32{}, grid.Indices...), Vertices: append([]float32{}, grid.Vertices...)}
+	for _, i := range grid.Indices {
+		two.Indices = append(two.Indices, i+grid.GetNumVertices())
+	}
+	two.Vertices = append(two.Vertices, grid.Vertices...)
+	inverted := make([]uint32, 0)
+	for tri := uint32(18); tri < 36; tri++ {
+		if tri != 20 {
+			inverted = append(inverted, tri)
+		}
+	}
+	oriented, flipped, err = Orient(flipTriangles(two, append([]uint32{3}, inverted...)...))
+	if err != nil {
+		t.Fatalf("Orient failed: %v", err)
+	}
+	if fmt.Sprint(flipped) != "[3 20]" || !validate.Validate(oriented).Oriented {
+		t.Errorf("Expected the triangles [3 20] to be flipped, got %v", flipped)
+	}
+}
+
+func TestOrientErrors(t *testing.T) {
+	m := cloudmesh.IndexedMesh{Indices: []uint32{0, 1, 3}, Vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}}
+	if _, _, err := Orient(m); err == nil {
+		t.Errorf("Expected an error for a vertex out of bounds")
+	}
+	// a degenerate triangle is left alone
+	m.Indices = []uint32{0, 1, 2, 1, 1, 2}
+	if _, flipped, err := Orient(m); err != nil || len(flipped) != 0 {
+		t.Errorf("Expected no flips, got %v (%v)", flipped, err)
+	}
+}
@@ -33,8 +33,7 @@ func Octahedron(numTriangles uint32) cloudmesh.IndexedMesh {
 		center := mesh.ComputeCentroid(baseSphere, tri)
 		//centerNorm := auxmath.Normalize(center)
 		//baseSphere.Vertices = append(baseSphere.Vertices, centerNorm[0], centerNorm[1], centerNorm[2])
-		baseSphere.Vertices = append(baseSphere.Vertices, center[0], center[1], center[2])
-		newVertex := uint32(len(baseSphere.Vertices)-3) / 3
+		newVertex := baseSphere.AddVertex(center[0], center[1], center[2])
 
 		baseSphere.Pop()
 		baseSphere.AddTriangle(vertices[1], newVertex, vertices[0])
@@ -89,3 +89,26 @@ func CreatePlane(numTris int) *cloudmesh.IndexedMesh {
 	ret.Indices = theseTriangles
 	return ret
 }
+
+// Grid creates a grid of n x n unit squares in the xy plane, starting at the origin,
+// each square split in 2 triangles facing +z.  The vertices are numbered row by row,
+// and are lifted along z by amplitude * sin(x/4) * cos(y/3), so that an amplitude
+// of 0 gives a flat grid and any other a rolling terrain tile.
+func Grid(n int, amplitude float32) cloudmesh.IndexedMesh {
+	m := *cloudmesh.NewMesh()
+	m.Reserve(uint32(2*n*n), uint32((n+1)*(n+1)))
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			z := float32(float64(amplitude) * math.Sin(float64(x)/4) * math.Cos(float64(y)/3))
+			m.AddVertex(float32(x), float32(y), z)
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			v := uint32(y*(n+1) + x)
+			m.AddTriangle(v, v+1, v+uint32(n)+2)
+			m.AddTriangle(v, v+uint32(n)+2, v+uint32(n)+1)
+		}
+	}
+	return m
+}
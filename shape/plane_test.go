@@ -1,6 +1,10 @@
 package shape
 
-import "testing"
+import (
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
 
 func TestCreatePlane(t *testing.T) {
 	singlevertices := []float32{
@@ -26,3 +30,27 @@ func TestCreatePlane(t *testing.T) {
 	}
 
 }
+
+func TestGrid(t *testing.T) {
+	grid := Grid(4, 0)
+	if grid.GetNumVertices() != 25 {
+		t.Errorf("Expected 25 vertices and got %v\n", grid.GetNumVertices())
+	}
+	if grid.GetNumFacets() != 32 {
+		t.Errorf("Expected 32 facets and got %v\n", grid.GetNumFacets())
+	}
+	// the vertices go row by row, the last one being the far corner
+	if p, _ := grid.GetPoint(24); p[0] != 4 || p[1] != 4 || p[2] != 0 {
+		t.Errorf("Expected the last vertex at (4,4,0) and got %v\n", p)
+	}
+	// the first triangle faces +z
+	if normal, err := mesh.ComputeNormal(grid, 0); err != nil || normal[2] < .99 {
+		t.Errorf("Expected the first triangle to face +z and got %v (%v)\n", normal, err)
+	}
+	tile := Grid(8, 3)
+	for v := uint32(0); v < tile.GetNumVertices(); v++ {
+		if p, _ := tile.GetPoint(v); p[2] < -3 || p[2] > 3 {
+			t.Errorf("Expected the height of vertex %v within the amplitude and got %v\n", v, p[2])
+		}
+	}
+}
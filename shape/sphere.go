@@ -3,6 +3,7 @@ package shape
 
 import (
 	"log"
+	"math"
 
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
@@ -66,8 +67,7 @@ func FacetSphere(numTriangles uint32) cloudmesh.IndexedMesh {
 
 		center := mesh.ComputeCentroid(baseSphere, tri)
 		centerNorm := auxmath.Normalize(center)
-		baseSphere.Vertices = append(baseSphere.Vertices, centerNorm[0], centerNorm[1], centerNorm[2])
-		newVertex := uint32(len(baseSphere.Vertices)-3) / 3
+		newVertex := baseSphere.AddVertex(centerNorm[0], centerNorm[1], centerNorm[2])
 
 		//DEBUG
 		//if debugI == 0 || debugI == 1 || debugI == 2 || debugI == 3 || debugI == 4 {
@@ -85,3 +85,15 @@ func FacetSphere(numTriangles uint32) cloudmesh.IndexedMesh {
 	}
 	return baseSphere //serializecloudmesh.IndexedMesh(baseSphere)
 }
+
+// Sphere creates a sphere of the given radius centered at the origin, with about
+// numTriangles triangles: the subdivided Octahedron with every vertex moved onto the sphere
+func Sphere(numTriangles uint32, radius float32) cloudmesh.IndexedMesh {
+	sphere := Octahedron(numTriangles)
+	for v := uint32(0); v < sphere.GetNumVertices(); v++ {
+		p, _ := sphere.GetPoint(v)
+		r := float32(math.Sqrt(float64(p[0]*p[0]+p[1]*p[1]+p[2]*p[2]))) / radius
+		sphere.SetPoint(v, []float32{p[0] / r, p[1] / r, p[2] / r})
+	}
+	return sphere
+}
@@ -1,6 +1,9 @@
 package shape
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 // This is a light test that just checks the number of triangles
 // and vetices because we hand coded the mesh.
@@ -32,3 +35,18 @@ func TestFacetSphere(t *testing.T) {
 		t.Errorf("Expected 8 facets and got %v\n", retVal.GetNumFacets())
 	}
 }
+
+func TestSphere(t *testing.T) {
+	sphere := Sphere(200, 100)
+	octahedron := Octahedron(200)
+	if sphere.GetNumFacets() != octahedron.GetNumFacets() {
+		t.Errorf("Expected %v facets and got %v\n", octahedron.GetNumFacets(), sphere.GetNumFacets())
+	}
+	for v := uint32(0); v < sphere.GetNumVertices(); v++ {
+		p, _ := sphere.GetPoint(v)
+		r := math.Sqrt(float64(p[0]*p[0] + p[1]*p[1] + p[2]*p[2]))
+		if math.Abs(r-100) > 1e-3 {
+			t.Errorf("Expected vertex %v at radius 100 and got %v\n", v, r)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package simplify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cluster"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/qem"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/vsa"
+)
+
+func init() {
+	Register(qemSimplifier{})
+	Register(clusterSimplifier{name: "cluster", representative: cluster.Average})
+	Register(clusterSimplifier{name: "cluster-quadric", representative: cluster.Quadric})
+	Register(vsaSimplifier{})
+}
+
+// qemSimplifier collapses edges by quadric error (see package qem).
+// It uses TargetFacets, MaxError, which is a quadric error (an area times a squared
+// distance, see qem.Options), and the boundary options.
+type qemSimplifier struct{}
+
+func (qemSimplifier) Name() string { return "qem" }
+
+func (s qemSimplifier) Simplify(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, Report, error) {
+	start := time.Now()
+	out, err := qem.Simplify(m, qem.Options{TargetFacets: opts.TargetFacets, MaxError: opts.MaxError,
+		LockBoundary: opts.LockBoundary, BoundaryTolerance: opts.BoundaryTolerance})
+	if err != nil {
+		return out, Report{}, err
+	}
+	if err := CarryAttributes(m, &out); err != nil {
+		return out, Report{}, err
+	}
+	r, err := newReport(s.Name(), m, out, start, opts)
+	return out, r, err
+}
+
+// clusterSimplifier clusters vertices on a grid (see package cluster).
+// It uses CellSize or Resolution, and the boundary options.  With neither, it searches for the finest
+// resolution that gives at most TargetFacets triangles.
+type clusterSimplifier struct {
+	name           string
+	representative cluster.Representative
+}
+
+func (s clusterSimplifier) Name() string { return s.name }
+
+func (s clusterSimplifier) Simplify(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, Report, error) {
+	start := time.Now()
+	var out cloudmesh.IndexedMesh
+	var err error
+	switch {
+	case opts.CellSize > 0:
+		out, err = cluster.Simplify(m, s.options(opts, opts.CellSize))
+	case opts.Resolution > 0:
+		out, err = s.simplifyAt(m, opts, opts.Resolution)
+	case opts.TargetFacets > 0:
+		out, err = s.simplifyToTarget(m, opts)
+	default:
+		err = errors.New("simplify: cluster needs a cell size, a resolution or a target")
+	}
+	if err != nil {
+		return out, Report{}, err
+	}
+	if err := CarryAttributes(m, &out); err != nil {
+		return out, Report{}, err
+	}
+	r, err := newReport(s.Name(), m, out, start, opts)
+	return out, r, err
+}
+
+func (s clusterSimplifier) options(opts Options, cellSize float32) cluster.Options {
+	return cluster.Options{CellSize: cellSize, Representative: s.representative,
+		LockBoundary: opts.LockBoundary, BoundaryTolerance: float32(opts.BoundaryTolerance)}
+}
+
+func (s clusterSimplifier) simplifyAt(m mesh.Mesh, opts Options, resolution int) (cloudmesh.IndexedMesh, error) {
+	cellSize, err := cluster.CellSizeForResolution(m, resolution)
+	if err != nil {
+		return *cloudmesh.NewMesh(), err
+	}
+	return cluster.Simplify(m, s.options(opts, cellSize))
+}
+
+// simplifyToTarget bisects the resolution for the finest clustering with at most target triangles
+func (s clusterSimplifier) simplifyToTarget(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, error) {
+	target := opts.TargetFacets
+	best, err := s.simplifyAt(m, opts, 1)
+	if err != nil || best.GetNumFacets() > target {
+		return best, err
+	}
+	// grow the resolution until there are too many triangles or it stops mattering
+	lo, hi := 1, 2
+	for ; hi <= maxResolution; hi *= 2 {
+		out, err := s.simplifyAt(m, opts, hi)
+		if err != nil {
+			return out, err
+		}
+		if out.GetNumFacets() > target {
+			break
+		}
+		best, lo = out, hi
+		if out.GetNumFacets() == m.GetNumFacets() {
+			return best, nil
+		}
+	}
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		out, err := s.simplifyAt(m, opts, mid)
+		if err != nil {
+			return out, err
+		}
+		if out.GetNumFacets() > target {
+			hi = mid
+		} else {
+			best, lo = out, mid
+		}
+	}
+	return best, nil
+}
+
+// maxResolution bounds the search for a resolution
+const maxResolution = 1 << 14
+
+// vsaSimplifier approximates the mesh with planar proxies and meshes them (see package vsa).
+// It uses MaxError as the proxy error threshold, seeds about one proxy per two
+// triangles of TargetFacets, but never more than maxVSASeeds, and uses Seed.  VSA
+// then adds proxies until the error threshold is met, so TargetFacets is only a hint:
+// the output often has more triangles, which Report.TargetMissed tells.
+type vsaSimplifier struct{}
+
+// maxVSASeeds bounds the number of seeds of the vsa simplifier.  Every partition
+// measures every triangle against every proxy, so the seeds set the cost of a run;
+// beyond them VSA adds a proxy at the worst triangle of every iteration anyway.
+const maxVSASeeds = 32
+
+func (vsaSimplifier) Name() string { return "vsa" }
+
+func (s vsaSimplifier) Simplify(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, Report, error) {
+	start := time.Now()
+	vsaOpts := vsa.DefaultOptions()
+	if opts.MaxError > 0 {
+		vsaOpts.ErrorThreshold = float32(opts.MaxError)
+	}
+	if opts.TargetFacets > 1 {
+		vsaOpts.NumSeeds = int(opts.TargetFacets / 2)
+		if vsaOpts.NumSeeds > maxVSASeeds {
+			vsaOpts.NumSeeds = maxVSASeeds
+		}
+	}
+	vsaOpts.Seed = opts.Seed
+	result, err := vsa.Run(m, vsaOpts)
+	if err != nil {
+		return *cloudmesh.NewMesh(), Report{}, err
+	}
+	out, err := vsa.CreateMesh(m, result)
+	if err != nil {
+		return out, Report{}, fmt.Errorf("simplify: %v", err)
+	}
+	if err := CarryAttributes(m, &out); err != nil {
+		return out, Report{}, err
+	}
+	r, err := newReport(s.Name(), m, out, start, opts)
+	return out, r, err
+}
@@ -0,0 +1,187 @@
+// Package simplify gives the simplification algorithms of this module a common
+// interface, and keeps a registry of them by name so that they can be picked and
+// compared uniformly.
+package simplify
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// Options configures a simplification.  Every algorithm uses the options that make
+// sense for it and ignores the others.
+type Options struct {
+	// TargetFacets is the number of triangles to aim for
+	TargetFacets uint32 `json:"targetFacets,omitempty"`
+	// MaxError bounds the error of the simplification, in the algorithm's own metric
+	MaxError float64 `json:"maxError,omitempty"`
+	// CellSize is the edge length of the cells of grid based algorithms
+	CellSize float32 `json:"cellSize,omitempty"`
+	// Resolution is the number of cells along the longest side of the bounding box,
+	// used by grid based algorithms when CellSize is 0
+	Resolution int `json:"resolution,omitempty"`
+	// LockBoundary keeps the boundary of open meshes in place, and BoundaryTolerance
+	// is how far it may move with LockBoundary (see qem.Options)
+	LockBoundary      bool    `json:"lockBoundary,omitempty"`
+	BoundaryTolerance float64 `json:"boundaryTolerance,omitempty"`
+	// Seed for randomized algorithms.  0 picks a seed from the clock.
+	Seed int64 `json:"seed,omitempty"`
+	// Measure fills the distances between the input and the output in the Report
+	Measure bool `json:"measure,omitempty"`
+}
+
+// Report describes what a simplification did
+type Report struct {
+	Algorithm      string        `json:"algorithm"`
+	InputFacets    uint32        `json:"inputFacets"`
+	InputVertices  uint32        `json:"inputVertices"`
+	OutputFacets   uint32        `json:"outputFacets"`
+	OutputVertices uint32        `json:"outputVertices"`
+	Duration       time.Duration `json:"duration"`
+	// TargetMissed is set when the output has more triangles than Options.TargetFacets,
+	// e.g., because the algorithm stopped at its error threshold first
+	TargetMissed bool `json:"targetMissed,omitempty"`
+	// MaxDistance and MeanDistance approximate the Hausdorff distance between the
+	// input and the output (see mesh.HausdorffDistance).  They are only set with Options.Measure.
+	MaxDistance  float64 `json:"maxDistance,omitempty"`
+	MeanDistance float64 `json:"meanDistance,omitempty"`
+}
+
+// Simplifier is a mesh simplification algorithm
+type Simplifier interface {
+	// Name is the name the simplifier is registered under
+	Name() string
+	// Simplify returns the simplified mesh and a report of the simplification
+	Simplify(m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, Report, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Simplifier)
+)
+
+// Register makes a simplifier available by its name.  It panics if the name is
+// already taken, as registering twice is a programming error.
+func Register(s Simplifier) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if s == nil {
+		panic("simplify: Register of a nil Simplifier")
+	}
+	if _, dup := registry[s.Name()]; dup {
+		panic("simplify: Register called twice for " + s.Name())
+	}
+	registry[s.Name()] = s
+}
+
+// Lookup returns the simplifier registered under the name
+func Lookup(name string) (Simplifier, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	s, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("simplify: unknown algorithm %q", name)
+	}
+	return s, nil
+}
+
+// Names returns the sorted names of the registered simplifiers
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run simplifies the mesh with the simplifier registered under the name
+func Run(name string, m mesh.Mesh, opts Options) (cloudmesh.IndexedMesh, Report, error) {
+	s, err := Lookup(name)
+	if err != nil {
+		return *cloudmesh.NewMesh(), Report{}, err
+	}
+	return s.Simplify(m, opts)
+}
+
+// CarryAttributes gives the simplification out of m the attributes of m, when m is a
+// cloudmesh.IndexedMesh that has some.  Every vertex of out takes the vertex attributes
+// interpolated at the closest point of m, and every triangle the face attributes of the
+// triangle of m closest to its centroid, so that colors and materials survive while
+// texture coordinates blur across seams.
+func CarryAttributes(m mesh.Mesh, out *cloudmesh.IndexedMesh) error {
+	var in cloudmesh.IndexedMesh
+	switch im := m.(type) {
+	case cloudmesh.IndexedMesh:
+		in = im
+	case *cloudmesh.IndexedMesh:
+		in = *im
+	default:
+		return nil
+	}
+	if in.Attributes.Empty() || in.GetNumFacets() == 0 {
+		return nil
+	}
+	if err := in.Attributes.Check(in.GetNumVertices(), in.GetNumFacets()); err != nil {
+		return fmt.Errorf("simplify: %v", err)
+	}
+	grid, err := mesh.NewClosestPointGrid(in)
+	if err != nil {
+		return fmt.Errorf("simplify: %v", err)
+	}
+
+	numVertices := out.GetNumVertices()
+	corners := make([][3]uint32, numVertices)
+	weights := make([][3]float32, numVertices)
+	for v := uint32(0); v < numVertices; v++ {
+		p, _ := mesh.GetPoint64(out, v)
+		_, facet, _ := grid.Closest(p)
+		corners[v], _ = in.GetTriangle(facet)
+		tri, _ := mesh.GetTrianglePoints64(in, facet)
+		for i, w := range mesh.Barycentric(p, tri) {
+			weights[v][i] = float32(w)
+		}
+	}
+	faces := make([]uint32, out.GetNumFacets())
+	for t := range faces {
+		tri, err := mesh.GetTrianglePoints64(out, uint32(t))
+		if err != nil {
+			return fmt.Errorf("simplify: %v", err)
+		}
+		var centroid [3]float64
+		for i := 0; i < 3; i++ {
+			centroid[i] = (tri[0][i] + tri[1][i] + tri[2][i]) / 3
+		}
+		_, faces[t], _ = grid.Closest(centroid)
+	}
+	out.Attributes = in.Attributes.Resample(corners, weights, faces)
+	return nil
+}
+
+// newReport fills the report of a simplification that started at start
+func newReport(name string, in mesh.Mesh, out cloudmesh.IndexedMesh, start time.Time, opts Options) (Report, error) {
+	r := Report{
+		Algorithm:      name,
+		InputFacets:    in.GetNumFacets(),
+		InputVertices:  in.GetNumVertices(),
+		OutputFacets:   out.GetNumFacets(),
+		OutputVertices: out.GetNumVertices(),
+		Duration:       time.Since(start),
+		TargetMissed:   opts.TargetFacets > 0 && out.GetNumFacets() > opts.TargetFacets,
+	}
+	if opts.Measure && in.GetNumFacets() > 0 && out.GetNumFacets() > 0 {
+		var err error
+		r.MaxDistance, r.MeanDistance, err = mesh.HausdorffDistance(in, out)
+		if err != nil {
+			return r, fmt.Errorf("simplify: measuring %s: %v", name, err)
+		}
+	}
+	return r, nil
+}
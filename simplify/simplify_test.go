@@ -0,0 +1,132 @@
+package simplify
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func TestRegistry(t *testing.T) {
+	names := Names()
+	for _, expected := range []string{"cluster", "cluster-quadric", "qem", "vsa"} {
+		s, err := Lookup(expected)
+		if err != nil {
+			t.Errorf("Expected %v to be registered: %v (have %v)", expected, err, names)
+			continue
+		}
+		if s.Name() != expected {
+			t.Errorf("Expected %v to be registered under its name, got %v", expected, s.Name())
+		}
+	}
+	if _, err := Lookup("nope"); err == nil {
+		t.Errorf("Expected an error looking up an unknown algorithm")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering a name twice to panic")
+		}
+	}()
+	Register(qemSimplifier{})
+}
+
+func TestSideBySide(t *testing.T) {
+	sphere := shape.Sphere(2000, 100)
+	opts := Options{TargetFacets: 200, Resolution: 8, Seed: 1, Measure: true}
+	for _, name := range Names() {
+		out, report, err := Run(name, sphere, opts)
+		if err != nil {
+			t.Errorf("%v failed: %v", name, err)
+			continue
+		}
+		if report.Algorithm != name || report.InputFacets != sphere.GetNumFacets() ||
+			report.OutputFacets != out.GetNumFacets() || report.OutputVertices != out.GetNumVertices() {
+			t.Errorf("%v: the report does not match the meshes: %+v", name, report)
+		}
+		if report.TargetMissed != (out.GetNumFacets() > opts.TargetFacets) {
+			t.Errorf("%v: expected TargetMissed with more than %v triangles, got %+v", name, opts.TargetFacets, report)
+		}
+		if out.GetNumFacets() == 0 || out.GetNumFacets() > sphere.GetNumFacets() {
+			t.Errorf("%v: unexpected number of triangles %v", name, out.GetNumFacets())
+		}
+		// the sphere has a radius of 100
+		if report.MaxDistance <= 0 || report.MaxDistance > 30 || report.MeanDistance > report.MaxDistance {
+			t.Errorf("%v: unexpected distances %v and %v", name, report.MaxDistance, report.MeanDistance)
+		}
+	}
+}
+
+func TestQEMTarget(t *testing.T) {
+	out, report, err := Run("qem", shape.Sphere(2000, 100), Options{TargetFacets: 100})
+	if err != nil {
+		t.Fatalf("qem failed: %v", err)
+	}
+	if out.GetNumFacets() != 100 || report.MaxDistance != 0 || report.TargetMissed {
+		t.Errorf("Expected 100 triangles and no measurement, got %v and %+v", out.GetNumFacets(), report)
+	}
+	// vsa stops at its error threshold, far above the target on a sphere
+	out, report, err = Run("vsa", shape.Sphere(800, 100), Options{TargetFacets: 100, Seed: 1})
+	if err != nil || out.GetNumFacets() <= 100 || !report.TargetMissed {
+		t.Errorf("Expected vsa to report that it missed the target, got %+v (%v)", report, err)
+	}
+	if _, _, err := Run("cluster", shape.Sphere(200, 100), Options{}); err == nil {
+		t.Errorf("Expected cluster to need a cell size or a resolution")
+	}
+}
+
+func TestCarryAttributes(t *testing.T) {
+	// normals pointing outward, and a material of 1 on the top half and 2 on the bottom one
+	sphere := shape.Sphere(500, 100)
+	for v := 0; v < len(sphere.Vertices); v += 3 {
+		p := sphere.Vertices[v : v+3]
+		sphere.Attributes.Normals = append(sphere.Attributes.Normals, p[0]/100, p[1]/100, p[2]/100)
+	}
+	material := func(m cloudmesh.IndexedMesh, t uint32) (uint32, float32) {
+		tri, _ := m.GetTriangle(t)
+		z := (m.Vertices[3*tri[0]+2] + m.Vertices[3*tri[1]+2] + m.Vertices[3*tri[2]+2]) / 3
+		if z > 0 {
+			return 1, z
+		}
+		return 2, z
+	}
+	for t := uint32(0); t < sphere.GetNumFacets(); t++ {
+		m, _ := material(sphere, t)
+		sphere.Attributes.Materials = append(sphere.Attributes.Materials, m)
+	}
+
+	opts := Options{TargetFacets: 100, Resolution: 8, Seed: 1}
+	for _, name := range Names() {
+		out, _, err := Run(name, &sphere, opts)
+		if err != nil {
+			t.Errorf("%v failed: %v", name, err)
+			continue
+		}
+		if err := out.Attributes.Check(out.GetNumVertices(), out.GetNumFacets()); err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		for v := uint32(0); v < out.GetNumVertices(); v++ {
+			p, _ := out.GetPointVec3(v)
+			n := out.Attributes.Normals[3*v : 3*v+3]
+			if cos := p.Normalize().Dot([3]float32{n[0], n[1], n[2]}); cos < .9 {
+				t.Errorf("%v: the normal of vertex %d is off by %v", name, v, math.Acos(float64(cos)))
+				break
+			}
+		}
+		for f := uint32(0); f < out.GetNumFacets(); f++ {
+			// away from the equator the material cannot be ambiguous
+			if expected, z := material(out, f); math.Abs(float64(z)) > 20 && out.Attributes.Materials[f] != expected {
+				t.Errorf("%v: expected triangle %d at %v to have the material %d, got %d", name, f, z, expected, out.Attributes.Materials[f])
+				break
+			}
+		}
+	}
+
+	// a mesh without attributes gets none
+	out, _, err := Run("qem", shape.Sphere(200, 100), Options{TargetFacets: 50})
+	if err != nil || !out.Attributes.Empty() {
+		t.Errorf("Expected no attributes, got %v (%v)", out.Attributes, err)
+	}
+}
@@ -77,6 +77,39 @@ func LoadSTLFile(path string) (mesh cloudmesh.IndexedMesh, err error) {
 	return CreateMesh(bufReader, numTriangles)
 }
 
+// LoadSTLFileWelded loads an STL file and merges its vertices within epsilon of
+// each other (see cloudmesh.IndexedMesh.Weld).  It returns the number of vertices merged.
+func LoadSTLFileWelded(path string, epsilon float32) (cloudmesh.IndexedMesh, int, error) {
+	m, err := LoadSTLFile(path)
+	if err != nil {
+		return m, 0, err
+	}
+	welded, numMerged := m.Weld(epsilon)
+	return welded, numMerged, nil
+}
+
+// LoadSTLFile64 loads an STL file into a double precision mesh.  STL files hold
+// float32 coordinates, which convert exactly.
+func LoadSTLFile64(path string) (cloudmesh.IndexedMesh64, error) {
+	m, err := LoadSTLFile(path)
+	if err != nil {
+		return *cloudmesh.NewMesh64(), err
+	}
+	return m.ToIndexedMesh64(), nil
+}
+
+// LoadSTLFileRecentered loads an STL file, moves it so that the center of its
+// bounding box is at the origin, where float32 is precise, and returns that center
+// in double precision: a vertex p of the mesh is at p + center in the file.
+func LoadSTLFileRecentered(path string) (cloudmesh.IndexedMesh, [3]float64, error) {
+	m, err := LoadSTLFile64(path)
+	if err != nil {
+		return emptyMesh(), [3]float64{}, err
+	}
+	recentered, center := m.Recenter()
+	return recentered.ToIndexedMesh(), center, nil
+}
+
 // CreateMesh returns a mesh given a buffer reader
 //
 func CreateMesh(file *bufio.Reader, numTris uint32) (m cloudmesh.IndexedMesh, err error) {
@@ -139,10 +172,35 @@ func CreateMesh(file *bufio.Reader, numTris uint32) (m cloudmesh.IndexedMesh, er
 //WriteSTLMesh Mesh version of the above
 func WriteSTLMeshName(m mesh.Mesh,  name string){
 
+	numTris := m.GetNumFacets()
+	fmt.Printf("Writing STL -> numTris: %d \n", numTris)
+	buf, err := encodeSTL(m)
+	if err != nil {
+		log.Fatalf("WriteMesh: %s", err)
+	}
+
+	ioutil.WriteFile(name, buf, 0644)
+
+}
+
+// SaveSTLFile writes a mesh to a binary STL file.  Unlike WriteSTLMeshName it
+// returns the errors, including those of writing the file.
+func SaveSTLFile(m mesh.Mesh, path string) error {
+	buf, err := encodeSTL(m)
+	if err != nil {
+		return fmt.Errorf("stl.SaveSTLFile: %v", err)
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		return fmt.Errorf("stl.SaveSTLFile: %v", err)
+	}
+	return nil
+}
+
+// encodeSTL returns the binary STL file of a mesh
+func encodeSTL(m mesh.Mesh) ([]byte, error) {
 	var header [80]byte
 
 	numTris := m.GetNumFacets()
-	fmt.Printf("Writing STL -> numTris: %d \n", numTris)
 	normalGarbage := make([]byte, 12)
 	garbage := make([]byte, 2)
 
@@ -153,21 +211,18 @@ func WriteSTLMeshName(m mesh.Mesh,  name string){
 		binary.Write(buf, binary.LittleEndian, normalGarbage)
 		triVertices, err := m.GetVertices(tri)
 		if err != nil {
-			log.Fatalf("WriteMesh GetVertices: %s", err)
+			return nil, err
 		}
 		for p := uint32(0); p < 3; p++ {
 			physicalPoint, err := m.GetPoint(triVertices[p])
 			if err != nil {
-				log.Fatalf("WriteMesh: %s", err)
+				return nil, err
 			}
 			binary.Write(buf, binary.LittleEndian, physicalPoint)
 		}
 		binary.Write(buf, binary.LittleEndian, garbage)
-
 	}
-
-	ioutil.WriteFile(name, buf.Bytes(), 0644)
-
+	return buf.Bytes(), nil
 }
 
 //WriteSTLMesh Mesh version of the above
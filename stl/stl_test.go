@@ -4,6 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
@@ -47,7 +51,7 @@ func TestCreateMesh(t *testing.T) {
 	myMesh, err := CreateMesh(file, 5)
 
 	if err != nil {
-		t.Errorf("Error creating mesh: ", err)
+		t.Errorf("Error creating mesh: %v", err)
 	}
 
 	for i := range myMesh.Indices {
@@ -62,6 +66,99 @@ func TestCreateMesh(t *testing.T) {
 	}
 }
 
+func TestLoadSTLFileWelded(t *testing.T) {
+	// two triangles whose shared corners are 1 ulp apart
+	next := math.Nextafter32
+	m := cloudmesh.IndexedMesh{
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, next(0, 1), 0, 0, next(1, 2), 1, 0, 0, 1, 0},
+		Indices:  []uint32{0, 1, 2, 3, 4, 5},
+	}
+	dir, err := ioutil.TempDir("", "stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "split.stl")
+	WriteSTLMeshName(m, path)
+
+	loaded, err := LoadSTLFile(path)
+	if err != nil || loaded.GetNumVertices() != 6 {
+		t.Fatalf("Expected 6 vertices without welding, got %d: %v", loaded.GetNumVertices(), err)
+	}
+	welded, numMerged, err := LoadSTLFileWelded(path, 1e-6)
+	if err != nil {
+		t.Fatalf("LoadSTLFileWelded failed: %v", err)
+	}
+	if numMerged != 2 || welded.GetNumVertices() != 4 || welded.GetNumFacets() != 2 {
+		t.Errorf("Expected 2 vertices to be merged, got %d merged and %d vertices", numMerged, welded.GetNumVertices())
+	}
+	if _, _, err := LoadSTLFileWelded(filepath.Join(dir, "missing.stl"), 1e-6); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestLoadSTLFileRecentered(t *testing.T) {
+	// a triangle 1e5 units from the origin, with edges of a hundredth
+	m := cloudmesh.IndexedMesh{Vertices: []float32{1e5, 1e5, 0, 1e5 + .01, 1e5, 0, 1e5, 1e5 + .01, 0}, Indices: []uint32{0, 1, 2}}
+	dir, err := ioutil.TempDir("", "stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "far.stl")
+	WriteSTLMeshName(m, path)
+
+	far, err := LoadSTLFile64(path)
+	if err != nil || far.GetNumFacets() != 1 {
+		t.Fatalf("Expected a triangle, got %d: %v", far.GetNumFacets(), err)
+	}
+	recentered, center, err := LoadSTLFileRecentered(path)
+	if err != nil {
+		t.Fatalf("LoadSTLFileRecentered failed: %v", err)
+	}
+	for v := uint32(0); v < 3; v++ {
+		p, _ := far.GetPoint64(v)
+		q, _ := recentered.GetPoint(v)
+		for i := 0; i < 3; i++ {
+			if float64(q[i])+center[i] != p[i] {
+				t.Errorf("Expected vertex %d at %v, got %v + %v", v, p, q, center)
+			}
+		}
+	}
+	if _, _, err := LoadSTLFileRecentered(filepath.Join(dir, "missing.stl")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestSaveSTLFile(t *testing.T) {
+	m := cloudmesh.IndexedMesh{Vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}, Indices: []uint32{0, 1, 2}}
+	dir, err := ioutil.TempDir("", "stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "triangle.stl")
+	if err := SaveSTLFile(m, path); err != nil {
+		t.Fatalf("SaveSTLFile failed: %v", err)
+	}
+	loaded, err := LoadSTLFile(path)
+	if err != nil || loaded.GetNumFacets() != 1 {
+		t.Errorf("Expected to load 1 triangle, got %d: %v", loaded.GetNumFacets(), err)
+	}
+	if err := SaveSTLFile(m, filepath.Join(dir, "missing", "triangle.stl")); err == nil {
+		t.Error("Expected an error writing to a missing directory")
+	}
+	bad := cloudmesh.IndexedMesh{Vertices: []float32{0, 0, 0}, Indices: []uint32{0, 0, 5}}
+	if err := SaveSTLFile(bad, path); err == nil {
+		t.Error("Expected an error for an out of bounds index")
+	}
+	// an index one past the last vertex is out of bounds as well
+	bad.Indices = []uint32{0, 0, 1}
+	if err := SaveSTLFile(bad, path); err == nil {
+		t.Error("Expected an error for an index one past the last vertex")
+	}
+}
+
 func TestEmptyMesh(t *testing.T) {
 	retVal := emptyMesh()
 	if len(retVal.Vertices) != 0 {
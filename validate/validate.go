@@ -0,0 +1,238 @@
+// Package validate inspects a mesh and reports its size, its topology and what is
+// wrong with it, e.g., before a mesh is imported.
+package validate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// Report is the result of Validate.  The lists hold triangle or vertex indices of the
+// input mesh.  Triangles that are out of range, degenerate or duplicates are left
+// out of the topology (edges, boundary, components, defects and Euler characteristic).
+type Report struct {
+	NumVertices  uint32 `json:"numVertices"`
+	NumTriangles uint32 `json:"numTriangles"`
+	NumEdges     int    `json:"numEdges"`
+
+	NumBoundaryEdges    int `json:"numBoundaryEdges"`
+	NumBoundaryLoops    int `json:"numBoundaryLoops"`
+	NumComponents       int `json:"numComponents"`
+	NonManifoldEdges    int `json:"nonManifoldEdges"`
+	NonManifoldVertices int `json:"nonManifoldVertices"`
+	// InconsistentEdges are the edges whose two triangles run them the same way
+	InconsistentEdges int `json:"inconsistentEdges"`
+
+	// OutOfRangeTriangles reference a vertex that does not exist
+	OutOfRangeTriangles []uint32 `json:"outOfRangeTriangles"`
+	// DegenerateTriangles use the same vertex more than once
+	DegenerateTriangles []uint32 `json:"degenerateTriangles"`
+	// ZeroAreaTriangles have three distinct vertices but no area, relative to their size (see zeroAreaTolerance)
+	ZeroAreaTriangles []uint32 `json:"zeroAreaTriangles"`
+	// DuplicateTriangles use the same vertices as an earlier triangle, in any order
+	DuplicateTriangles   []uint32 `json:"duplicateTriangles"`
+	UnreferencedVertices []uint32 `json:"unreferencedVertices"`
+	// InvalidVertices have a NaN or infinite coordinate
+	InvalidVertices []uint32      `json:"invalidVertices"`
+	Defects         []mesh.Defect `json:"defects"`
+
+	// Oriented tells whether every edge is run both ways by its two triangles
+	Oriented bool `json:"oriented"`
+	// Watertight tells whether the mesh is a closed, oriented manifold
+	Watertight          bool `json:"watertight"`
+	EulerCharacteristic int  `json:"eulerCharacteristic"`
+	// Genus is the total genus of the components of a watertight mesh, and -1 otherwise
+	Genus int `json:"genus"`
+}
+
+// Validate inspects the mesh
+func Validate(m mesh.Mesh) Report {
+	numVertices := m.GetNumVertices()
+	r := Report{
+		NumVertices:          numVertices,
+		NumTriangles:         m.GetNumFacets(),
+		OutOfRangeTriangles:  make([]uint32, 0),
+		DegenerateTriangles:  make([]uint32, 0),
+		ZeroAreaTriangles:    make([]uint32, 0),
+		DuplicateTriangles:   make([]uint32, 0),
+		UnreferencedVertices: make([]uint32, 0),
+		InvalidVertices:      make([]uint32, 0),
+		Defects:              make([]mesh.Defect, 0),
+		Genus:                -1,
+	}
+
+	// the triangles that make the topology, in a mesh of their own
+	clean := cloudmesh.IndexedMesh{Indices: make([]uint32, 0, 3*r.NumTriangles), Vertices: make([]float32, 0, 3*numVertices)}
+	invalid := make([]bool, numVertices)
+	for v := uint32(0); v < numVertices; v++ {
+		p, _ := m.GetPoint(v)
+		for _, c := range p {
+			if math.IsNaN(float64(c)) || math.IsInf(float64(c), 0) {
+				r.InvalidVertices = append(r.InvalidVertices, v)
+				invalid[v] = true
+				break
+			}
+		}
+		clean.Vertices = append(clean.Vertices, p...)
+	}
+	cleanToInput := make([]uint32, 0, r.NumTriangles)
+	referenced := make([]bool, numVertices)
+	seen := make(map[[3]uint32]bool, r.NumTriangles)
+	for t := uint32(0); t < r.NumTriangles; t++ {
+		vertices, _ := m.GetVertices(t)
+		if vertices[0] >= numVertices || vertices[1] >= numVertices || vertices[2] >= numVertices {
+			r.OutOfRangeTriangles = append(r.OutOfRangeTriangles, t)
+			continue
+		}
+		for _, v := range vertices {
+			referenced[v] = true
+		}
+		if vertices[0] == vertices[1] || vertices[1] == vertices[2] || vertices[2] == vertices[0] {
+			r.DegenerateTriangles = append(r.DegenerateTriangles, t)
+			continue
+		}
+		key := [3]uint32{vertices[0], vertices[1], vertices[2]}
+		sort.Slice(key[:], func(i, j int) bool { return key[i] < key[j] })
+		if seen[key] {
+			r.DuplicateTriangles = append(r.DuplicateTriangles, t)
+			continue
+		}
+		seen[key] = true
+		unknownArea := invalid[vertices[0]] || invalid[vertices[1]] || invalid[vertices[2]]
+		if !unknownArea && zeroArea(m, t) {
+			r.ZeroAreaTriangles = append(r.ZeroAreaTriangles, t)
+		}
+		clean.Indices = append(clean.Indices, vertices...)
+		cleanToInput = append(cleanToInput, t)
+	}
+	for v, ok := range referenced {
+		if !ok {
+			r.UnreferencedVertices = append(r.UnreferencedVertices, uint32(v))
+		}
+	}
+
+	neighborhood := mesh.CreateNeighborhood(clean)
+	for _, d := range neighborhood.GetDefects() {
+		tris := make([]uint32, len(d.Triangles))
+		for i, t := range d.Triangles {
+			tris[i] = cleanToInput[t]
+		}
+		r.Defects = append(r.Defects, mesh.Defect{Kind: d.Kind, Vertices: d.Vertices, Triangles: tris})
+		switch d.Kind {
+		case mesh.NonManifoldEdge:
+			r.NonManifoldEdges++
+		case mesh.NonManifoldVertex:
+			r.NonManifoldVertices++
+		case mesh.InconsistentWinding:
+			r.InconsistentEdges++
+		}
+	}
+	edges, _ := mesh.FindBoundaryEdges(clean, neighborhood)
+	r.NumBoundaryEdges = len(edges)
+	r.NumBoundaryLoops = len(mesh.FindBoundaryLoops(edges))
+	r.NumComponents = countComponents(clean, neighborhood)
+
+	// the Euler characteristic of the vertices, edges and triangles of the topology
+	undirected := make(map[[2]uint32]bool, 3*len(cleanToInput)/2)
+	used := make(map[uint32]bool, numVertices)
+	for c := 0; c < len(clean.Indices); c += 3 {
+		for i := 0; i < 3; i++ {
+			a, b := clean.Indices[c+i], clean.Indices[c+(i+1)%3]
+			used[a] = true
+			if a > b {
+				a, b = b, a
+			}
+			undirected[[2]uint32{a, b}] = true
+		}
+	}
+	r.NumEdges = len(undirected)
+	r.EulerCharacteristic = len(used) - len(undirected) + len(cleanToInput)
+
+	r.Oriented = r.InconsistentEdges == 0
+	r.Watertight = len(cleanToInput) > 0 && len(r.Defects) == 0 && r.NumBoundaryEdges == 0 &&
+		len(r.OutOfRangeTriangles) == 0 && len(r.DegenerateTriangles) == 0 && len(r.DuplicateTriangles) == 0
+	if r.Watertight {
+		// each closed orientable component has a characteristic of 2 - 2 genus
+		r.Genus = (2*r.NumComponents - r.EulerCharacteristic) / 2
+	}
+	return r
+}
+
+// countComponents returns the number of edge connected components of the triangles
+func countComponents(m mesh.Mesh, neighborhood mesh.MeshNeighborhood) int {
+	numTris := m.GetNumFacets()
+	component := make([]int, numTris)
+	for t := range component {
+		component[t] = -1
+	}
+	numComponents := 0
+	stack := make([]uint32, 0)
+	for t := uint32(0); t < numTris; t++ {
+		if component[t] >= 0 {
+			continue
+		}
+		component[t] = numComponents
+		stack = append(stack[:0], t)
+		for len(stack) > 0 {
+			curr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			neighbors, _ := neighborhood.GetTriangleNeighborsOfTriangle(curr)
+			for _, n := range neighbors {
+				if component[n] < 0 {
+					component[n] = numComponents
+					stack = append(stack, n)
+				}
+			}
+		}
+		numComponents++
+	}
+	return numComponents
+}
+
+// Problems lists what is wrong with the mesh, i.e., everything but an open boundary
+// and several components.  It is empty when the mesh can be used as it is.
+func (r Report) Problems() []string {
+	problems := make([]string, 0)
+	add := func(n int, what string) {
+		if n > 0 {
+			problems = append(problems, fmt.Sprintf("%d %s", n, what))
+		}
+	}
+	add(len(r.OutOfRangeTriangles), "triangles reference vertices out of range")
+	add(len(r.InvalidVertices), "vertices have NaN or infinite coordinates")
+	add(len(r.DegenerateTriangles), "triangles use a vertex more than once")
+	add(len(r.ZeroAreaTriangles), "triangles have no area")
+	add(len(r.DuplicateTriangles), "triangles are duplicates")
+	add(len(r.UnreferencedVertices), "vertices are not used by any triangle")
+	add(r.NonManifoldEdges, "edges are shared by more than two triangles")
+	add(r.NonManifoldVertices, "vertices join several fans of triangles")
+	add(r.InconsistentEdges, "edges have inconsistent winding")
+	return problems
+}
+
+// Valid tells whether the report has no problems
+func (r Report) Valid() bool {
+	return len(r.Problems()) == 0
+}
+
+// zeroAreaTolerance is the sine of the angle between the edges of a triangle below
+// which the triangle has no area.  Positions are float32, so collinear points land
+// about 1e-7 off their line.
+const zeroAreaTolerance = 1e-6
+
+// zeroArea tells if a triangle has no area, i.e., |u x v| <= zeroAreaTolerance*|u|*|v|
+// for the edges u and v from its first corner.  The test is in double precision and
+// relative to the edges, so that tiny triangles are not flagged for being tiny.
+func zeroArea(m mesh.Mesh, t uint32) bool {
+	p, err := mesh.GetTrianglePoints64(m, t)
+	if err != nil {
+		return false
+	}
+	u, v := auxmath.Vec3d(p[1]).Sub(p[0]), auxmath.Vec3d(p[2]).Sub(p[0])
+	return u.Cross(v).Magnitude() <= zeroAreaTolerance*u.Magnitude()*v.Magnitude()
+}
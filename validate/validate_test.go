@@ -0,0 +1,156 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// createTorus creates a torus of n x n quads, each split in two triangles
+func createTorus(n int) cloudmesh.IndexedMesh {
+	m := cloudmesh.IndexedMesh{Indices: make([]uint32, 0), Vertices: make([]float32, 0)}
+	for i := 0; i < n; i++ {
+		u := 2 * math.Pi * float64(i) / float64(n)
+		for j := 0; j < n; j++ {
+			v := 2 * math.Pi * float64(j) / float64(n)
+			r := 3 + math.Cos(v)
+			m.Vertices = append(m.Vertices, float32(r*math.Cos(u)), float32(r*math.Sin(u)), float32(math.Sin(v)))
+		}
+	}
+	index := func(i, j int) uint32 { return uint32((i%n)*n + j%n) }
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			a, b, c, d := index(i, j), index(i+1, j), index(i+1, j+1), index(i, j+1)
+			m.Indices = append(m.Indices, a, b, c, a, c, d)
+		}
+	}
+	return m
+}
+
+func TestClosedMeshes(t *testing.T) {
+	cube := shape.BasicCube()
+	twoCubes := cloudmesh.IndexedMesh{Indices: append([]uint32{}, cube.Indices...), Vertices: append([]float32{}, cube.Vertices...)}
+	for _, i := range cube.Indices {
+		twoCubes.Indices = append(twoCubes.Indices, i+cube.GetNumVertices())
+	}
+	for i, c := range cube.Vertices {
+		if i%3 == 0 {
+			c += 10
+		}
+		twoCubes.Vertices = append(twoCubes.Vertices, c)
+	}
+	cases := []struct {
+		name                     string
+		m                        cloudmesh.IndexedMesh
+		components, euler, genus int
+	}{
+		{"cube", cube, 1, 2, 0},
+		{"two cubes", twoCubes, 2, 4, 0},
+		{"torus", createTorus(8), 1, 0, 1},
+		{"sphere", shape.Octahedron(800), 1, 2, 0},
+	}
+	for _, c := range cases {
+		r := Validate(c.m)
+		if !r.Valid() {
+			t.Errorf("%s: expected no problems, got %v", c.name, r.Problems())
+		}
+		if !r.Watertight || !r.Oriented || r.NumBoundaryEdges != 0 || r.NumBoundaryLoops != 0 {
+			t.Errorf("%s: expected a watertight mesh, got %+v", c.name, r)
+		}
+		if r.NumComponents != c.components || r.EulerCharacteristic != c.euler || r.Genus != c.genus {
+			t.Errorf("%s: expected %d components, Euler characteristic %d and genus %d, got %d, %d and %d",
+				c.name, c.components, c.euler, c.genus, r.NumComponents, r.EulerCharacteristic, r.Genus)
+		}
+		if r.NumEdges != 3*int(c.m.GetNumFacets())/2 {
+			t.Errorf("%s: expected %d edges, got %d", c.name, 3*c.m.GetNumFacets()/2, r.NumEdges)
+		}
+	}
+}
+
+func TestOpenMesh(t *testing.T) {
+	r := Validate(shape.Grid(4, 0))
+	if !r.Valid() || r.Watertight || r.Genus != -1 {
+		t.Errorf("Expected a valid mesh that is not watertight, got %+v", r)
+	}
+	if r.NumBoundaryEdges != 16 || r.NumBoundaryLoops != 1 || r.EulerCharacteristic != 1 {
+		t.Errorf("Expected 16 boundary edges in a loop and an Euler characteristic of 1, got %d, %d and %d",
+			r.NumBoundaryEdges, r.NumBoundaryLoops, r.EulerCharacteristic)
+	}
+}
+
+func TestProblems(t *testing.T) {
+	m := shape.Grid(2, 0)
+	nan := float32(math.NaN())
+	// 9: unreferenced, 10: NaN, 11: on the line from 0 to 1
+	m.Vertices = append(m.Vertices, 5, 5, 5, nan, 0, 0, .5, 0, 0)
+	m.Indices = append(m.Indices,
+		0, 1, 12, // out of range
+		0, 0, 1, // degenerate
+		4, 0, 1, // duplicate of the first triangle
+		0, 11, 1, // zero area
+		2, 1, 10, // references the NaN vertex
+	)
+	r := Validate(m)
+	expected := map[string][]uint32{
+		"out of range": {8}, "degenerate": {9}, "duplicate": {10}, "zero area": {11},
+		"unreferenced": {9}, "invalid": {10},
+	}
+	got := map[string][]uint32{
+		"out of range": r.OutOfRangeTriangles, "degenerate": r.DegenerateTriangles, "duplicate": r.DuplicateTriangles,
+		"zero area": r.ZeroAreaTriangles, "unreferenced": r.UnreferencedVertices, "invalid": r.InvalidVertices,
+	}
+	for what, e := range expected {
+		if fmt.Sprint(got[what]) != fmt.Sprint(e) {
+			t.Errorf("Expected the %s list %v, got %v", what, e, got[what])
+		}
+	}
+	if r.Valid() || r.Watertight || len(r.Problems()) != 6 {
+		t.Errorf("Expected 6 problems, got %v", r.Problems())
+	}
+}
+
+func TestZeroArea(t *testing.T) {
+	m := cloudmesh.IndexedMesh{Vertices: []float32{
+		0, 0, 0, 1, 0, 0, -1, 0, 0, // collinear, the first corner in the middle
+		0, 0, 0, 1e-4, 0, 0, 0, 1e-4, 0, // tiny but well shaped
+		5000, 5000, 5000, 5001, 5000, 5000, 5000, 5001, 5000, // far from the origin
+	}, Indices: []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8}}
+	r := Validate(m)
+	if fmt.Sprint(r.ZeroAreaTriangles) != "[0]" {
+		t.Errorf("Expected only the collinear triangle to have no area, got %v", r.ZeroAreaTriangles)
+	}
+}
+
+func TestDefects(t *testing.T) {
+	// three triangles on the edge (0, 1) of a grid, then a flipped triangle on its other side
+	m := shape.Grid(1, 0)
+	m.Vertices = append(m.Vertices, 0, 0, 1, 1, -1, 0)
+	m.Indices = append(m.Indices, 1, 0, 4, 1, 0, 5)
+	r := Validate(m)
+	if r.NonManifoldEdges != 1 || r.Valid() || r.Watertight {
+		t.Errorf("Expected a non-manifold edge, got %+v", r)
+	}
+
+	m = shape.Grid(1, 0)
+	m.Vertices = append(m.Vertices, 1, -1, 0)
+	m.Indices = append(m.Indices, 0, 1, 4)
+	r = Validate(m)
+	if r.InconsistentEdges != 1 || r.Oriented || r.Valid() {
+		t.Errorf("Expected an edge with inconsistent winding, got %+v", r)
+	}
+	if len(r.Defects) != 1 || fmt.Sprint(r.Defects[0].Triangles) != "[0 2]" {
+		t.Errorf("Expected the defect to reference the input triangles 0 and 2, got %v", r.Defects)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Error marshalling the report: %v", err)
+	}
+	if !strings.Contains(string(data), `"kind":"inconsistent winding"`) || !strings.Contains(string(data), `"oriented":false`) {
+		t.Errorf("Expected the defect by name in the JSON, got %s", data)
+	}
+}
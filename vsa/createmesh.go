@@ -4,52 +4,61 @@ import (
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
 	"fmt"
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
-	"math"
 	"errors"
 )
 
 //TODO: add "edge extraction" (from the paper), or determine if it's unnecessary
 
 type proxyVertex struct{
-	proxies [] plane
+	proxies [] int32 //IDs in the proxy table
 	meshIndex uint32
 }
 
 type vsaPolygon struct{
-	proxy* plane
+	proxy int32
 	vertices []proxyVertex
 }
 
 //TODO: consider passing the plane by ref (pointer)
 func projectPointOntoPlane(point []float32, pl plane) []float32 {
 	//assumes the normal is a unit vector
+	//the proxy point is in double precision, so the distance is too
 
-	v,_ := auxmath.Subtract(point, pl.point)
-	pointDist,_ := auxmath.Dot(v,pl.normal)
-	pointDist = float32(math.Abs(float64(pointDist)))
-	nScale := auxmath.Scale(pl.normal, -pointDist)
-	retVal,_:= auxmath.Subtract(point,nScale)
+	pointDist := 0.0
+	for i := 0; i < 3; i++ {
+		pointDist += (float64(point[i]) - pl.point[i]) * float64(pl.normal[i])
+	}
+	retVal := make([]float32, 3)
+	for i := 0; i < 3; i++ {
+		retVal[i] = float32(float64(point[i]) - pointDist*float64(pl.normal[i]))
+	}
 	return retVal
 }
 
 /*Returns the position of a "proxy vertex," which is a vertex of the mesh
  that belonging to 3 or more proxies (or technically 2 or more in regions with a mesh boundary).
  */
-func proxyVertexPosition(m mesh.Mesh, p proxyVertex) (retVal []float32, err error){
+func proxyVertexPosition(m mesh.Mesh, proxies []plane, p proxyVertex) (retVal []float32, err error){
 	//Project this mesh vertex onto each of its proxy planes and take the average
+	if p.meshIndex >= m.GetNumVertices(){
+		return make([]float32, 0, 3), fmt.Errorf("proxyVertexPosition: bad input mesh index")
+	}
 	meshPos,err := m.GetPoint(p.meshIndex)
 	if err != nil{
 		return make([]float32, 0, 3), fmt.Errorf("proxyVertexPosition: bad input mesh index")
 	}
+	if len(p.proxies) == 0{
+		return meshPos,nil
+	}
 
-	retVal = make([]float32, 0, 3)
+	retVal = make([]float32, 3)
 	for i:= range p.proxies{
-		proxy := p.proxies[i]
+		proxy := proxies[p.proxies[i]]
 		proj := projectPointOntoPlane(meshPos,proxy)
 		retVal,_ = auxmath.Add(retVal, proj)
 	}
 
-	retVal = auxmath.Scale(retVal,float32(len(p.proxies)) )
+	retVal = auxmath.Scale(retVal,1/float32(len(p.proxies)) )
 
 	return retVal,nil
 }
@@ -60,28 +69,32 @@ func segmentOneFace() {
 
 type anchorVertex struct{
 	index uint32 //index in the mesh
-	proxyPlanes map[*plane]bool
+	proxyPlanes map[int32]bool //IDs of the proxies that meet at this vertex
 }
 
-/*Computes the anchor vertices via a coloring algorithm.*/
-func vsaGetAnchorVertices(pErrors [] pError, m mesh.Mesh) ([]anchorVertex, error) {
+/*Computes the anchor vertices via a coloring algorithm.
+ labels holds the proxy ID of every triangle in the mesh.*/
+func vsaGetAnchorVertices(labels [] int32, m mesh.Mesh) ([]anchorVertex, error) {
 
 	coloredTris := make([]bool,m.GetNumFacets())
 
 	neighborhood := mesh.CreateNeighborhood(m)
 
-	//borderTris := make([]pError,0)
-	borderTris := make(map[pError]bool)
+	if len(labels) != int(m.GetNumFacets()){
+		return make([]anchorVertex,0),errors.New("mesh needs to be reindexed before it can be used in vsa")
+	}
+
+	borderTris := make(map[uint32]bool)
 	allColored := false
 	debugNumProxyGroups := 0
 	for !allColored{
 		//seed the next proxyGroup with the first uncolored tri
 		allColored = true;
-		proxyGroup := make([]pError,0)
+		proxyGroup := make([]uint32,0)
 		for i := range coloredTris{
 			if !coloredTris[i]{
 				coloredTris[i] = true
-				proxyGroup = append(proxyGroup, pErrors[i])
+				proxyGroup = append(proxyGroup, uint32(i))
 				allColored = false
 				break;
 			}
@@ -95,31 +108,23 @@ func vsaGetAnchorVertices(pErrors [] pError, m mesh.Mesh) ([]anchorVertex, error
 			currTri := proxyGroup[0]
 			proxyGroup = proxyGroup[1:]
 
-			neighbors,_ := neighborhood.GetTriangleNeighborsOfTriangle(currTri.trindex)
+			neighbors,_ := neighborhood.GetTriangleNeighborsOfTriangle(currTri)
 			for n := range neighbors{
 				neighb := neighbors[n]
 				//get the proxy associated with this triangle
-				//can we assume that the mesh is indexed in such a way that "neighb"
-				// can used as an index into "pErrors"?  For now it seems that we can assume this since
-				// vsa.initialize has this assumption (which it probably shouldn't; a one-time scan of the
-				//mesh needs to be done for correctness)
-				if neighb > uint32(len(pErrors)){
-					return make([]anchorVertex,0),errors.New("mesh needs to be reindexed before it can be used in vsa")
-				}
-				neighbProxy := pErrors[neighb]
-				if neighbProxy.trindex != neighb{
+				//the labels are indexed by triangle, so "neighb" can be used as an index into "labels"
+				if neighb >= uint32(len(labels)){
 					return make([]anchorVertex,0),errors.New("mesh needs to be reindexed before it can be used in vsa")
 				}
 
-				if neighbProxy.p != currTri.p {
-					//borderTris = append(borderTris, currTri)
+				if labels[neighb] != labels[currTri] {
 					borderTris[currTri] = true
 					continue
 					//don't worry about neighbProxy; this will be added later
 				}else{ //triangles are part of the same proxy group
 					if !coloredTris[neighb]{
 						coloredTris[neighb] = true;
-						proxyGroup = append(proxyGroup,neighbProxy)
+						proxyGroup = append(proxyGroup,neighb)
 					}
 
 				}
@@ -131,9 +136,8 @@ func vsaGetAnchorVertices(pErrors [] pError, m mesh.Mesh) ([]anchorVertex, error
 	//for each proxy group
 
 	borderVertexDegrees := make(map[uint32]anchorVertex)
-	for b,_ := range borderTris{
+	for bIndex := range borderTris{
 
-		bIndex := b.trindex;
 		vertices,err := m.GetVertices(bIndex)
 		if err != nil{
 			return make([]anchorVertex,0), errors.New("Could not get the vertices of the given border triangle")
@@ -142,11 +146,11 @@ func vsaGetAnchorVertices(pErrors [] pError, m mesh.Mesh) ([]anchorVertex, error
 			vertexIndex := vertices[j]
 			v, exists := borderVertexDegrees[vertexIndex]
 			if exists{
-				v.proxyPlanes[b.p] = true
+				v.proxyPlanes[labels[bIndex]] = true
 				borderVertexDegrees[vertexIndex] = v;
 			}else{
-				a := anchorVertex{index: bIndex, proxyPlanes:make(map[*plane]bool)}
-				a.proxyPlanes[b.p] = true
+				a := anchorVertex{index: vertexIndex, proxyPlanes:make(map[int32]bool)}
+				a.proxyPlanes[labels[bIndex]] = true
 				borderVertexDegrees[vertexIndex] = a
 			}
 		}
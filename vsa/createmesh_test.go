@@ -9,7 +9,8 @@ import (
 
 func TestCreateAnchorVertices(t *testing.T) {
 	myMesh := shape.Octahedron(8)
-	proxies, pErrors := vsaVanillaError(myMesh,.01, 1)
+	p := vsaVanillaError(myMesh,.01, 1)
+	proxies := p.proxies
 	for i := 0; i < len(proxies); i++ {
 		fmt.Printf("Proxy: %v\n", proxies[i])
 	}
@@ -18,7 +19,7 @@ func TestCreateAnchorVertices(t *testing.T) {
 		t.Fail()
 	}
 
-	anchorVertices,e := vsaGetAnchorVertices(pErrors,myMesh)
+	anchorVertices,e := vsaGetAnchorVertices(p.labels,myMesh)
 
 	if e != nil{
 		t.Fail()
@@ -40,7 +41,8 @@ func TestCreateAnchorVertices3(t *testing.T) {
 	myMesh := shape.Octahedron(10)
 	stl.WriteSTLMeshName(myMesh, "octahedron_10.stl")
 
-	proxies, pErrors := vsaVanillaError(myMesh,.01, 1)
+	p := vsaVanillaError(myMesh,.01, 1)
+	proxies := p.proxies
 	for i := 0; i < len(proxies); i++ {
 		fmt.Printf("Proxy: %v\n", proxies[i])
 	}
@@ -49,7 +51,7 @@ func TestCreateAnchorVertices3(t *testing.T) {
 		t.Fail()
 	}
 
-	anchorVertices,e := vsaGetAnchorVertices(pErrors,myMesh)
+	anchorVertices,e := vsaGetAnchorVertices(p.labels,myMesh)
 
 	if e != nil{
 		t.Fail()
@@ -71,7 +73,8 @@ func TestCreateAnchorVertices3(t *testing.T) {
 
 func TestCreateAnchorVertices2(t *testing.T) {
 	myMesh := shape.Octahedron(2000)
-	proxies, pErrors := vsaVanillaError(myMesh,.01, 1)
+	p := vsaVanillaError(myMesh,.01, 1)
+	proxies := p.proxies
 	for i := 0; i < len(proxies); i++ {
 		fmt.Printf("Proxy: %v\n", proxies[i])
 	}
@@ -80,7 +83,7 @@ func TestCreateAnchorVertices2(t *testing.T) {
 		t.Fail()
 	}
 
-	anchorVertices,e := vsaGetAnchorVertices(pErrors,myMesh)
+	anchorVertices,e := vsaGetAnchorVertices(p.labels,myMesh)
 
 	if e != nil{
 		t.Fail()
@@ -101,7 +104,8 @@ func TestCreateAnchorVertices2(t *testing.T) {
 
 func TestCreateAnchorVertices4(t *testing.T) {
 	myMesh := shape.BasicCube()
-	proxies, pErrors := vsaVanillaError(myMesh,.01, 1)
+	p := vsaVanillaError(myMesh,.01, 1)
+	proxies := p.proxies
 	for i := 0; i < len(proxies); i++ {
 		fmt.Printf("Proxy: %v\n", proxies[i])
 	}
@@ -110,7 +114,7 @@ func TestCreateAnchorVertices4(t *testing.T) {
 		t.Fail()
 	}
 
-	anchorVertices,e := vsaGetAnchorVertices(pErrors,myMesh)
+	anchorVertices,e := vsaGetAnchorVertices(p.labels,myMesh)
 
 	if e != nil{
 		t.Fail()
@@ -0,0 +1,408 @@
+package vsa
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// regionLoop is a closed loop of region boundary vertices, in the winding order of the region's triangles
+type regionLoop []uint32
+
+// CreateMesh builds the simplified mesh of a VSA result.  Every connected region of
+// triangles with the same proxy becomes
+// a polygon whose corners are the anchor vertices on its boundary, placed on the
+// average of the planes of the proxies that meet there, and the polygon is
+// triangulated by ear clipping in the plane of its proxy.
+//
+// A region that cannot be made into a simple polygon (it has holes, it pinches, or
+// the polygon does not triangulate in its plane) keeps its original triangles, and
+// every vertex of its boundary becomes an anchor so that its neighbors still
+// connect to it without cracks.
+func CreateMesh(m mesh.Mesh, r Result) (cloudmesh.IndexedMesh, error) {
+	numTris := m.GetNumFacets()
+	numVertices := m.GetNumVertices()
+	if len(r.Labels) != int(numTris) {
+		return *cloudmesh.NewMesh(), fmt.Errorf("vsa.CreateMesh: expected %d labels and got %d", numTris, len(r.Labels))
+	}
+	proxies := make([]plane, len(r.Proxies))
+	for id, p := range r.Proxies {
+		proxies[id] = plane{point: p.Point, normal: []float32{p.Normal[0], p.Normal[1], p.Normal[2]}}
+	}
+
+	tris := make([][3]uint32, numTris)
+	halfEdges := make(map[[2]uint32]uint32, 3*numTris)
+	for t := uint32(0); t < numTris; t++ {
+		vertices, err := m.GetVertices(t)
+		if err != nil {
+			return *cloudmesh.NewMesh(), fmt.Errorf("vsa.CreateMesh: %v", err)
+		}
+		if id := r.Labels[t]; id < 0 || int(id) >= len(proxies) {
+			return *cloudmesh.NewMesh(), fmt.Errorf("vsa.CreateMesh: triangle %d has an invalid proxy ID %d", t, id)
+		}
+		for c := 0; c < 3; c++ {
+			if vertices[c] >= numVertices {
+				return *cloudmesh.NewMesh(), errors.New("vsa.CreateMesh: vertex index is out of bounds")
+			}
+			tris[t][c] = vertices[c]
+			halfEdges[[2]uint32{vertices[c], vertices[(c+1)%3]}] = t
+		}
+	}
+
+	// a proxy can own several disconnected patches of triangles, and each of them is a region
+	regions, regionProxy := connectedRegions(tris, r.Labels, halfEdges)
+
+	// the regions and proxies around every vertex, and whether it is on the boundary of the mesh
+	vertexRegions := make([][]int32, numVertices)
+	vertexProxies := make([][]int32, numVertices)
+	onBoundary := make([]bool, numVertices)
+	// the boundary edges of every region, as a map from a vertex to the next one
+	regionNext := make([]map[uint32]uint32, len(regionProxy))
+	keep := make([]bool, len(regionProxy))
+	for id := range regionNext {
+		regionNext[id] = make(map[uint32]uint32)
+	}
+	for t, tri := range tris {
+		id := regions[t]
+		for c := 0; c < 3; c++ {
+			a, b := tri[c], tri[(c+1)%3]
+			if !containsID(vertexRegions[a], id) {
+				vertexRegions[a] = append(vertexRegions[a], id)
+			}
+			if !containsID(vertexProxies[a], r.Labels[t]) {
+				vertexProxies[a] = append(vertexProxies[a], r.Labels[t])
+			}
+			twin, ok := halfEdges[[2]uint32{b, a}]
+			if !ok {
+				onBoundary[a] = true
+				onBoundary[b] = true
+			}
+			if ok && regions[twin] == id {
+				continue
+			}
+			if _, exists := regionNext[id][a]; exists {
+				keep[id] = true // the region pinches at a
+			}
+			regionNext[id][a] = b
+		}
+	}
+
+	isAnchor := make([]bool, numVertices)
+	for v := range isAnchor {
+		numSides := len(vertexRegions[v])
+		if onBoundary[v] {
+			numSides++
+		}
+		isAnchor[v] = numSides >= 3
+	}
+
+	loops := make([][]regionLoop, len(regionProxy))
+	for id := range regionProxy {
+		var ok bool
+		loops[id], ok = boundaryLoops(regionNext[id])
+		if !ok || len(loops[id]) != 1 || len(loops[id][0]) < 3 {
+			keep[id] = true
+		}
+	}
+
+	regionTris := make([][]uint32, len(regionProxy))
+	for t, id := range regions {
+		regionTris[id] = append(regionTris[id], uint32(t))
+	}
+	// a kept region connects to its neighbors through all of its boundary vertices
+	anchorRegion := func(id int) {
+		for _, t := range regionTris[id] {
+			for _, v := range tris[t] {
+				if len(vertexRegions[v]) > 1 || onBoundary[v] {
+					isAnchor[v] = true
+				}
+			}
+		}
+	}
+	for id := range regionProxy {
+		if keep[id] {
+			anchorRegion(id)
+		}
+	}
+
+	// give every polygon at least 3 corners and triangulate them, until nothing changes
+	polygons := make([][][3]uint32, len(regionProxy))
+	for changed := true; changed; {
+		changed = false
+		for id := range regionProxy {
+			if keep[id] {
+				continue
+			}
+			loop := loops[id][0]
+			numAnchors := 0
+			for _, v := range loop {
+				if isAnchor[v] {
+					numAnchors++
+				}
+			}
+			if numAnchors < 3 {
+				for i := 0; i < 3; i++ {
+					isAnchor[loop[i*len(loop)/3]] = true
+				}
+				changed = true
+			}
+		}
+		if changed {
+			continue
+		}
+		for id := range regionProxy {
+			if keep[id] {
+				continue
+			}
+			polygon, ok := triangulateRegion(m, proxies[regionProxy[id]], loops[id][0], isAnchor)
+			if !ok {
+				keep[id] = true
+				anchorRegion(id)
+				changed = true
+				continue
+			}
+			polygons[id] = polygon
+		}
+	}
+
+	retVal := cloudmesh.IndexedMesh{Indices: make([]uint32, 0), Vertices: make([]float32, 0)}
+	newIndex := make(map[uint32]uint32)
+	addVertex := func(v uint32) (uint32, error) {
+		if index, ok := newIndex[v]; ok {
+			return index, nil
+		}
+		var p []float32
+		var err error
+		if isAnchor[v] {
+			p, err = proxyVertexPosition(m, proxies, proxyVertex{proxies: vertexProxies[v], meshIndex: v})
+		} else {
+			p, err = m.GetPoint(v)
+		}
+		if err != nil {
+			return 0, err
+		}
+		index := retVal.GetNumVertices()
+		retVal.Vertices = append(retVal.Vertices, p...)
+		newIndex[v] = index
+		return index, nil
+	}
+	for id := range regionProxy {
+		if !keep[id] {
+			continue
+		}
+		polygons[id] = polygons[id][:0]
+		for _, t := range regionTris[id] {
+			polygons[id] = append(polygons[id], tris[t])
+		}
+	}
+	for id := range regionProxy {
+		for _, tri := range polygons[id] {
+			var indices [3]uint32
+			for c, v := range tri {
+				index, err := addVertex(v)
+				if err != nil {
+					return *cloudmesh.NewMesh(), fmt.Errorf("vsa.CreateMesh: %v", err)
+				}
+				indices[c] = index
+			}
+			retVal.AddTriangle(indices[0], indices[1], indices[2])
+		}
+	}
+	return retVal, nil
+}
+
+// connectedRegions splits the triangles of every proxy into edge-connected regions.
+// It returns the region of every triangle and the proxy of every region.
+func connectedRegions(tris [][3]uint32, labels []int32, halfEdges map[[2]uint32]uint32) (regions []int32, regionProxy []int32) {
+	regions = make([]int32, len(tris))
+	for t := range regions {
+		regions[t] = noProxy
+	}
+	regionProxy = make([]int32, 0)
+	for seed := range tris {
+		if regions[seed] != noProxy {
+			continue
+		}
+		id := int32(len(regionProxy))
+		regionProxy = append(regionProxy, labels[seed])
+		regions[seed] = id
+		queue := []uint32{uint32(seed)}
+		for len(queue) > 0 {
+			t := queue[0]
+			queue = queue[1:]
+			for c := 0; c < 3; c++ {
+				twin, ok := halfEdges[[2]uint32{tris[t][(c+1)%3], tris[t][c]}]
+				if ok && regions[twin] == noProxy && labels[twin] == labels[seed] {
+					regions[twin] = id
+					queue = append(queue, twin)
+				}
+			}
+		}
+	}
+	return regions, regionProxy
+}
+
+func containsID(ids []int32, id int32) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// boundaryLoops chains the boundary edges of a region into closed loops.
+// It returns false if some edges do not close into a loop.
+func boundaryLoops(next map[uint32]uint32) ([]regionLoop, bool) {
+	starts := make([]uint32, 0, len(next))
+	for v := range next {
+		starts = append(starts, v)
+	}
+	// visit the loops in a deterministic order
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	visited := make(map[uint32]bool, len(next))
+	loops := make([]regionLoop, 0, 1)
+	for _, start := range starts {
+		if visited[start] {
+			continue
+		}
+		loop := make(regionLoop, 0)
+		for v := start; !visited[v]; {
+			visited[v] = true
+			loop = append(loop, v)
+			n, ok := next[v]
+			if !ok {
+				return append(loops, loop), false
+			}
+			v = n
+		}
+		loops = append(loops, loop)
+	}
+	return loops, true
+}
+
+// triangulateRegion triangulates the anchors of a region boundary loop in the plane of its proxy
+func triangulateRegion(m mesh.Mesh, proxy plane, loop regionLoop, isAnchor []bool) ([][3]uint32, bool) {
+	n := auxmath.Vec3d{float64(proxy.normal[0]), float64(proxy.normal[1]), float64(proxy.normal[2])}
+	// u and v span the plane
+	u := auxmath.Vec3d{1, 0, 0}
+	if math.Abs(n[0]) > .9 {
+		u = auxmath.Vec3d{0, 1, 0}
+	}
+	u = n.Cross(u)
+	if u.Magnitude() == 0 {
+		return nil, false
+	}
+	u = u.Normalize()
+	v := n.Cross(u)
+
+	corners := make([]uint32, 0)
+	points := make([][2]float64, 0)
+	for _, vertex := range loop {
+		if !isAnchor[vertex] {
+			continue
+		}
+		p, err := m.GetPoint(vertex)
+		if err != nil {
+			return nil, false
+		}
+		corners = append(corners, vertex)
+		points = append(points, [2]float64{
+			float64(p[0])*u[0] + float64(p[1])*u[1] + float64(p[2])*u[2],
+			float64(p[0])*v[0] + float64(p[1])*v[1] + float64(p[2])*v[2]})
+	}
+	triangles, ok := earClip(points)
+	if !ok {
+		return nil, false
+	}
+	retVal := make([][3]uint32, len(triangles))
+	for i, t := range triangles {
+		retVal[i] = [3]uint32{corners[t[0]], corners[t[1]], corners[t[2]]}
+	}
+	return retVal, true
+}
+
+func orient2D(a [2]float64, b [2]float64, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// earClip triangulates a simple polygon, keeping its orientation.  At every step it
+// cuts the ear with the largest smallest angle.  It fails if the polygon has no
+// area or is not simple enough to always have an ear.
+func earClip(points [][2]float64) ([][3]int, bool) {
+	if len(points) < 3 {
+		return nil, false
+	}
+	area := 0.0
+	for i := range points {
+		area += orient2D([2]float64{}, points[i], points[(i+1)%len(points)])
+	}
+	if area == 0 {
+		return nil, false
+	}
+	sign := 1.0
+	if area < 0 {
+		sign = -1
+	}
+
+	remaining := make([]int, len(points))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	triangles := make([][3]int, 0, len(points)-2)
+	for len(remaining) > 3 {
+		best, bestQuality := -1, -1.0
+		for i := range remaining {
+			a := remaining[(i+len(remaining)-1)%len(remaining)]
+			b := remaining[i]
+			c := remaining[(i+1)%len(remaining)]
+			if sign*orient2D(points[a], points[b], points[c]) <= 0 {
+				continue // reflex or flat
+			}
+			inside := false
+			for _, j := range remaining {
+				if j != a && j != b && j != c && inTriangle(points[j], points[a], points[b], points[c], sign) {
+					inside = true
+					break
+				}
+			}
+			if inside {
+				continue
+			}
+			if q := minAngle(points[a], points[b], points[c]); q > bestQuality {
+				best, bestQuality = i, q
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+		triangles = append(triangles, [3]int{
+			remaining[(best+len(remaining)-1)%len(remaining)], remaining[best], remaining[(best+1)%len(remaining)]})
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+	if sign*orient2D(points[remaining[0]], points[remaining[1]], points[remaining[2]]) <= 0 {
+		return nil, false
+	}
+	return append(triangles, [3]int{remaining[0], remaining[1], remaining[2]}), true
+}
+
+// inTriangle tells if p is inside or on the triangle abc of the given orientation
+func inTriangle(p [2]float64, a [2]float64, b [2]float64, c [2]float64, sign float64) bool {
+	return sign*orient2D(a, b, p) >= 0 && sign*orient2D(b, c, p) >= 0 && sign*orient2D(c, a, p) >= 0
+}
+
+// minAngle returns the smallest angle of a triangle, in radians
+func minAngle(a [2]float64, b [2]float64, c [2]float64) float64 {
+	angle := func(o, p, q [2]float64) float64 {
+		ux, uy := p[0]-o[0], p[1]-o[1]
+		vx, vy := q[0]-o[0], q[1]-o[1]
+		return math.Abs(math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy))
+	}
+	return math.Min(angle(a, b, c), math.Min(angle(b, c, a), angle(c, a, b)))
+}
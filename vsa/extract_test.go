@@ -0,0 +1,134 @@
+package vsa
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// checkWatertight checks that every edge of the mesh is used once in each direction
+func checkWatertight(t *testing.T, m cloudmesh.IndexedMesh) {
+	edges := make(map[[2]uint32]int)
+	for f := uint32(0); f < m.GetNumFacets(); f++ {
+		vertices, _ := m.GetVertices(f)
+		for c := 0; c < 3; c++ {
+			edges[[2]uint32{vertices[c], vertices[(c+1)%3]}]++
+		}
+	}
+	for e, count := range edges {
+		if count != 1 || edges[[2]uint32{e[1], e[0]}] != 1 {
+			t.Fatalf("Edge %v is used %d times and its twin %d times", e, count, edges[[2]uint32{e[1], e[0]}])
+		}
+	}
+}
+
+func TestCreateMeshCube(t *testing.T) {
+	cube := shape.BasicCube()
+	opts := DefaultOptions()
+	opts.Seed = 1
+	r, err := Run(cube, opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	simplified, err := CreateMesh(cube, r)
+	if err != nil {
+		t.Fatalf("CreateMesh failed: %v", err)
+	}
+	if simplified.GetNumFacets() != 12 || simplified.GetNumVertices() != 8 {
+		t.Errorf("Expected 12 triangles and 8 vertices, got %d and %d", simplified.GetNumFacets(), simplified.GetNumVertices())
+	}
+	checkWatertight(t, simplified)
+}
+
+func TestCreateMeshOctahedron(t *testing.T) {
+	octahedron := shape.Octahedron(800)
+	opts := DefaultOptions()
+	opts.PlanarPrePass = true
+	opts.PlanarOffsetTolerance = 1e-3
+	opts.Seed = 1
+	r, err := Run(octahedron, opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	simplified, err := CreateMesh(octahedron, r)
+	if err != nil {
+		t.Fatalf("CreateMesh failed: %v", err)
+	}
+	if simplified.GetNumFacets() != 8 || simplified.GetNumVertices() != 6 {
+		t.Errorf("Expected 8 triangles and 6 vertices, got %d and %d", simplified.GetNumFacets(), simplified.GetNumVertices())
+	}
+	checkWatertight(t, simplified)
+}
+
+func TestCreateMeshWrongLabels(t *testing.T) {
+	cube := shape.BasicCube()
+	if _, err := CreateMesh(cube, Result{Labels: make([]int32, 3)}); err == nil {
+		t.Errorf("Expected an error for a result of another mesh")
+	}
+	if _, err := CreateMesh(cube, Result{Labels: make([]int32, cube.GetNumFacets())}); err == nil {
+		t.Errorf("Expected an error for labels without proxies")
+	}
+}
+
+func TestEarClip(t *testing.T) {
+	// an L shape, clockwise
+	points := [][2]float64{{0, 0}, {0, 2}, {1, 2}, {1, 1}, {2, 1}, {2, 0}}
+	triangles, ok := earClip(points)
+	if !ok || len(triangles) != 4 {
+		t.Fatalf("Expected 4 triangles, got %v (%v)", len(triangles), ok)
+	}
+	area := 0.0
+	for _, tri := range triangles {
+		a := orient2D(points[tri[0]], points[tri[1]], points[tri[2]])
+		if a >= 0 {
+			t.Errorf("Triangle %v does not keep the orientation of the polygon", tri)
+		}
+		area += a / 2
+	}
+	if math.Abs(area+3) > 1e-12 {
+		t.Errorf("Expected the triangles to cover an area of 3, got %v", -area)
+	}
+
+	if _, ok := earClip([][2]float64{{0, 0}, {1, 1}, {2, 2}}); ok {
+		t.Errorf("Expected a flat polygon to fail")
+	}
+}
+
+func TestProjectPointOntoPlane(t *testing.T) {
+	pl := plane{point: [3]float64{0, 0, 1}, normal: []float32{0, 0, 1}}
+	for _, p := range [][]float32{{1, 2, 3}, {1, 2, -3}} {
+		q := projectPointOntoPlane(p, pl)
+		if q[0] != p[0] || q[1] != p[1] || q[2] != 1 {
+			t.Errorf("Expected %v to project to (%v,%v,1), got %v", p, p[0], p[1], q)
+		}
+	}
+}
+
+func TestCreateMeshSphere(t *testing.T) {
+	sphere := shape.Octahedron(2000)
+	for v := 0; v < len(sphere.Vertices); v += 3 {
+		p := sphere.Vertices[v : v+3]
+		r := float32(math.Sqrt(float64(p[0]*p[0]+p[1]*p[1]+p[2]*p[2]))) / 100
+		p[0], p[1], p[2] = p[0]/r, p[1]/r, p[2]/r
+	}
+	opts := DefaultOptions()
+	opts.NumSeeds = 30
+	opts.MaxIterations = 30
+	opts.Seed = 3
+	r, err := Run(sphere, opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	simplified, err := CreateMesh(sphere, r)
+	if err != nil {
+		t.Fatalf("CreateMesh failed: %v", err)
+	}
+	// the partition of a sphere is fragmented, so this mostly checks that the
+	// regions stitch together without cracks
+	if simplified.GetNumFacets() > sphere.GetNumFacets() {
+		t.Errorf("Expected at most %d triangles, got %d", sphere.GetNumFacets(), simplified.GetNumFacets())
+	}
+	checkWatertight(t, simplified)
+}
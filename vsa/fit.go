@@ -0,0 +1,155 @@
+package vsa
+
+import (
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+)
+
+// proxyMoments accumulates the area weighted moments of the triangles of one proxy.
+// The L2,1 optimal proxy is the area weighted average of the normals going through
+// the area weighted average of the barycenters, so these sums are all a fit needs.
+type proxyMoments struct {
+	area      float64
+	count     int
+	normalSum [3]float64    // sum of area * normal
+	centerSum [3]float64    // sum of area * barycenter
+	second    [3][3]float64 // sum of the integrals of p*p^T over each triangle
+}
+
+// addTriangle adds the triangle (a,b,c) to the moments.  The points are in double
+// precision, so that meshes far from the origin (see mesh.Mesh64) fit as well as others.
+// The second moments are only needed by refineNormal, so they are skipped unless asked for.
+func (pm *proxyMoments) addTriangle(a [3]float64, b [3]float64, c [3]float64, withSecond bool) {
+	// half the cross product is the normal scaled by the area
+	areaNormal := auxmath.Vec3d(b).Sub(a).Cross(auxmath.Vec3d(c).Sub(a)).Scale(.5)
+	area := areaNormal.Magnitude()
+
+	pm.count++
+	pm.area += area
+	for i := 0; i < 3; i++ {
+		pm.normalSum[i] += areaNormal[i]
+		pm.centerSum[i] += area * (a[i] + b[i] + c[i]) / 3
+	}
+	if !withSecond {
+		return
+	}
+	// integral of p*p^T over a triangle: area/12 * (a*a^T + b*b^T + c*c^T + s*s^T), s = a+b+c
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			s := (a[i] + b[i] + c[i]) * (a[j] + b[j] + c[j])
+			pm.second[i][j] += area / 12 * (a[i]*a[j] + b[i]*b[j] + c[i]*c[j] + s)
+		}
+	}
+}
+
+// fit returns the L2,1 optimal plane of the accumulated triangles.
+// The second return value is false when there is nothing to fit (no triangles, no
+// area, or normals that cancel out).  The mean normal is normalized in double
+// precision, so that proxies made of tiny triangles get a normal as well.
+func (pm *proxyMoments) fit() (plane, bool) {
+	if pm.count == 0 || pm.area <= 0 {
+		return plane{}, false
+	}
+	mean := [3]float64{pm.normalSum[0] / pm.area, pm.normalSum[1] / pm.area, pm.normalSum[2] / pm.area}
+	n, ok := normalize64(mean)
+	if !ok {
+		return plane{}, false
+	}
+	normal := make([]float32, 3)
+	var center [3]float64
+	for i := 0; i < 3; i++ {
+		normal[i] = float32(n[i])
+		center[i] = pm.centerSum[i] / pm.area
+	}
+	return plane{point: center, normal: normal}, true
+}
+
+// covariance returns the area weighted covariance of the triangles about their barycenter,
+// so that the L2 error of a plane with unit normal n through the barycenter is n^T*C*n.
+func (pm *proxyMoments) covariance() [3][3]float64 {
+	var cov [3][3]float64
+	if pm.area <= 0 {
+		return cov
+	}
+	var g [3]float64
+	for i := 0; i < 3; i++ {
+		g[i] = pm.centerSum[i] / pm.area
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			cov[i][j] = pm.second[i][j] - pm.area*g[i]*g[j]
+		}
+	}
+	return cov
+}
+
+// quadraticForm returns n^T*C*n
+func quadraticForm(cov [3][3]float64, n [3]float64) float64 {
+	sum := float64(0)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			sum += n[i] * cov[i][j] * n[j]
+		}
+	}
+	return sum
+}
+
+func normalize64(v [3]float64) ([3]float64, bool) {
+	norm := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	if norm < 1e-12 {
+		return v, false
+	}
+	return [3]float64{v[0] / norm, v[1] / norm, v[2] / norm}, true
+}
+
+// refineNormal moves the unit normal n towards the normal that minimizes the L2
+// (distance) error n^T*C*n of a non-planar proxy.  Each iteration takes a Newton
+// step on the unit sphere (Rayleigh quotient iteration), falling back to a
+// gradient step whenever the Newton step would increase the error.  A planar
+// proxy has no error to remove and is returned as is.
+func refineNormal(cov [3][3]float64, n [3]float64, iterations int) [3]float64 {
+	trace := cov[0][0] + cov[1][1] + cov[2][2]
+	if trace <= 0 {
+		return n
+	}
+	for it := 0; it < iterations; it++ {
+		energy := quadraticForm(cov, n)
+		// gradient of n^T*C*n projected onto the tangent plane of the unit sphere
+		var cn, grad [3]float64
+		for i := 0; i < 3; i++ {
+			cn[i] = cov[i][0]*n[0] + cov[i][1]*n[1] + cov[i][2]*n[2]
+		}
+		for i := 0; i < 3; i++ {
+			grad[i] = 2 * (cn[i] - energy*n[i])
+		}
+		if math.Sqrt(grad[0]*grad[0]+grad[1]*grad[1]+grad[2]*grad[2]) < 1e-9*trace {
+			break // stationary: n is an eigenvector of C
+		}
+
+		// Newton: solve (C - energy*I) y = n
+		shifted := cov
+		for i := 0; i < 3; i++ {
+			shifted[i][i] -= energy
+		}
+		candidate := n
+		if y, err := auxmath.Solve3x3(shifted, n, 1e-12*trace*trace*trace); err == nil {
+			if y, ok := normalize64(y); ok {
+				candidate = y
+			}
+		}
+		if candidate == n || quadraticForm(cov, candidate) > energy {
+			// gradient step; 1/(2*trace) is below 1/(2*largest eigenvalue) so it can't overshoot
+			for i := 0; i < 3; i++ {
+				candidate[i] = n[i] - grad[i]/(2*trace)
+			}
+			candidate, _ = normalize64(candidate)
+		}
+		// keep the orientation of the proxy
+		if candidate[0]*n[0]+candidate[1]*n[1]+candidate[2]*n[2] < 0 {
+			candidate = [3]float64{-candidate[0], -candidate[1], -candidate[2]}
+		}
+		n = candidate
+	}
+	return n
+}
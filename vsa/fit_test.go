@@ -0,0 +1,122 @@
+package vsa
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/cloudmesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+// point32 returns the point of a proxy as float32, for expectVector
+func point32(pl plane) []float32 {
+	return []float32{float32(pl.point[0]), float32(pl.point[1]), float32(pl.point[2])}
+}
+
+func expectVector(t *testing.T, what string, expected []float32, got []float32) {
+	for i := range expected {
+		if math.Abs(float64(expected[i]-got[i])) > 1e-4 {
+			t.Errorf("%v: expected %v and got %v", what, expected, got)
+			return
+		}
+	}
+}
+
+// Each face of shape.BasicCube is made of triangles 2k and 2k+1.  Fitting a proxy to
+// each face must give the face's plane: its outward normal through the face center.
+func TestProxyFitCube(t *testing.T) {
+	cube := shape.BasicCube()
+	expectedNormals := [][]float32{
+		{0, -1, 0}, {0, 0, -1}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {-1, 0, 0}}
+	expectedCenters := [][]float32{
+		{50, 0, 50}, {50, 50, 0}, {100, 50, 50}, {50, 100, 50}, {50, 50, 100}, {0, 50, 50}}
+
+	for _, refine := range []int{0, 10} {
+		p := initialize(int(cube.GetNumFacets()))
+		p.proxies = make([]plane, 6)
+		for tri := range p.labels {
+			p.labels[tri] = int32(tri / 2)
+			p.errors[tri] = 0
+		}
+		vanillaProxyFit(cube, &p, refine)
+		for id := range p.proxies {
+			expectVector(t, "normal", expectedNormals[id], p.proxies[id].normal)
+			expectVector(t, "center", expectedCenters[id], point32(p.proxies[id]))
+		}
+	}
+}
+
+// A big and a small triangle sharing a proxy: the fit must weight them by area,
+// and must not let the first triangle's normal replace the sum.
+func TestProxyFitAreaWeighted(t *testing.T) {
+	m := cloudmesh.IndexedMesh{
+		Vertices: []float32{
+			0, 0, 0,
+			0, 1, 0,
+			0, 0, 1,
+			4, 0, 0,
+			0, 4, 0},
+		Indices: []uint32{
+			0, 1, 2, // area .5, normal (1,0,0)
+			0, 3, 4}} // area 8, normal (0,0,1)
+	p := initialize(2)
+	p.proxies = make([]plane, 1)
+	p.labels[0], p.labels[1] = 0, 0
+	p.errors[0], p.errors[1] = 0, 0
+	vanillaProxyFit(m, &p, 0)
+
+	norm := float32(math.Sqrt(.5*.5 + 8*8))
+	expectVector(t, "normal", []float32{.5 / norm, 0, 8 / norm}, p.proxies[0].normal)
+	expectVector(t, "center", []float32{
+		(.5*0 + 8*4.0/3) / 8.5,
+		(.5*1.0/3 + 8*4.0/3) / 8.5,
+		(.5*1.0/3 + 8*0) / 8.5}, point32(p.proxies[0]))
+}
+
+// A proxy of millimetre triangles on a mesh in metres has a total area far below
+// what float32 normalization handles, and must still get its normal.
+func TestProxyFitTinyTriangles(t *testing.T) {
+	var pm proxyMoments
+	for i := 0; i < 4; i++ {
+		x := float64(i) * 1e-3
+		pm.addTriangle([3]float64{x, 0, 5}, [3]float64{x + 1e-3, 0, 5}, [3]float64{x, 1e-3, 5}, false)
+	}
+	fitted, ok := pm.fit()
+	if !ok {
+		t.Fatal("Expected a fit")
+	}
+	expectVector(t, "normal", []float32{0, 0, 1}, fitted.normal)
+	expectVector(t, "center", []float32{.0018333, .0003333, 5}, point32(fitted))
+
+	// normals that cancel out have no plane
+	var opposite proxyMoments
+	opposite.addTriangle([3]float64{0, 0, 0}, [3]float64{1e-3, 0, 0}, [3]float64{0, 1e-3, 0}, false)
+	opposite.addTriangle([3]float64{0, 0, 0}, [3]float64{0, 1e-3, 0}, [3]float64{1e-3, 0, 0}, false)
+	if _, ok := opposite.fit(); ok {
+		t.Error("Expected no fit for normals that cancel out")
+	}
+}
+
+// Two unit squares in the planes z=0 and x=0 meeting along the y axis.  The plane
+// with the least squared distance to them has the normal (1,0,1)/sqrt(2).
+func TestRefineNormal(t *testing.T) {
+	squares := [][3][3]float64{
+		{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}}, {{0, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+		{{0, 0, 0}, {0, 1, 0}, {0, 1, 1}}, {{0, 0, 0}, {0, 1, 1}, {0, 0, 1}}}
+	var pm proxyMoments
+	for _, tri := range squares {
+		pm.addTriangle(tri[0], tri[1], tri[2], true)
+	}
+	cov := pm.covariance()
+
+	start, _ := normalize64([3]float64{1, 0, .6})
+	n := refineNormal(cov, start, 20)
+	expected := 1 / math.Sqrt(2)
+	if math.Abs(n[0]-expected) > 1e-6 || math.Abs(n[1]) > 1e-6 || math.Abs(n[2]-expected) > 1e-6 {
+		t.Errorf("Expected (%v,0,%v) and got %v", expected, expected, n)
+	}
+	// the L2 error of that plane is the smallest eigenvalue of the covariance, 2 * 1/24
+	if e := quadraticForm(cov, n); math.Abs(e-1.0/12) > 1e-6 {
+		t.Errorf("Expected an L2 error of %v and got %v", 1.0/12, e)
+	}
+}
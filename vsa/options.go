@@ -0,0 +1,66 @@
+package vsa
+
+// Options configures a VSA run.  Zero fields take the value of DefaultOptions, except
+// RefineIterations, PlanarPrePass, Seed and Verbose, whose zero values have a meaning of their own.
+type Options struct {
+	// ErrorThreshold stops the iterations once the worst triangle error is below it.
+	ErrorThreshold float32 `json:"errorThreshold"`
+	// NumSeeds is the number of random seed triangles the run starts with.
+	NumSeeds int `json:"numSeeds"`
+	// MaxIterations bounds the number of partition/fit iterations.
+	MaxIterations int `json:"maxIterations"`
+	// RefineIterations is the number of Newton steps used to refine the normal of
+	// each proxy towards the L2 (distance) optimum after the L2,1 fit.  0 disables it.
+	RefineIterations int `json:"refineIterations"`
+	// PlanarPrePass groups connected (nearly) coplanar triangles into fixed proxies
+	// before the iterations start, so that VSA only optimizes the curved remainder.
+	PlanarPrePass bool `json:"planarPrePass"`
+	// PlanarNormalTolerance is the largest 1 - cos(angle) between the normals of two
+	// triangles of the same planar region.
+	PlanarNormalTolerance float32 `json:"planarNormalTolerance"`
+	// PlanarOffsetTolerance is the largest distance of a vertex of a planar region to its plane.
+	PlanarOffsetTolerance float32 `json:"planarOffsetTolerance"`
+	// PlanarMinTriangles is the smallest number of triangles that makes a planar region.
+	PlanarMinTriangles int `json:"planarMinTriangles"`
+	// NumNeighbors is the number of nearest neighbors that make up the neighborhood
+	// of a point when running on a point cloud.
+	NumNeighbors int `json:"numNeighbors"`
+	// Seed for the random seed triangle selection.  0 picks a seed from the clock.
+	Seed int64 `json:"seed"`
+	// Verbose logs the errors of every iteration and the final proxies.  It does not
+	// change the result, so it is not saved with it.
+	Verbose bool `json:"-"`
+}
+
+// DefaultOptions returns the options used by VSAVanilla
+func DefaultOptions() Options {
+	return Options{ErrorThreshold: .1, NumSeeds: 1, MaxIterations: 100,
+		PlanarNormalTolerance: 1e-5, PlanarOffsetTolerance: 1e-4, PlanarMinTriangles: 2, NumNeighbors: 8}
+}
+
+// withDefaults returns the options with the zero fields set as in DefaultOptions
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.ErrorThreshold == 0 {
+		o.ErrorThreshold = d.ErrorThreshold
+	}
+	if o.NumSeeds == 0 {
+		o.NumSeeds = d.NumSeeds
+	}
+	if o.MaxIterations == 0 {
+		o.MaxIterations = d.MaxIterations
+	}
+	if o.PlanarNormalTolerance == 0 {
+		o.PlanarNormalTolerance = d.PlanarNormalTolerance
+	}
+	if o.PlanarOffsetTolerance == 0 {
+		o.PlanarOffsetTolerance = d.PlanarOffsetTolerance
+	}
+	if o.PlanarMinTriangles == 0 {
+		o.PlanarMinTriangles = d.PlanarMinTriangles
+	}
+	if o.NumNeighbors == 0 {
+		o.NumNeighbors = d.NumNeighbors
+	}
+	return o
+}
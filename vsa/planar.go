@@ -0,0 +1,104 @@
+package vsa
+
+import (
+	"math"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// planarRegions groups connected triangles that lie in the same plane by growing
+// regions over the triangle neighborhood of the mesh.  A neighbor joins a region when
+// its normal is within normalTolerance (1 - cosine of the angle) of the region's seed
+// normal and its vertices are within offsetTolerance of the seed plane.  Comparing
+// against the seed instead of the last triangle added keeps a slowly curving surface
+// from being swallowed one triangle at a time.
+//
+// Only regions with at least minTriangles triangles are returned.  regionLabels holds
+// the index of the region of every triangle, or noProxy for triangles left to VSA.
+func planarRegions(m mesh.Mesh, normalTolerance float32, offsetTolerance float32, minTriangles int) (regionLabels []int32, regions []plane) {
+	numTris := m.GetNumFacets()
+	neighborhood := mesh.CreateNeighborhood(m)
+
+	regionLabels = make([]int32, numTris)
+	for i := range regionLabels {
+		regionLabels[i] = noProxy
+	}
+	visited := make([]bool, numTris)
+	regions = make([]plane, 0)
+
+	for seed := uint32(0); seed < numTris; seed++ {
+		if visited[seed] {
+			continue
+		}
+		visited[seed] = true
+		seedNormal, _, err := triangleNormal(m, seed)
+		if err != nil {
+			// degenerate triangles have no plane to grow from
+			continue
+		}
+		seedPoint, _ := mesh.ComputeCentroid64(m, seed)
+
+		region := []uint32{seed}
+		queue := []uint32{seed}
+		for len(queue) > 0 {
+			//pop
+			currTri := queue[0]
+			queue = queue[1:]
+
+			neighbors, _ := neighborhood.GetTriangleNeighborsOfTriangle(currTri)
+			for _, neighb := range neighbors {
+				if visited[neighb] || !onPlane(m, neighb, seedPoint, seedNormal, normalTolerance, offsetTolerance) {
+					continue
+				}
+				visited[neighb] = true
+				region = append(region, neighb)
+				queue = append(queue, neighb)
+			}
+		}
+
+		if len(region) < minTriangles {
+			// give the triangles a chance to join a region grown from another seed
+			for _, tri := range region {
+				visited[tri] = false
+			}
+			visited[seed] = true
+			continue
+		}
+
+		var moments proxyMoments
+		for _, tri := range region {
+			regionLabels[tri] = int32(len(regions))
+			points, _ := mesh.GetTrianglePoints64(m, tri)
+			moments.addTriangle(points[0], points[1], points[2], false)
+		}
+		fitted, ok := moments.fit()
+		if !ok {
+			fitted = plane{point: seedPoint, normal: seedNormal.Slice()}
+		}
+		regions = append(regions, fitted)
+	}
+	return regionLabels, regions
+}
+
+// onPlane checks if a triangle lies in the plane through point with the given normal
+func onPlane(m mesh.Mesh, tri uint32, point [3]float64, normal auxmath.Vec3, normalTolerance float32, offsetTolerance float32) bool {
+	triNormal, _, err := triangleNormal(m, tri)
+	if err != nil {
+		return false
+	}
+	if 1-triNormal.Dot(normal) > normalTolerance {
+		return false
+	}
+	points, _ := mesh.GetTrianglePoints64(m, tri)
+	for _, vertex := range points {
+		dist := 0.0
+		for i := 0; i < 3; i++ {
+			dist += (vertex[i] - point[i]) * float64(normal[i])
+		}
+		if math.Abs(dist) > float64(offsetTolerance) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package vsa
+
+import (
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func TestPlanarRegionsCube(t *testing.T) {
+	cube := shape.BasicCube()
+	labels, regions := planarRegions(cube, 1e-5, 1e-4, 2)
+	if len(regions) != 6 {
+		t.Fatalf("Expected 6 planar regions and got %v", len(regions))
+	}
+	// triangles 2k and 2k+1 make up a face of the cube
+	for tri := 0; tri < len(labels); tri += 2 {
+		if labels[tri] == noProxy || labels[tri] != labels[tri+1] {
+			t.Errorf("Expected triangles %v and %v to share a region, got %v and %v", tri, tri+1, labels[tri], labels[tri+1])
+		}
+	}
+}
+
+func TestPlanarRegionsGeoreferenced(t *testing.T) {
+	// a centimeter cube five million units from the origin: float32 cannot tell its
+	// corners apart, but the double precision mesh keeps its six faces
+	cube := shape.BasicCube().ToIndexedMesh64()
+	for i := range cube.Vertices {
+		cube.Vertices[i] = cube.Vertices[i]/1e4 + 5e6
+	}
+	labels, regions := planarRegions(cube, 1e-5, 1e-6, 2)
+	if len(regions) != 6 {
+		t.Fatalf("Expected 6 planar regions and got %v", len(regions))
+	}
+	for tri := 0; tri < len(labels); tri += 2 {
+		if labels[tri] == noProxy || labels[tri] != labels[tri+1] {
+			t.Errorf("Expected triangles %v and %v to share a region, got %v and %v", tri, tri+1, labels[tri], labels[tri+1])
+		}
+	}
+	if _, regions := planarRegions(cube.ToIndexedMesh(), 1e-5, 1e-6, 2); len(regions) != 0 {
+		t.Errorf("Expected the float32 cube to collapse, got %v planar regions", len(regions))
+	}
+}
+
+func TestPlanarRegionsOctahedron(t *testing.T) {
+	// every face of shape.Octahedron is subdivided in its own plane
+	octahedron := shape.Octahedron(200)
+	labels, regions := planarRegions(octahedron, 1e-5, 1e-3, 2)
+	if len(regions) != 8 {
+		t.Errorf("Expected 8 planar regions and got %v", len(regions))
+	}
+	for tri, id := range labels {
+		if id == noProxy {
+			t.Errorf("Expected triangle %v to belong to a planar region", tri)
+		}
+	}
+}
+
+func TestPlanarRegionsSphere(t *testing.T) {
+	// no two neighboring triangles of a sphere are coplanar
+	sphere := shape.FacetSphere(100)
+	_, regions := planarRegions(sphere, 1e-5, 1e-4, 2)
+	if len(regions) != 0 {
+		t.Errorf("Expected no planar regions and got %v", len(regions))
+	}
+}
+
+func TestPlanarPrePass(t *testing.T) {
+	cube := shape.BasicCube()
+	opts := DefaultOptions()
+	opts.ErrorThreshold = .01
+	opts.PlanarPrePass = true
+	r, err := Run(cube, opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(r.Proxies) != 6 {
+		t.Errorf("Expected 6 proxies and got %v", len(r.Proxies))
+	}
+	if len(r.Anchors) != 8 {
+		t.Errorf("Expected 8 anchors and got %v", len(r.Anchors))
+	}
+}
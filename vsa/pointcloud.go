@@ -0,0 +1,235 @@
+package vsa
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/pointcloud"
+)
+
+// PointProxy is a planar proxy of a point cloud along with the points it represents
+type PointProxy struct {
+	Point   [3]float32 `json:"point"`
+	Normal  [3]float32 `json:"normal"`
+	Members []uint32   `json:"members"`
+}
+
+// RunPointCloud partitions a point cloud into planar proxies.  It is the point cloud
+// counterpart of Run: the k nearest neighbors of a point stand in for the triangle
+// neighborhood, the point normals for the triangle normals, and each proxy is fitted
+// to its member points with principal component analysis.  The normals are estimated
+// from the neighborhood if the cloud doesn't have them yet.
+func RunPointCloud(c *pointcloud.PointCloud, opts Options) ([]PointProxy, error) {
+	numPoints := c.GetNumPoints()
+	if numPoints < 1 {
+		return nil, errors.New("vsa.RunPointCloud: there weren't any points in the point cloud")
+	}
+	opts = opts.withDefaults()
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	neighborhood := pointcloud.CreateKNNNeighborhood(*c, opts.NumNeighbors)
+	if !c.HasNormals() {
+		if err := pointcloud.EstimateNormals(c, neighborhood); err != nil {
+			return nil, fmt.Errorf("vsa.RunPointCloud: %v", err)
+		}
+	}
+
+	p := initialize(int(numPoints))
+	numSeeds := opts.NumSeeds
+	if numSeeds < 1 {
+		numSeeds = 1
+	}
+	if numSeeds > int(numPoints) {
+		numSeeds = int(numPoints)
+	}
+	// seedPoints[id] is the point proxy id floods the cloud from
+	seedPoints := make([]uint32, 0, numSeeds)
+	for _, seed := range rng.Perm(int(numPoints))[:numSeeds] {
+		seedPoints = append(seedPoints, uint32(seed))
+		p.proxies = append(p.proxies, pointPlane(c, uint32(seed)))
+	}
+
+	numIterations := 0
+	maxError := float32(math.MaxFloat32)
+	for maxError > opts.ErrorThreshold && numIterations < opts.MaxIterations {
+		floodPartition(c, neighborhood, &p, seedPoints)
+		worstPoint, thisIterationError := pointProxyFit(c, &p, seedPoints)
+
+		if thisIterationError > opts.ErrorThreshold {
+			p.proxies = append(p.proxies, pointPlane(c, worstPoint))
+			seedPoints = append(seedPoints, worstPoint)
+		}
+		if thisIterationError < maxError {
+			maxError = thisIterationError
+		}
+		numIterations++
+	}
+	removeEmptyProxies(&p)
+
+	proxies := make([]PointProxy, len(p.proxies))
+	for id, pl := range p.proxies {
+		for i := 0; i < 3; i++ {
+			proxies[id].Point[i] = float32(pl.point[i])
+		}
+		copy(proxies[id].Normal[:], pl.normal)
+		proxies[id].Members = make([]uint32, 0)
+	}
+	for point, id := range p.labels {
+		if id == noProxy {
+			return nil, fmt.Errorf("vsa.RunPointCloud: point %d was not assigned to a proxy", point)
+		}
+		proxies[id].Members = append(proxies[id].Members, uint32(point))
+	}
+	return proxies, nil
+}
+
+// pointPlane returns the plane through a point with the normal of the point
+func pointPlane(c *pointcloud.PointCloud, point uint32) plane {
+	position, _ := c.GetPoint(point)
+	normal, _ := c.GetNormal(point)
+	return plane{point: [3]float64{float64(position[0]), float64(position[1]), float64(position[2])}, normal: normal}
+}
+
+// pointError is the L2,1 error of a point against a proxy.
+// Every point stands for the same amount of surface, so there is no area weight.
+func pointError(c *pointcloud.PointCloud, point uint32, proxy plane) float32 {
+	sum := float32(0)
+	for i := 0; i < 3; i++ {
+		d := c.Normals[3*point+uint32(i)] - proxy.normal[i]
+		sum += d * d
+	}
+	return sum
+}
+
+// floodItem is a point waiting to be claimed by a proxy
+type floodItem struct {
+	point uint32
+	proxy int32
+	err   float32
+}
+
+// floodQueue is a min-heap of floodItems ordered by error
+type floodQueue []floodItem
+
+func (q floodQueue) Len() int            { return len(q) }
+func (q floodQueue) Less(i, j int) bool  { return q[i].err < q[j].err }
+func (q floodQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *floodQueue) Push(x interface{}) { *q = append(*q, x.(floodItem)) }
+func (q *floodQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// floodPartition grows every proxy from its seed point over the neighborhood,
+// always claiming the point with the smallest error next, so that every proxy
+// ends up with a connected set of points (the flooding of the original VSA paper).
+func floodPartition(c *pointcloud.PointCloud, neighborhood pointcloud.CloudNeighborhood, p *partition, seedPoints []uint32) {
+	for t := range p.labels {
+		p.labels[t] = noProxy
+		p.errors[t] = math.MaxFloat32
+	}
+	q := make(floodQueue, 0, len(p.labels))
+	for id, seed := range seedPoints {
+		q = append(q, floodItem{point: seed, proxy: int32(id), err: pointError(c, seed, p.proxies[id])})
+	}
+	heap.Init(&q)
+
+	for q.Len() > 0 {
+		item := heap.Pop(&q).(floodItem)
+		if p.labels[item.point] != noProxy {
+			// already claimed by a proxy with a smaller error
+			continue
+		}
+		p.labels[item.point] = item.proxy
+		p.errors[item.point] = item.err
+		neighbors, _ := neighborhood.GetNeighborsOfPoint(item.point)
+		for _, n := range neighbors {
+			if p.labels[n] == noProxy {
+				proxy := p.proxies[item.proxy]
+				heap.Push(&q, floodItem{point: n, proxy: item.proxy, err: pointError(c, n, proxy)})
+			}
+		}
+	}
+
+	// points that can't be reached from any seed go to the proxy with the smallest error
+	for point := range p.labels {
+		if p.labels[point] != noProxy {
+			continue
+		}
+		for id := range p.proxies {
+			e := pointError(c, uint32(point), p.proxies[id])
+			if e < p.errors[point] {
+				p.labels[point] = int32(id)
+				p.errors[point] = e
+			}
+		}
+	}
+}
+
+// pointProxyFit fits every proxy to its member points with principal component
+// analysis, and moves the seed of every proxy to its best fitting member.
+// It returns the point with the worst error along with the error value.
+func pointProxyFit(c *pointcloud.PointCloud, p *partition, seedPoints []uint32) (uint32, float32) {
+	members := make([][]float32, len(p.proxies))
+	normalSums := make([][]float32, len(p.proxies))
+	for id := range p.proxies {
+		members[id] = make([]float32, 0)
+		normalSums[id] = []float32{0, 0, 0}
+	}
+	worstPoint := uint32(0)
+	maxError := float32(0)
+	for point, id := range p.labels {
+		if id == noProxy {
+			continue
+		}
+		if p.errors[point] > maxError {
+			worstPoint = uint32(point)
+			maxError = p.errors[point]
+		}
+		members[id] = append(members[id], c.Points[3*point:3*point+3]...)
+		normalSums[id], _ = auxmath.Add(normalSums[id], c.Normals[3*point:3*point+3])
+	}
+
+	for id := range p.proxies {
+		if len(members[id]) == 0 {
+			continue
+		}
+		meanNormal := auxmath.Normalize(normalSums[id])
+		center, normal, _, err := pointcloud.FitPlane(members[id])
+		if err != nil {
+			// too few points for a plane of their own
+			normal = meanNormal
+		} else if dot, _ := auxmath.Dot(normal, meanNormal); dot < 0 {
+			// the fitted normal has an arbitrary sign; follow the points
+			normal = auxmath.Scale(normal, -1)
+		}
+		p.proxies[id] = plane{point: [3]float64{float64(center[0]), float64(center[1]), float64(center[2])}, normal: normal}
+	}
+
+	// the member with the smallest error seeds the next flooding
+	bestErrors := make([]float32, len(p.proxies))
+	for id := range bestErrors {
+		bestErrors[id] = math.MaxFloat32
+	}
+	for point, id := range p.labels {
+		if id == noProxy {
+			continue
+		}
+		e := pointError(c, uint32(point), p.proxies[id])
+		if e < bestErrors[id] {
+			bestErrors[id] = e
+			seedPoints[id] = uint32(point)
+		}
+	}
+	return worstPoint, maxError
+}
@@ -0,0 +1,90 @@
+package vsa
+
+import (
+	"math"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/pointcloud"
+)
+
+// sampleCube samples the faces of the cube (0,0,0)-(100,100,100), keeping away
+// from the edges so that no point has neighbors on two faces.
+func sampleCube() *pointcloud.PointCloud {
+	c := pointcloud.NewPointCloud()
+	for u := float32(20); u <= 80; u += 5 {
+		for v := float32(20); v <= 80; v += 5 {
+			c.AddPoint(u, v, 0)
+			c.AddPoint(u, v, 100)
+			c.AddPoint(u, 0, v)
+			c.AddPoint(u, 100, v)
+			c.AddPoint(0, u, v)
+			c.AddPoint(100, u, v)
+		}
+	}
+	return c
+}
+
+func TestRunPointCloudCube(t *testing.T) {
+	c := sampleCube()
+	opts := DefaultOptions()
+	opts.Seed = 3
+	proxies, err := RunPointCloud(c, opts)
+	if err != nil {
+		t.Fatalf("RunPointCloud failed: %v", err)
+	}
+	if len(proxies) != 6 {
+		t.Fatalf("Expected 6 proxies and got %v", len(proxies))
+	}
+
+	numMembers := 0
+	for id, proxy := range proxies {
+		numMembers += len(proxy.Members)
+		// the normal is one of the axes, pointing out of the cube
+		axis := -1
+		for i := 0; i < 3; i++ {
+			if math.Abs(math.Abs(float64(proxy.Normal[i]))-1) < 1e-4 {
+				axis = i
+			}
+		}
+		if axis < 0 {
+			t.Errorf("Proxy %v: expected an axis aligned normal and got %v", id, proxy.Normal)
+			continue
+		}
+		outward := float32(100)
+		if proxy.Normal[axis] < 0 {
+			outward = 0
+		}
+		// every member lies on the face of the proxy
+		for _, m := range proxy.Members {
+			point, _ := c.GetPoint(m)
+			if point[axis] != outward {
+				t.Errorf("Proxy %v: point %v is not on the face %v=%v", id, point, axis, outward)
+				break
+			}
+		}
+	}
+	if numMembers != int(c.GetNumPoints()) {
+		t.Errorf("Expected every point to belong to a proxy, got %v of %v", numMembers, c.GetNumPoints())
+	}
+}
+
+func TestRunPointCloudEmpty(t *testing.T) {
+	if _, err := RunPointCloud(pointcloud.NewPointCloud(), DefaultOptions()); err == nil {
+		t.Error("Expected an error for an empty point cloud")
+	}
+}
+
+func TestRunPointCloudZeroOptions(t *testing.T) {
+	c := sampleCube()
+	proxies, err := RunPointCloud(c, Options{Seed: 3})
+	if err != nil {
+		t.Fatalf("RunPointCloud failed: %v", err)
+	}
+	numMembers := 0
+	for _, proxy := range proxies {
+		numMembers += len(proxy.Members)
+	}
+	if len(proxies) == 0 || numMembers != int(c.GetNumPoints()) {
+		t.Errorf("Expected every point to belong to one of the proxies, got %v points in %v proxies", numMembers, len(proxies))
+	}
+}
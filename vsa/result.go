@@ -0,0 +1,135 @@
+package vsa
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+)
+
+// ResultVersion is the version of the JSON document written by Result.WriteJSON.
+// Bump it whenever the layout of Result changes.
+const ResultVersion = 1
+
+// Proxy is a planar proxy as it is stored in a Result
+type Proxy struct {
+	Point  [3]float64 `json:"point"`
+	Normal [3]float32 `json:"normal"`
+}
+
+// Anchor is a mesh vertex where 3 or more proxies meet
+type Anchor struct {
+	Vertex  uint32  `json:"vertex"`
+	Proxies []int32 `json:"proxies"` // IDs of the proxies, sorted
+}
+
+// MeshInfo identifies the mesh a Result was computed on
+type MeshInfo struct {
+	Hash        string `json:"hash"`
+	NumFacets   uint32 `json:"numFacets"`
+	NumVertices uint32 `json:"numVertices"`
+}
+
+// Result is the outcome of a VSA run: the proxy table, the proxy ID of every
+// triangle and the anchor vertices, along with what is needed to reproduce it.
+type Result struct {
+	Version int      `json:"version"`
+	Mesh    MeshInfo `json:"mesh"`
+	// Options holds the options of the run.  Options.Seed is the seed that was
+	// actually used, so running again with these options gives the same result.
+	Options Options  `json:"options"`
+	Proxies []Proxy  `json:"proxies"`
+	Labels  []int32  `json:"labels"`
+	Anchors []Anchor `json:"anchors"`
+}
+
+// Run partitions the mesh into planar proxies and returns the result
+func Run(m mesh.Mesh, opts Options) (Result, error) {
+	opts = opts.withDefaults()
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	hash, err := mesh.ComputeHash(m)
+	if err != nil {
+		return Result{}, fmt.Errorf("vsa.Run: %v", err)
+	}
+
+	p := vsaRun(m, opts, rand.New(rand.NewSource(opts.Seed)))
+	for t, id := range p.labels {
+		if id == noProxy {
+			return Result{}, fmt.Errorf("vsa.Run: triangle %d was not assigned to a proxy", t)
+		}
+	}
+	anchorVertices, err := vsaGetAnchorVertices(p.labels, m)
+	if err != nil {
+		return Result{}, fmt.Errorf("vsa.Run: %v", err)
+	}
+
+	r := Result{
+		Version: ResultVersion,
+		Mesh:    MeshInfo{Hash: hash, NumFacets: m.GetNumFacets(), NumVertices: m.GetNumVertices()},
+		Options: opts,
+		Proxies: make([]Proxy, len(p.proxies)),
+		Labels:  p.labels,
+		Anchors: make([]Anchor, 0, len(anchorVertices)),
+	}
+	for id, pl := range p.proxies {
+		r.Proxies[id].Point = pl.point
+		copy(r.Proxies[id].Normal[:], pl.normal)
+	}
+	for _, a := range anchorVertices {
+		anchor := Anchor{Vertex: a.index, Proxies: make([]int32, 0, len(a.proxyPlanes))}
+		for id := range a.proxyPlanes {
+			anchor.Proxies = append(anchor.Proxies, id)
+		}
+		sort.Slice(anchor.Proxies, func(i, j int) bool { return anchor.Proxies[i] < anchor.Proxies[j] })
+		r.Anchors = append(r.Anchors, anchor)
+	}
+	sort.Slice(r.Anchors, func(i, j int) bool { return r.Anchors[i].Vertex < r.Anchors[j].Vertex })
+	return r, nil
+}
+
+// WriteJSON writes the result as a JSON document
+func (r Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadJSON reads a result written by WriteJSON and checks that it can be
+// applied to the given mesh, i.e., that it was computed on the same mesh.
+func ReadJSON(rd io.Reader, m mesh.Mesh) (Result, error) {
+	var r Result
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return Result{}, fmt.Errorf("vsa.ReadJSON: %v", err)
+	}
+	if r.Version != ResultVersion {
+		return Result{}, fmt.Errorf("vsa.ReadJSON: unsupported version %d, expected %d", r.Version, ResultVersion)
+	}
+
+	hash, err := mesh.ComputeHash(m)
+	if err != nil {
+		return Result{}, fmt.Errorf("vsa.ReadJSON: %v", err)
+	}
+	if hash != r.Mesh.Hash {
+		return Result{}, fmt.Errorf("vsa.ReadJSON: the result was computed on a different mesh")
+	}
+	if len(r.Labels) != int(m.GetNumFacets()) {
+		return Result{}, fmt.Errorf("vsa.ReadJSON: expected %d labels and got %d", m.GetNumFacets(), len(r.Labels))
+	}
+	for t, id := range r.Labels {
+		if id < 0 || int(id) >= len(r.Proxies) {
+			return Result{}, fmt.Errorf("vsa.ReadJSON: triangle %d has an invalid proxy ID %d", t, id)
+		}
+	}
+	for _, a := range r.Anchors {
+		if a.Vertex >= m.GetNumVertices() {
+			return Result{}, fmt.Errorf("vsa.ReadJSON: anchor vertex %d is out of bounds", a.Vertex)
+		}
+	}
+	return r, nil
+}
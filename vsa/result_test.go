@@ -0,0 +1,136 @@
+package vsa
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+)
+
+func TestResultRoundTrip(t *testing.T) {
+	myMesh := shape.BasicCube()
+	opts := DefaultOptions()
+	opts.Seed = 42
+	r, err := Run(myMesh, opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(r.Proxies) != 6 {
+		t.Errorf("Expected 6 proxies and got %v", len(r.Proxies))
+	}
+	if len(r.Anchors) != 8 {
+		t.Errorf("Expected 8 anchors and got %v", len(r.Anchors))
+	}
+
+	buf := new(bytes.Buffer)
+	if err := r.WriteJSON(buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	loaded, err := ReadJSON(buf, myMesh)
+	if err != nil {
+		t.Fatalf("ReadJSON failed: %v", err)
+	}
+
+	if loaded.Options != r.Options {
+		t.Errorf("Expected options %v and got %v", r.Options, loaded.Options)
+	}
+	for i := range r.Proxies {
+		if loaded.Proxies[i] != r.Proxies[i] {
+			t.Errorf("Proxy %v: expected %v and got %v", i, r.Proxies[i], loaded.Proxies[i])
+		}
+	}
+	for i := range r.Labels {
+		if loaded.Labels[i] != r.Labels[i] {
+			t.Errorf("Label %v: expected %v and got %v", i, r.Labels[i], loaded.Labels[i])
+		}
+	}
+	for i := range r.Anchors {
+		if loaded.Anchors[i].Vertex != r.Anchors[i].Vertex || len(loaded.Anchors[i].Proxies) != 3 {
+			t.Errorf("Anchor %v: expected %v and got %v", i, r.Anchors[i], loaded.Anchors[i])
+		}
+	}
+}
+
+func TestResultZeroOptions(t *testing.T) {
+	myMesh := shape.BasicCube()
+	r, err := Run(myMesh, Options{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if r.Options.MaxIterations != DefaultOptions().MaxIterations || r.Options.Seed == 0 {
+		t.Errorf("Expected the default options and a seed, got %+v", r.Options)
+	}
+	buf := new(bytes.Buffer)
+	if err := r.WriteJSON(buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	if _, err := ReadJSON(buf, myMesh); err != nil {
+		t.Errorf("ReadJSON rejected the result of Run: %v", err)
+	}
+}
+
+func TestResultSeed(t *testing.T) {
+	myMesh := shape.Octahedron(200)
+	opts := DefaultOptions()
+	opts.Seed = 7
+	opts.NumSeeds = 4
+	r1, _ := Run(myMesh, opts)
+	r2, _ := Run(myMesh, r1.Options)
+	for i := range r1.Labels {
+		if r1.Labels[i] != r2.Labels[i] {
+			t.Fatalf("Expected the same seed to give the same labels, triangle %v differs", i)
+		}
+	}
+}
+
+func TestReadJSONWrongMesh(t *testing.T) {
+	r, _ := Run(shape.BasicCube(), DefaultOptions())
+	buf := new(bytes.Buffer)
+	r.WriteJSON(buf)
+	if _, err := ReadJSON(buf, shape.Octahedron(8)); err == nil {
+		t.Error("Expected an error when applying a result to a different mesh")
+	}
+
+	bad := strings.NewReader(`{"version": 999}`)
+	if _, err := ReadJSON(bad, shape.BasicCube()); err == nil {
+		t.Error("Expected an error for an unsupported version")
+	}
+}
+
+// A sphere of 1 cm far from the origin: float32 can't tell its points apart, so
+// the proxies only keep their place if they are fitted in double precision.
+func TestRunFarFromOrigin(t *testing.T) {
+	sphere := shape.Sphere(200, .01).ToIndexedMesh64()
+	for i := range sphere.Vertices {
+		sphere.Vertices[i] += 5e6
+	}
+	opts := DefaultOptions()
+	opts.Seed = 1
+	opts.NumSeeds = 8
+	opts.MaxIterations = 10
+	r, err := Run(sphere, opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(r.Proxies) < 8 {
+		t.Fatalf("Expected at least 8 proxies, got %d", len(r.Proxies))
+	}
+	for id, p := range r.Proxies {
+		var offset [3]float64
+		dist, dot := 0.0, 0.0
+		for i := 0; i < 3; i++ {
+			offset[i] = p.Point[i] - 5e6
+			dist += offset[i] * offset[i]
+		}
+		dist = math.Sqrt(dist)
+		for i := 0; i < 3; i++ {
+			dot += offset[i] / dist * float64(p.Normal[i])
+		}
+		// the centroid of a patch of the sphere, under its normal
+		if dist < .002 || dist > .01 || dot < .9 {
+			t.Errorf("Proxy %d: expected a point on the sphere under its normal, got %v and %v", id, p.Point, p.Normal)
+		}
+	}
+}
@@ -1,247 +1,322 @@
 package vsa
 
 import (
-	"fmt"
 	"log"
 	"math"
+	"math/rand"
+	"time"
 
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
 )
 
+// plane is a proxy: a unit normal through a point.  The point is in double precision,
+// so that proxies keep their place on meshes far from the origin (see mesh.Mesh64).
 type plane struct {
-	point  []float32
+	point  [3]float64
 	normal []float32
 }
-type pError struct {
-	p       *plane
-	perror  float32
-	trindex uint32
+
+// trianglePlane returns the plane of a triangle, through its centroid
+func trianglePlane(m mesh.Mesh, tri uint32) (plane, error) {
+	center, err := mesh.ComputeCentroid64(m, tri)
+	if err != nil {
+		return plane{normal: make([]float32, 3)}, err
+	}
+	n, err := mesh.ComputeNormal64(m, tri)
+	return plane{point: center, normal: []float32{float32(n[0]), float32(n[1]), float32(n[2])}}, err
 }
 
-func vanillaGeometricPartition(m mesh.Mesh, proxies [] plane, pErrors [] pError) {
-	for i := range proxies {
-		// compute the error for all triangles in the mesh
-		seedError := ComputePlaneError(m, proxies[i])
-		//fmt.Printf("looking at seed: %d\n", i)
-		for x := range pErrors {
+// noProxy is the label of a triangle that has not been assigned to a proxy yet.
+const noProxy = int32(-1)
+
+// partition assigns every triangle of a mesh to a proxy.
+// Proxies are identified by their index in the proxy table, so a label can be
+// copied, compared or written to disk without caring where the plane lives in memory.
+type partition struct {
+	proxies []plane   // the proxy table, indexed by proxy ID
+	labels  []int32   // labels[t] is the proxy ID of triangle t
+	errors  []float32 // errors[t] is the error of triangle t against its proxy
+	// proxies [0,fixed) come from the planar pre-pass; they, and their triangles, are never refit or reassigned
+	fixed int32
+}
 
-			// if the error for this proxy is less than what we have on record in pErrors
-			// set the proxy for this plan as well as the new error
-			if seedError[x].perror < pErrors[x].perror {
-				pErrors[x].p = seedError[x].p
-				pErrors[x].perror = seedError[x].perror
+// isFixed tells if triangle t belongs to a proxy from the planar pre-pass
+func (p *partition) isFixed(t int) bool {
+	return p.labels[t] != noProxy && p.labels[t] < p.fixed
+}
+
+// vanillaGeometricPartition assigns every triangle to the proxy with the smallest error.
+func vanillaGeometricPartition(m mesh.Mesh, p *partition) {
+	// the proxies moved since the last partition, so the old errors are meaningless
+	free := make([]bool, len(p.labels))
+	for t := range p.labels {
+		if p.isFixed(t) {
+			continue
+		}
+		free[t] = true
+		p.labels[t] = noProxy
+		p.errors[t] = math.MaxFloat32
+	}
+	// the triangles don't move, so their normals are computed once for all the proxies
+	normals := make([]auxmath.Vec3, len(p.labels))
+	areas := make([]float32, len(p.labels))
+	for t := range p.labels {
+		if free[t] {
+			normals[t], areas[t], _ = triangleNormal(m, uint32(t))
+		}
+	}
+	for id := int(p.fixed); id < len(p.proxies); id++ {
+		proxyNormal, _ := auxmath.ToVec3(p.proxies[id].normal)
+		for t := range p.labels {
+			// if the error for this proxy is less than what we have on record
+			// set the proxy for this triangle as well as the new error
+			if !free[t] {
+				continue
+			}
+			if e := planeError(proxyNormal, normals[t], areas[t]); e < p.errors[t] {
+				p.labels[t] = int32(id)
+				p.errors[t] = e
 			}
 		}
 	}
 }
 
-// for every plane that is consumed by x triangles, change the definition of the plane to be the average
-// of the barycenters and the average of all of the normals of the triangles.
-//returns the triangle index who had the worst error along with the error value
-func vanillaProxyFit(m mesh.Mesh, pErrors [] pError) (uint32, float32){
+// for every plane that is consumed by x triangles, change the definition of the plane to be the area weighted
+// average of the barycenters and the area weighted average of all of the normals of the triangles,
+// which is the optimal proxy for the L2,1 metric.  When refineIterations is positive the normal
+// of each proxy is then refined towards the optimal normal for the L2 metric (see refineNormal).
+//returns the triangle index who had the worst error along with the error value, and the mean error
+func vanillaProxyFit(m mesh.Mesh, p *partition, refineIterations int) (uint32, float32, float32) {
 
-	proxies := make(map[*plane]int)
+	moments := make([]proxyMoments, len(p.proxies))
 	totalErr := float32(0)
 	worstTri := uint32(0)
 	maxError := float32(0)
 
-	for i := range pErrors {
+	for t := range p.labels {
 
-		tri := pErrors[i].trindex
-		triError := pErrors[i].perror
+		tri := uint32(t)
+		id := p.labels[t]
+		if id == noProxy || p.isFixed(t) {
+			continue
+		}
+		triError := p.errors[t]
 		totalErr += triError
 		if triError > maxError {
 			worstTri = tri
 			maxError = triError
 		}
 
-		triNorm, err := mesh.ComputeNormal(m, tri)
-		triBarycenter := mesh.ComputeCentroid(m,tri)
+		points, err := mesh.GetTrianglePoints64(m, tri)
 		if err != nil {
-			fmt.Println("Ouch that hurt.")
-		}
-
-		_, ok := proxies[pErrors[i].p]
-		// if we have already seen this plane
-		if ok {
-			// update how many triangles have this plane as its proxy
-			proxies[pErrors[i].p] += 1
-
-			// extract the current normal(s)
-			normal := pErrors[i].p.normal
-			// Compute the new normal by adding the x, y and z components
-			// We will renormalize at the end, so no need for division
-			updatedNormal := []float32{normal[0] + triNorm[0],
-						normal[1] + triNorm[1],
-						normal[2] + triNorm[2]}
-			// assign the new normal
-			pErrors[i].p.normal = updatedNormal
-
-			//do the same for the proxy's centroid
-			//again, we will average once at the end
-			center := pErrors[i].p.point
-			//TODO need an add/subtract/negate service for float slices
-			updatedPoint := []float32{center[0] + triBarycenter[0],
-						center[1] + triBarycenter[1],
-						center[2] + triBarycenter[2]}
-			pErrors[i].p.point = updatedPoint
-
-		} else {
-			// we have never seen this triangle so add one
-			proxies[pErrors[i].p] += 1
-			// set the normal of the plane to the normal of the current triangle
-			// next time we will add in the normal above
-			pErrors[i].p.normal = triNorm
-		}
-	}
-	fmt.Printf("The total error is %v\n", totalErr/float32(m.GetNumFacets()))
-	fmt.Printf("The max error is %v\n", maxError)
-	// iterate over the speed hashmap to compute the actual average normal for each plane
-	// k = *plane, value = int( number of triangles with this plane as their proxy)
-	for k, v := range proxies {
-		k.normal = auxmath.Normalize(k.normal)
-		//average the barycenter
-		currCenter := k.point
-		avgCenter := make([]float32,3)
-		//TODO: auxMath.Scale
-		for i := 0; i < 3; i++ {
-			// compute the average of the normals
-			avgCenter[i] = currCenter[i] / float32(v)
-		}
-		k.point = avgCenter
-	}
-	return worstTri,maxError
-}
-
-func initialize(numTris int) (p []pError){
-	pErrors := make([]pError, int(numTris), int(numTris))
+			continue
+		}
+		moments[id].addTriangle(points[0], points[1], points[2], refineIterations > 0)
+	}
+	for id := int(p.fixed); id < len(p.proxies); id++ {
+		fitted, ok := moments[id].fit()
+		if !ok {
+			// a proxy that lost all of its triangles (or only has degenerate ones) keeps its old definition
+			continue
+		}
+		if refineIterations > 0 {
+			n := [3]float64{float64(fitted.normal[0]), float64(fitted.normal[1]), float64(fitted.normal[2])}
+			n = refineNormal(moments[id].covariance(), n, refineIterations)
+			fitted.normal = []float32{float32(n[0]), float32(n[1]), float32(n[2])}
+		}
+		p.proxies[id] = fitted
+	}
+	return worstTri, maxError, totalErr / float32(m.GetNumFacets())
+}
+
+// removeEmptyProxies drops the proxies that did not win any triangle and
+// renumbers the labels so that the proxy IDs stay contiguous.
+// Fixed proxies always own their triangles, so they keep their IDs.
+func removeEmptyProxies(p *partition) {
+	newIDs := make([]int32, len(p.proxies))
+	for id := range newIDs {
+		newIDs[id] = noProxy
+	}
+	for _, id := range p.labels {
+		if id != noProxy {
+			newIDs[id] = 0
+		}
+	}
+	kept := make([]plane, 0, len(p.proxies))
+	for id := range p.proxies {
+		if newIDs[id] == noProxy {
+			continue
+		}
+		newIDs[id] = int32(len(kept))
+		kept = append(kept, p.proxies[id])
+	}
+	for t, id := range p.labels {
+		if id != noProxy {
+			p.labels[t] = newIDs[id]
+		}
+	}
+	p.proxies = kept
+}
+
+func initialize(numTris int) partition {
+	labels := make([]int32, numTris)
+	errors := make([]float32, numTris)
 	// setup all triangles to have infinite error
-	for i := 0; i < int(numTris); i++ {
-		// set the error 2^32 -1 , very large
-		pErr := pError{p: nil, perror: math.MaxFloat32, trindex: uint32(i)}
-		pErrors[i] = pErr
+	for i := 0; i < numTris; i++ {
+		labels[i] = noProxy
+		errors[i] = math.MaxFloat32
 	}
-	return pErrors
+	return partition{proxies: make([]plane, 0), labels: labels, errors: errors}
 }
 
-func printProxies( proxies [] plane){
+func logProxies(proxies []plane) {
 	numProxies := len(proxies)
-	for i:= 0; i < numProxies; i++ {
-		fmt.Printf("Proxy: %v\n", i)
+	for i := 0; i < numProxies; i++ {
 		n := proxies[i].normal
 		c := proxies[i].point
-		fmt.Printf("Normal: (%v,%v,%v)\n",n[0], n[1], n[2])
-		fmt.Printf("Center: (%v,%v,%v)\n\n", c[0], c[1], c[2])
+		log.Printf("Proxy %v: normal (%v,%v,%v), center (%v,%v,%v)", i, n[0], n[1], n[2], c[0], c[1], c[2])
 	}
 }
 
-func vsaVanillaError(m mesh.Mesh, errorThreshold float32, numSeeds int) ([]plane, []pError){
+func vsaVanillaError(m mesh.Mesh, errorThreshold float32, numSeeds int) partition {
+	opts := DefaultOptions()
+	opts.ErrorThreshold = errorThreshold
+	opts.NumSeeds = numSeeds
+	return vsaRun(m, opts, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// vsaRun runs the Lloyd iterations of VSA, drawing the seed triangles from rng.
+func vsaRun(m mesh.Mesh, opts Options, rng *rand.Rand) partition {
 	numTris := m.GetNumFacets()
 	// check to make sure that we have some triangles
 	if numTris < 1 {
-		log.Printf("There weren't any triangles in the mesh\n")
-		return nil,nil
+		return initialize(0)
 	}
 
-	pErrors := initialize(int(numTris))
+	p := initialize(int(numTris))
+
+	if opts.PlanarPrePass {
+		// the flat regions become fixed proxies and VSA only has to deal with the rest
+		regionLabels, regions := planarRegions(m, opts.PlanarNormalTolerance, opts.PlanarOffsetTolerance, opts.PlanarMinTriangles)
+		p.proxies = append(p.proxies, regions...)
+		p.fixed = int32(len(regions))
+		for t, id := range regionLabels {
+			if id != noProxy {
+				p.labels[t] = id
+				p.errors[t] = 0
+			}
+		}
+	}
 
+	// only the triangles outside of the fixed proxies can seed new proxies
+	rseeds := make([]uint32, 0, int(numTris))
+	for i := 0; i < int(numTris); i++ {
+		if !p.isFixed(i) {
+			rseeds = append(rseeds, uint32(i))
+		}
+	}
 
+	numSeeds := opts.NumSeeds
 	// The case where we have less than 10 triangles
 	if numSeeds < 1 {
 		numSeeds = 1
 	}
-
-	rseeds := make([]uint32, int(numTris), int(numTris))
-	for i := 0; i < int(numTris); i++ {
-		rseeds[i] = uint32(i)
+	if numSeeds > len(rseeds) {
+		numSeeds = len(rseeds)
 	}
 
 	seeds := make([]uint32, 0, numSeeds)
-	// for the 10% of seeds, generate the 10% of random seed triangles
+	// pick numSeeds distinct seed triangles
 	for i := 0; i < numSeeds; i++ {
-		seed := auxmath.RandomUint32(0, uint32(int(numTris)-i))
-		seeds = append(seeds, rseeds[seed])
-		copy(rseeds[:i], rseeds[i:])
+		seed := i + rng.Intn(len(rseeds)-i)
+		rseeds[i], rseeds[seed] = rseeds[seed], rseeds[i]
+		seeds = append(seeds, rseeds[i])
 	}
 
 	//Convert the seed triangles to proxies
-	proxies := make([]plane,numSeeds)
-	for i:= 0; i < len(seeds); i++{
-		normal,err := mesh.ComputeNormal(m,seeds[i])
-		if err != nil {
-			//continue
-			log.Printf("Couldn't compute normal: %v", err)
-			//return nil
+	for i := 0; i < len(seeds); i++ {
+		seed, err := trianglePlane(m, seeds[i])
+		if err != nil && opts.Verbose {
+			log.Printf("Couldn't compute the normal of seed triangle %v: %v", seeds[i], err)
 		}
-		center := mesh.ComputeCentroid(m,seeds[i])
-		proxies[i].point = center
-		proxies[i].normal = normal
+		p.proxies = append(p.proxies, seed)
 	}
 
 	numIterations := 0
-	maxNumIterations := 100
+	maxNumIterations := opts.MaxIterations
 	maxError := float32(math.MaxFloat32)
 
-
-	for maxError > errorThreshold && numIterations < maxNumIterations{
-		vanillaGeometricPartition(m, proxies, pErrors)
-		worstTri, thisIterationError := vanillaProxyFit(m, pErrors)
-		center := mesh.ComputeCentroid(m, worstTri)
-		normal, _ := mesh.ComputeNormal(m, worstTri)
-
-		if( thisIterationError > errorThreshold) {
-			proxies = append(proxies, plane{point: center, normal: normal})
+	for maxError > opts.ErrorThreshold && numIterations < maxNumIterations {
+		vanillaGeometricPartition(m, &p)
+		worstTri, thisIterationError, meanError := vanillaProxyFit(m, &p, opts.RefineIterations)
+		if opts.Verbose {
+			log.Printf("Iteration %v: the mean error is %v and the max error is %v", numIterations, meanError, thisIterationError)
+		}
+		if thisIterationError > opts.ErrorThreshold {
+			worst, _ := trianglePlane(m, worstTri)
+			p.proxies = append(p.proxies, worst)
 		}
 
-		if(thisIterationError < maxError){
+		if thisIterationError < maxError {
 			maxError = thisIterationError
 		}
 
 		numIterations++
 	}
-	fmt.Printf("The final number of proxies is %v\n", len(proxies))
-
-	printProxies(proxies)
-	return proxies,pErrors
+	removeEmptyProxies(&p)
+	if opts.Verbose {
+		log.Printf("The final number of proxies is %v", len(p.proxies))
+		logProxies(p.proxies)
+	}
+	return p
 
 }
 
-func vsaVanillaNumSeeds(m mesh.Mesh, numSeeds int) ([]plane, []pError){
+func vsaVanillaNumSeeds(m mesh.Mesh, numSeeds int) partition {
 	return vsaVanillaError(m, .1, numSeeds)
 }
 
-func VSAVanilla(m mesh.Mesh) ([]plane, []pError) {
-	return vsaVanillaError(m,.1, 1)
+// VSAVanilla partitions the mesh into planar proxies.  It returns the proxy
+// table and, for every triangle, the ID (index into the table) of its proxy.
+func VSAVanilla(m mesh.Mesh) ([]plane, []int32) {
+	p := vsaVanillaError(m, .1, 1)
+	return p.proxies, p.labels
 }
 
-// ComputePlaneError - given a seed triangle compute the error for every triangle in the mesh
-func ComputePlaneError(m mesh.Mesh, proxy plane) (p []pError) {
+// ComputePlaneError - given a proxy compute the error for every triangle in the mesh
+func ComputePlaneError(m mesh.Mesh, proxy plane) []float32 {
 	numTris := m.GetNumFacets()
-	pErrors := make([]pError, 0, int(numTris))
+	planeErrors := make([]float32, 0, int(numTris))
+
+	proxyNormal, _ := auxmath.ToVec3(proxy.normal)
 
 	// for every triangle
 	for i := 0; i < int(numTris); i++ {
 		// compute the normal of the current triangle
-		triNormal, err := mesh.ComputeNormal(m, uint32(i))
-		if err != nil {
-			//continue
-			log.Printf("Couldn't compute normal: %v", err)
-			//return nil
-		}
+		// a degenerate triangle has no area, so no error whatever its normal
+		triNormal, area, _ := triangleNormal(m, uint32(i))
 
-		// Compute the difference between the normals.
-		diff := make([]float32, 0, 3)
-		for x := 0; x < 3; x++ {
-			//fmt.Printf("Seed Normal: %d\t TriNormal: %d\n", seedPlane.normal[x], triNormal[x])
-			diff = append(diff, proxy.normal[x]-triNormal[x])
-		}
-		mag := auxmath.Magnitude(diff) * mesh.ComputeArea(m, uint32(i))
+		// append our new error to the slice of errors to be returned
+		planeErrors = append(planeErrors, planeError(proxyNormal, triNormal, area))
+	}
+	return planeErrors
+}
 
-		pErr := pError{p: &proxy, perror: mag, trindex: uint32(i)}
+// planeError is the error of a triangle against a proxy: the difference between
+// their normals, weighted by the area of the triangle
+func planeError(proxyNormal auxmath.Vec3, triNormal auxmath.Vec3, area float32) float32 {
+	return proxyNormal.Sub(triNormal).Magnitude() * area
+}
 
-		// append our new error to the slice of errors to be returned
-		pErrors = append(pErrors, pErr)
+// triangleNormal returns the unit normal and the area of a triangle, computed in
+// double precision when the mesh has double precision vertices (see mesh.Mesh64)
+func triangleNormal(m mesh.Mesh, tri uint32) (auxmath.Vec3, float32, error) {
+	if _, ok := m.(mesh.Mesh64); ok {
+		n, err := mesh.ComputeNormal64(m, tri)
+		return auxmath.Vec3{float32(n[0]), float32(n[1]), float32(n[2])}, float32(mesh.ComputeArea64(m, tri)), err
 	}
-	return pErrors
+	points, _ := mesh.GetTrianglePoints(m, tri)
+	n, err := mesh.ComputeTriangleNormalVec3(points[0], points[1], points[2])
+	return n, mesh.ComputeTriangleAreaVec3(points[0], points[1], points[2]), err
 }
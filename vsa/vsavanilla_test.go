@@ -3,6 +3,8 @@ package vsa
 import (
 	"testing"
 	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/shape"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/mesh"
+	"bitbucket.org/cloudcomputer/cloud-mesh-simplifier/auxmath"
 	"fmt"
 )
 
@@ -21,7 +23,7 @@ func TestVSAVanilla(t *testing.T){
 
 func TestVSAVanilla2(t *testing.T) {
 	myMesh := shape.Octahedron(2000)
-	proxies,_ := vsaVanillaError(myMesh,.01, 1)
+	proxies := vsaVanillaError(myMesh,.01, 1).proxies
 	for i := 0; i < len(proxies); i++ {
 		fmt.Printf("Proxy: %v\n", proxies[i])
 	}
@@ -29,4 +31,24 @@ func TestVSAVanilla2(t *testing.T) {
 	if len(proxies) != 8 {
 		t.Fail()
 	}
-}
\ No newline at end of file
+}
+// Every triangle of the cube must be labelled with a proxy from the table
+// whose normal is the normal of the triangle's face.
+func TestVSAVanillaLabels(t *testing.T) {
+	myMesh := shape.BasicCube()
+	proxies, labels := VSAVanilla(myMesh)
+
+	if len(labels) != int(myMesh.GetNumFacets()) {
+		t.Fatalf("Expected %v labels and got %v", myMesh.GetNumFacets(), len(labels))
+	}
+	for tri, id := range labels {
+		if id < 0 || int(id) >= len(proxies) {
+			t.Fatalf("Triangle %v has a label outside of the proxy table: %v", tri, id)
+		}
+		normal, _ := mesh.ComputeNormal(myMesh, uint32(tri))
+		dot, _ := auxmath.Dot(normal, proxies[id].normal)
+		if dot < .999 {
+			t.Errorf("Triangle %v: expected proxy normal %v and got %v", tri, normal, proxies[id].normal)
+		}
+	}
+}